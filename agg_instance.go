@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// How long claimed feed stays reserved for instance which claimed it. Heartbeats
+// renew it for feeds the instance still holds, so only crashed instances lose claims
+const feedClaimLease = 5 * time.Minute
+
+// How often running agg instance reports it is alive
+const heartbeatEvery = 15 * time.Second
+
+// Instance without heartbeat for this long is considered crashed and its claims are released
+const instanceStaleAfter = time.Minute
+
+// Running agg process, registered in database so several hosts can share the work
+type aggInstance struct {
+	id        uuid.UUID
+	hostname  string
+	pid       int
+	startedAt time.Time
+	// agg running on interval, the only kind serving queued refresh requests
+	daemon bool
+}
+
+func newAggInstance(daemon bool) aggInstance {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return aggInstance{
+		id:        uuid.New(),
+		hostname:  hostname,
+		pid:       os.Getpid(),
+		startedAt: time.Now(),
+		daemon:    daemon,
+	}
+}
+
+// Registers new agg instance for the duration of run, keeps its heartbeat going and unregisters it afterwards
+func runAggInstance(ctx, fetchCtx context.Context, s *state, daemon bool, run func(instance aggInstance) error) error {
+	instance := newAggInstance(daemon)
+	if registerErr := instance.heartbeat(fetchCtx, s); registerErr != nil {
+		return registerErr
+	}
+
+	stopHeartbeat := instance.startHeartbeat(fetchCtx, s)
+	defer func() {
+		stopHeartbeat()
+		instance.unregister(context.WithoutCancel(ctx), s)
+	}()
+
+	return run(instance)
+}
+
+// Instance id in form used by feed claims
+func (a aggInstance) claimID() uuid.NullUUID {
+	return uuid.NullUUID{UUID: a.id, Valid: true}
+}
+
+// Records heartbeat of instance, renews leases of feeds it is still fetching and
+// releases feeds claimed by instances which stopped sending theirs
+func (a aggInstance) heartbeat(ctx context.Context, s *state) error {
+	heartbeatErr := s.db.HeartbeatAggInstance(ctx, database.HeartbeatAggInstanceParams{
+		ID:        a.id,
+		Hostname:  a.hostname,
+		Pid:       int32(a.pid),
+		StartedAt: a.startedAt,
+		Daemon:    a.daemon,
+	})
+	if heartbeatErr != nil {
+		return fmt.Errorf("error sending heartbeat of agg instance %s: %v", a.id, heartbeatErr)
+	}
+
+	// Slow fetch keeps its feed, otherwise another instance could claim it meanwhile
+	if _, extendErr := s.db.ExtendFeedClaims(ctx, database.ExtendFeedClaimsParams{
+		LeaseSeconds: int32(feedClaimLease / time.Second),
+		InstanceID:   a.claimID(),
+	}); extendErr != nil {
+		return fmt.Errorf("error renewing feed claims of agg instance %s: %v", a.id, extendErr)
+	}
+
+	// Deleting instance clears claimed_by of its feeds
+	removed, staleErr := s.db.DeleteStaleAggInstances(ctx, int32(instanceStaleAfter/time.Second))
+	if staleErr != nil {
+		return fmt.Errorf("error removing stale agg instances: %v", staleErr)
+	}
+	if removed > 0 {
+		slog.Info("released feeds claimed by stale agg instances", "instances", removed)
+	}
+
+	return nil
+}
+
+// Sends heartbeats in background until returned stop function is called, stop waits for last heartbeat to finish
+func (a aggInstance) startHeartbeat(ctx context.Context, s *state) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(heartbeatEvery)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.heartbeat(ctx, s); err != nil {
+					slog.Error("heartbeat failed", "instance", a.id, "error", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Removes instance from database which also releases feeds it still holds
+func (a aggInstance) unregister(ctx context.Context, s *state) {
+	if err := s.db.DeleteAggInstance(ctx, a.id); err != nil {
+		slog.Error("error unregistering agg instance", "instance", a.id, "error", err)
+	}
+}
+
+// Makes claimed feed available to other instances again
+func releaseFeedClaim(ctx context.Context, s *state, feed database.Feed) {
+	if err := s.db.ReleaseFeedClaim(ctx, feed.ID); err != nil {
+		slog.Error("error releasing feed claim", "feed", feed.Name, "error", err)
+	}
+}
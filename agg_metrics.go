@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/metrics"
+)
+
+// Scheduler without progress for this long is reported unhealthy
+const schedulerStallAfter = feedClaimLease
+
+// Starts HTTP listener exposing /metrics and /healthz of agg daemon. Returned function stops it
+func startMetricsServer(s *state, addr string) (func(), error) {
+	s.metrics = metrics.New()
+	s.metrics.RegisterGauge("gator_fetch_queue_depth", "Feeds waiting in refresh queue.", func(ctx context.Context) (float64, error) {
+		depth, err := s.db.CountQueuedFeeds(ctx)
+		return float64(depth), err
+	})
+	s.metrics.RegisterGauge("gator_feeds_overdue", "Enabled feeds due for fetch which no instance has claimed yet.", func(ctx context.Context) (float64, error) {
+		overdue, err := s.db.CountOverdueFeeds(ctx)
+		return float64(overdue), err
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if err := checkHealth(r.Context(), s); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	listener, listenErr := net.Listen("tcp", addr)
+	if listenErr != nil {
+		return nil, fmt.Errorf("error starting metrics listener on %s: %v", addr, listenErr)
+	}
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if serveErr := server.Serve(listener); serveErr != nil && !errors.Is(serveErr, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "error", serveErr)
+		}
+	}()
+	slog.Info("serving metrics", "addr", listener.Addr().String())
+
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}, nil
+}
+
+// Healthy daemon reaches its database and its scheduler keeps making progress
+func checkHealth(ctx context.Context, s *state) error {
+	pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	if err := s.db.Ping(pingCtx); err != nil {
+		return fmt.Errorf("database unreachable: %v", err)
+	}
+
+	if idle := time.Since(s.metrics.LastTick()); idle > schedulerStallAfter {
+		return fmt.Errorf("scheduler made no progress for %s", idle.Round(time.Second))
+	}
+
+	return nil
+}
+
+func fetchOutcome(result scrapeResult) string {
+	if result.err != nil {
+		return "failure"
+	}
+	return "success"
+}
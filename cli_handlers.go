@@ -3,15 +3,20 @@ package main
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"strconv"
 
 	"github.com/MichalGul/blog_aggregator/internal/config"
 	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/MichalGul/blog_aggregator/internal/metrics"
+	"github.com/MichalGul/blog_aggregator/internal/storage"
 )
 
 type state struct {
-	db     *database.Queries
+	db     storage.Store
 	config *config.Config
+	// Set only when agg runs with --metrics
+	metrics *metrics.Registry
 }
 
 type command struct {
@@ -20,55 +25,76 @@ type command struct {
 }
 
 type commands struct {
-	handableCommands map[string]func(*state, command) error
+	handableCommands map[string]func(context.Context, *state, command) error
 }
 
 // Checks for logged in user in system. DRY code
-func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
+func middlewareLoggedIn(handler func(ctx context.Context, s *state, cmd command, user database.User) error) func(context.Context, *state, command) error {
 
-	return func(s *state, cmd command) error {
-		currentUser, userErr := s.db.GetUser(context.Background(), s.config.CURRENT_USER_NAME)
+	return func(ctx context.Context, s *state, cmd command) error {
+		currentUser, userErr := sessionUser(ctx, s)
 		if userErr != nil {
-			return fmt.Errorf("error getting current user: %v", userErr)
+			return userErr
 		}
+		slog.Debug("running command as user", "command", cmd.name, "user", currentUser.Name)
 
-		return handler(s, cmd, currentUser)
+		return handler(ctx, s, cmd, currentUser)
 	}
 }
 
+// Roles of users, admins may run commands affecting other users
+const (
+	roleAdmin  = "admin"
+	roleMember = "member"
+)
+
+// Checks that logged in user is admin before running handler
+func middlewareAdmin(handler func(ctx context.Context, s *state, cmd command, user database.User) error) func(context.Context, *state, command) error {
+
+	return middlewareLoggedIn(func(ctx context.Context, s *state, cmd command, user database.User) error {
+		if user.Role != roleAdmin {
+			return fmt.Errorf("%s command requires admin role, %s is %s", cmd.name, user.Name, user.Role)
+		}
+		return handler(ctx, s, cmd, user)
+	})
+}
+
 // Register new handler function for command name
-func (c *commands) register(name string, f func(*state, command) error) {
+func (c *commands) register(name string, f func(context.Context, *state, command) error) {
 	c.handableCommands[name] = f
 
 }
 
 // Runs given command with provided state if exists
-func (c *commands) run(s *state, cmd command) error {
+func (c *commands) run(ctx context.Context, s *state, cmd command) error {
 	avaliableCommand, commandExists := c.handableCommands[cmd.name]
 	if !commandExists {
 		return fmt.Errorf("command %s not avaliable", cmd.name)
 	}
-	return avaliableCommand(s, cmd)
+	slog.Debug("running command", "command", cmd.name, "args", redactArgs(cmd))
+	return avaliableCommand(ctx, s, cmd)
 }
 
-func handleReset(s *state, cmd command) error {
-	err := s.db.DeleteUsers(context.Background())
-
+func handleReset(ctx context.Context, s *state, cmd command, user database.User) error {
+	err := s.db.InTx(ctx, func(qtx database.Querier) error {
+		if userErr := qtx.DeleteUsers(ctx); userErr != nil {
+			return fmt.Errorf("error deleting users: %v", userErr)
+		}
+		if feedErr := qtx.DeleteFeeds(ctx); feedErr != nil {
+			return fmt.Errorf("error deleting feeds: %v", feedErr)
+		}
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("error reseting database state %v", err)
 	}
 
-	ferr := s.db.DeleteFeeds(context.Background())
-	if ferr != nil {
-		return fmt.Errorf("error reseting database state %v", err)
-	}
-
 	fmt.Println("Database was cleaned from data")
 
-	return err
+	return nil
 }
 
-func handleBrowse(s *state, cmd command, user database.User) error {
+func handleBrowse(ctx context.Context, s *state, cmd command, user database.User) error {
 	var limitRaw string
 	if len(cmd.args) == 0 {
 		limitRaw = "2"
@@ -78,10 +104,10 @@ func handleBrowse(s *state, cmd command, user database.User) error {
 
 	limit, parsErr := strconv.Atoi(limitRaw)
 	if parsErr != nil {
-		return fmt.Errorf("parse error:", parsErr)
+		return fmt.Errorf("parse error: %v", parsErr)
 	}
 
-	posts, browseErr := s.db.GetPostForUser(context.Background(), database.GetPostForUserParams{
+	posts, browseErr := s.db.GetPostForUser(ctx, database.GetPostForUserParams{
 		UserID: user.ID,
 		Limit:  int32(limit),
 	})
@@ -96,7 +122,7 @@ func handleBrowse(s *state, cmd command, user database.User) error {
 		fmt.Printf("Title: %s \n", posts[i].Title)
 		fmt.Printf("Published: %s \n", posts[i].PublishedAt.Time)
 		fmt.Printf("Description: %s \n", posts[i].Description.String)
-		feed, _ := s.db.GetFeedById(context.Background(), posts[i].FeedID)
+		feed, _ := s.db.GetFeedById(ctx, posts[i].FeedID)
 		fmt.Printf("Feed source: %s \n", feed.Name)
 
 		fmt.Printf("==================== \n")
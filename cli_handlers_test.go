@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+func TestHandleBrowse(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+	other := createTestUser(t, s, "bob")
+	feed := createTestFeed(t, s, user, "blog", "https://example.com/feed.xml")
+	otherFeed := createTestFeed(t, s, other, "news", "https://example.com/news.xml")
+
+	for _, batch := range []database.CreatePostsParams{
+		{
+			FeedID:       feed.ID,
+			Titles:       []string{"older post", "newest post", "oldest post"},
+			Urls:         []string{"https://example.com/2", "https://example.com/3", "https://example.com/1"},
+			PublishedAts: []string{"2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z", "2024-01-01T00:00:00Z"},
+		},
+		{
+			FeedID:       otherFeed.ID,
+			Titles:       []string{"post of bob"},
+			Urls:         []string{"https://example.com/bob"},
+			PublishedAts: []string{"2024-04-01T00:00:00Z"},
+		},
+	} {
+		batch.CreatedAt = time.Now()
+		batch.OriginalUrls = batch.Urls
+		batch.Descriptions = make([]string, len(batch.Titles))
+		batch.Contents = make([]string, len(batch.Titles))
+		if _, err := s.db.CreatePosts(ctx, batch); err != nil {
+			t.Fatalf("error creating posts: %v", err)
+		}
+	}
+
+	output := captureOutput(t, func() {
+		if err := handleBrowse(ctx, s, command{name: "browse", args: []string{"2"}}, user); err != nil {
+			t.Fatalf("browse failed: %v", err)
+		}
+	})
+
+	newest, older := strings.Index(output, "Title: newest post"), strings.Index(output, "Title: older post")
+	if newest == -1 || older == -1 || newest > older {
+		t.Errorf("browse printed %q, want newest post before older one", output)
+	}
+	if strings.Contains(output, "oldest post") {
+		t.Errorf("browse printed %q, want only 2 posts", output)
+	}
+	if strings.Contains(output, "post of bob") {
+		t.Errorf("browse printed %q, want no posts of other users' feeds", output)
+	}
+	if !strings.Contains(output, "Feed source: blog") {
+		t.Errorf("browse printed %q, want feed name of posts", output)
+	}
+}
+
+func TestHandleBrowseInvalidLimit(t *testing.T) {
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+
+	if err := handleBrowse(context.Background(), s, command{name: "browse", args: []string{"many"}}, user); err == nil {
+		t.Error("browse with invalid limit succeeded")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/cron"
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+// Number of latest posts used to estimate how often feed publishes
+const recentPostsWindow = 10
+
+// Upper bound of delay between retries of failing feed
+const maxFailureBackoff = 24 * time.Hour
+
+// Feed is disabled after this many failed fetches in a row
+const maxConsecutiveFailures = 10
+
+// Computes delay until next fetch of feed. New items reset it to feed minimum,
+// quiet fetches grow it by half up to typical gap between recent posts.
+func nextFetchInterval(feed database.Feed, inserted int, recentPosts []time.Time) time.Duration {
+	minInterval := time.Duration(feed.MinFetchIntervalSeconds) * time.Second
+	maxInterval := time.Duration(feed.MaxFetchIntervalSeconds) * time.Second
+
+	if inserted > 0 {
+		return minInterval
+	}
+
+	current := time.Duration(feed.FetchIntervalSeconds) * time.Second
+	next := current + current/2
+	if gap, ok := averagePostGap(recentPosts); ok && gap < next {
+		next = gap
+	}
+
+	return clampDuration(next, minInterval, maxInterval)
+}
+
+// Average time between consecutive posts, posts are expected newest first
+func averagePostGap(posts []time.Time) (time.Duration, bool) {
+	if len(posts) < 2 {
+		return 0, false
+	}
+
+	span := posts[0].Sub(posts[len(posts)-1])
+	if span <= 0 {
+		return 0, false
+	}
+
+	return span / time.Duration(len(posts)-1), true
+}
+
+func clampDuration(value, lower, upper time.Duration) time.Duration {
+	if value < lower {
+		return lower
+	}
+	if value > upper {
+		return upper
+	}
+	return value
+}
+
+// Stores adaptive interval and time of next fetch for feed after scraping it
+func scheduleNextFetch(ctx context.Context, db database.Querier, feed database.Feed, inserted int) (database.Feed, error) {
+	recentPosts, postsErr := db.GetRecentPostTimes(ctx, database.GetRecentPostTimesParams{
+		FeedID: feed.ID,
+		Limit:  recentPostsWindow,
+	})
+	if postsErr != nil {
+		return feed, fmt.Errorf("error getting recent posts of feed %s: %v", feed.Name, postsErr)
+	}
+
+	interval := nextFetchInterval(feed, inserted, recentPosts)
+	nextFetchAt := time.Now().Add(interval)
+
+	// Cron schedule overrides adaptive polling, adaptive interval is still tracked for when it is cleared
+	if feed.CronSchedule.Valid {
+		scheduledAt, cronErr := nextCronFetch(feed.CronSchedule.String, time.Now())
+		if cronErr != nil {
+			return feed, fmt.Errorf("error in schedule of feed %s: %v", feed.Name, cronErr)
+		}
+		nextFetchAt = scheduledAt
+	}
+
+	scheduledFeed, scheduleErr := db.ScheduleFeedFetch(ctx, database.ScheduleFeedFetchParams{
+		ID:                   feed.ID,
+		FetchIntervalSeconds: int32(interval / time.Second),
+		NextFetchAt: sql.NullTime{
+			Time:  nextFetchAt,
+			Valid: true,
+		},
+	})
+	if scheduleErr != nil {
+		return feed, fmt.Errorf("error scheduling next fetch of feed %s: %v", feed.Name, scheduleErr)
+	}
+
+	return scheduledFeed, nil
+}
+
+// Next activation of cron expression after given time
+func nextCronFetch(expr string, after time.Time) (time.Time, error) {
+	schedule, parseErr := cron.Parse(expr)
+	if parseErr != nil {
+		return time.Time{}, parseErr
+	}
+
+	next := schedule.Next(after)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", expr)
+	}
+
+	return next, nil
+}
+
+// Delay before retrying feed which failed given number of times in a row.
+// Doubles from feed minimum interval with +-20% jitter so failing feeds do not retry in lockstep.
+func failureBackoff(feed database.Feed, failures int32) time.Duration {
+	backoff := time.Duration(feed.MinFetchIntervalSeconds) * time.Second
+	for i := int32(1); i < failures && backoff < maxFailureBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, maxFailureBackoff)
+
+	spread := int64(backoff) / 5
+	jitter := time.Duration(rand.Int64N(2*spread+1) - spread)
+
+	return backoff + jitter
+}
+
+// Stores fetch error on feed, pushes its next fetch back and disables it after too many failures
+func recordFetchFailure(ctx context.Context, s *state, feed database.Feed, fetchErr error) (database.Feed, error) {
+	backoff := failureBackoff(feed, feed.ConsecutiveFailures+1)
+
+	failedFeed, recordErr := s.db.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		ID:        feed.ID,
+		LastError: parseToNullString(fetchErr.Error()),
+		NextFetchAt: sql.NullTime{
+			Time:  time.Now().Add(backoff),
+			Valid: true,
+		},
+		MaxFailures: maxConsecutiveFailures,
+	})
+	if recordErr != nil {
+		return feed, fmt.Errorf("error recording failure of feed %s: %v", feed.Name, recordErr)
+	}
+
+	if failedFeed.DisabledAt.Valid {
+		slog.Warn("feed disabled after repeated failures", "feed", failedFeed.Name, "url", failedFeed.Url, "failures", failedFeed.ConsecutiveFailures)
+	}
+
+	return failedFeed, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+func TestNextFetchInterval(t *testing.T) {
+	feed := database.Feed{
+		MinFetchIntervalSeconds: 300,
+		MaxFetchIntervalSeconds: 3600,
+		FetchIntervalSeconds:    1200,
+	}
+	now := time.Now()
+	// newest first, one post every 10 minutes
+	frequent := []time.Time{now, now.Add(-10 * time.Minute), now.Add(-20 * time.Minute)}
+	// one post every 2 minutes, more often than feed minimum
+	burst := []time.Time{now, now.Add(-2 * time.Minute), now.Add(-4 * time.Minute)}
+	rare := []time.Time{now, now.Add(-48 * time.Hour)}
+
+	cases := []struct {
+		name     string
+		current  int32
+		inserted int
+		posts    []time.Time
+		want     time.Duration
+	}{
+		{"new posts reset to minimum", 1200, 3, rare, 5 * time.Minute},
+		{"quiet fetch grows by half", 1200, 0, nil, 30 * time.Minute},
+		{"growth stops at maximum", 3000, 0, nil, time.Hour},
+		{"growth limited by post gap", 1200, 0, frequent, 10 * time.Minute},
+		{"post gap below minimum", 1200, 0, burst, 5 * time.Minute},
+		{"post gap above growth is ignored", 1200, 0, rare, 30 * time.Minute},
+		{"single post gives no gap", 1200, 0, []time.Time{now}, 30 * time.Minute},
+		// interval left below minimum by settings change is raised to it
+		{"interval below minimum", 60, 0, nil, 5 * time.Minute},
+		{"interval above maximum", 7200, 0, nil, time.Hour},
+	}
+
+	for _, tc := range cases {
+		feed.FetchIntervalSeconds = tc.current
+		if got := nextFetchInterval(feed, tc.inserted, tc.posts); got != tc.want {
+			t.Errorf("%s: next interval after %ds is %s, want %s", tc.name, tc.current, got, tc.want)
+		}
+	}
+}
+
+func TestAveragePostGap(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		posts  []time.Time
+		want   time.Duration
+		wantOk bool
+	}{
+		{nil, 0, false},
+		{[]time.Time{now}, 0, false},
+		{[]time.Time{now, now.Add(-time.Hour), now.Add(-3 * time.Hour)}, 90 * time.Minute, true},
+		// posts published at the same time or out of order give no gap
+		{[]time.Time{now, now}, 0, false},
+		{[]time.Time{now.Add(-time.Hour), now}, 0, false},
+	}
+
+	for _, tc := range cases {
+		if got, ok := averagePostGap(tc.posts); got != tc.want || ok != tc.wantOk {
+			t.Errorf("average gap of %d posts is %s (%t), want %s (%t)", len(tc.posts), got, ok, tc.want, tc.wantOk)
+		}
+	}
+}
+
+func TestFailureBackoff(t *testing.T) {
+	feed := database.Feed{MinFetchIntervalSeconds: 300}
+	cases := []struct {
+		failures int32
+		want     time.Duration
+	}{
+		{1, 5 * time.Minute},
+		{2, 10 * time.Minute},
+		{4, 40 * time.Minute},
+		{9, 1280 * time.Minute},
+		{10, maxFailureBackoff},
+		{1000, maxFailureBackoff},
+	}
+
+	for _, tc := range cases {
+		lower, upper := tc.want-tc.want/5, tc.want+tc.want/5
+		for range 100 {
+			if got := failureBackoff(feed, tc.failures); got < lower || got > upper {
+				t.Errorf("backoff after %d failures is %s, want %s +-20%%", tc.failures, got, tc.want)
+				break
+			}
+		}
+	}
+
+	// jitter spreads retries of feeds failing together
+	seen := map[time.Duration]bool{}
+	for range 100 {
+		seen[failureBackoff(feed, 3)] = true
+	}
+	if len(seen) < 2 {
+		t.Error("backoff gave the same delay every time, want jitter")
+	}
+}
+
+func TestRecordFetchFailure(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range map[string]*state{"memory": newTestState(t), "sqlite": newSQLiteTestState(t)} {
+		if name == "sqlite" {
+			if _, err := runMigrate(t, ctx, s, "up"); err != nil {
+				t.Fatalf("migrate up failed: %v", err)
+			}
+		}
+		feed := createTestFeed(t, s, createTestUser(t, s, "alice"), "blog", "https://example.com/feed.xml")
+
+		for i := int32(1); i <= maxConsecutiveFailures; i++ {
+			before := time.Now()
+			failed, err := recordFetchFailure(ctx, s, feed, errors.New("connection refused"))
+			if err != nil {
+				t.Fatalf("%s: error recording failure %d: %v", name, i, err)
+			}
+			if failed.ConsecutiveFailures != i || failed.LastError.String != "connection refused" {
+				t.Errorf("%s: failure %d recorded as %d failures with error %q", name, i, failed.ConsecutiveFailures, failed.LastError.String)
+			}
+			if !failed.NextFetchAt.Valid || failed.NextFetchAt.Time.Before(before.Add(4*time.Minute)) {
+				t.Errorf("%s: failure %d pushed next fetch to %v, want backoff from now", name, i, failed.NextFetchAt)
+			}
+			// feed is disabled exactly when the limit is reached
+			if disabled := failed.DisabledAt.Valid; disabled != (i == maxConsecutiveFailures) {
+				t.Errorf("%s: feed disabled is %t after %d failures, want disabled at %d", name, disabled, i, maxConsecutiveFailures)
+			}
+			feed = failed
+		}
+	}
+}
@@ -4,91 +4,510 @@ import (
 	"context"
 	"database/sql"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"html"
 	"io"
+	"log/slog"
+	"math"
 	"net/http"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/MichalGul/blog_aggregator/internal/credentials"
 	"github.com/MichalGul/blog_aggregator/internal/database"
-	"github.com/google/uuid"
 )
 
-func handleAgg(s *state, cmd command) error {
+// How long in-flight fetches may keep running after shutdown was requested
+const aggShutdownGrace = 15 * time.Second
+
+// Maximum number of due feeds fetched in single scheduler tick
+const dueFeedsBatchSize = 20
+
+// How often agg daemon checks queue of feeds requested with refresh
+const queuePollEvery = 5 * time.Second
+
+// Time limit of downloading single feed, source which stops responding fails instead of hanging agg
+const feedFetchTimeout = time.Minute
+
+// Result of single scrape pass over one feed
+type scrapeResult struct {
+	feed       database.Feed
+	startedAt  time.Time
+	httpStatus int
+	bytes      int64
+	seen       int
+	inserted   int
+	skipped    int
+	err        error
+}
+
+// Details of HTTP exchange with feed source
+type fetchStats struct {
+	httpStatus int
+	bytes      int64
+}
+
+// Totals collected by agg, printed on exit
+type aggSummary struct {
+	fetches  int
+	failures int
+	inserted int
+	skipped  int
+}
+
+func (a *aggSummary) record(result scrapeResult) {
+	a.fetches++
+	a.inserted += result.inserted
+	a.skipped += result.skipped
+	if result.err != nil {
+		a.failures++
+	}
+}
+
+// Records results of scrape pass and reports failed feeds
+func (a *aggSummary) recordAll(results []scrapeResult) {
+	for _, result := range results {
+		a.record(result)
+		if result.err != nil {
+			slog.Error("error scraping feed", "feed", result.feed.Name, "url", result.feed.Url, "error", result.err)
+		}
+	}
+}
+
+func (a *aggSummary) print() {
+	fmt.Printf("Aggregation stopped \n")
+	fmt.Printf("Fetches: %d, failed: %d \n", a.fetches, a.failures)
+	fmt.Printf("Posts added: %d, duplicates skipped: %d \n", a.inserted, a.skipped)
+}
+
+// Options of agg command parsed from its arguments
+type aggOptions struct {
+	interval    time.Duration
+	once        bool
+	all         bool
+	feedUrl     string
+	metricsAddr string
+	// how often daemon applies post retention, zero disables it
+	pruneEvery time.Duration
+}
+
+func parseAggOptions(cmdName string, args []string) (aggOptions, error) {
+	usageErr := fmt.Errorf("usage: %v <time_between_reqs> [--metrics <addr>] [--prune <interval>] | %v --once [--all] | %v --feed <url>", cmdName, cmdName, cmdName)
+	opts := aggOptions{}
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--once":
+			opts.once = true
+		case "--all":
+			opts.all = true
+		case "--feed":
+			if i+1 >= len(args) {
+				return aggOptions{}, fmt.Errorf("--feed expects feed url")
+			}
+			i++
+			opts.feedUrl = args[i]
+		case "--metrics":
+			if i+1 >= len(args) {
+				return aggOptions{}, fmt.Errorf("--metrics expects listen address eg. :9090")
+			}
+			i++
+			opts.metricsAddr = args[i]
+		case "--prune":
+			if i+1 >= len(args) {
+				return aggOptions{}, fmt.Errorf("--prune expects interval eg. 24h")
+			}
+			i++
+			pruneEvery, parseErr := time.ParseDuration(args[i])
+			if parseErr != nil || pruneEvery <= 0 {
+				return aggOptions{}, fmt.Errorf("invalid prune interval %s", args[i])
+			}
+			opts.pruneEvery = pruneEvery
+		default:
+			if opts.interval != 0 {
+				return aggOptions{}, usageErr
+			}
+			parsedTime, parseErr := time.ParseDuration(args[i])
+			if parseErr != nil {
+				return aggOptions{}, fmt.Errorf("invalid interval %s: %v", args[i], parseErr)
+			}
+			if parsedTime <= 0 {
+				return aggOptions{}, fmt.Errorf("interval must be positive, got %s", args[i])
+			}
+			opts.interval = parsedTime
+		}
+	}
+
+	if opts.feedUrl != "" {
+		opts.once = true
+	}
+	if opts.all && opts.feedUrl != "" {
+		return aggOptions{}, fmt.Errorf("--all and --feed can not be used together")
+	}
+	if opts.all && !opts.once {
+		return aggOptions{}, fmt.Errorf("--all can be used only with --once")
+	}
+	if !opts.once && opts.interval == 0 {
+		return aggOptions{}, usageErr
+	}
+	if opts.once && opts.pruneEvery != 0 {
+		return aggOptions{}, fmt.Errorf("--prune can be used only when agg runs as daemon")
+	}
+
+	return opts, nil
+}
+
+func handleAgg(ctx context.Context, s *state, cmd command) error {
 	// time_between_reqs interval feed 1s 1m 1h
-	// var feedStr string = "https://www.wagslane.dev/index.xml"
-	// ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	// defer cancel()
 
-	if len(cmd.args) < 1 || len(cmd.args) > 2 {
-		return fmt.Errorf("agg command expects one argument of time aggregation interval: %v <timme_between_reqs>", cmd.name)
+	opts, optsErr := parseAggOptions(cmd.name, cmd.args)
+	if optsErr != nil {
+		return optsErr
 	}
 
-	time_between_reqs := cmd.args[0]
-	parsedTime, _ := time.ParseDuration(time_between_reqs)
+	// Fetches get their own context so a shutdown lets them finish within the grace period
+	fetchCtx, cancelFetch := withShutdownGrace(ctx, aggShutdownGrace)
+	defer cancelFetch()
+
+	if opts.metricsAddr != "" {
+		stopMetrics, metricsErr := startMetricsServer(s, opts.metricsAddr)
+		if metricsErr != nil {
+			return metricsErr
+		}
+		defer stopMetrics()
+	}
+
+	return runAggInstance(ctx, fetchCtx, s, !opts.once, func(instance aggInstance) error {
+		if !opts.once {
+			return aggLoop(ctx, fetchCtx, s, instance, opts.interval, opts.pruneEvery)
+		}
+
+		feeds, claimErr := claimOnceFeeds(fetchCtx, s, instance, opts)
+		if claimErr != nil {
+			return claimErr
+		}
+		return aggOnce(ctx, fetchCtx, s, feeds)
+	})
+}
+
+// Scheduler of agg daemon. Queued refresh requests are served first, due feeds every interval
+// and feeds with cron schedule whenever their time comes. Post retention is applied every pruneEvery when set
+func aggLoop(ctx, fetchCtx context.Context, s *state, instance aggInstance, interval, pruneEvery time.Duration) error {
+	slog.Info("checking for due feeds", "every", interval.String())
+
+	summary := aggSummary{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	queueTicker := time.NewTicker(queuePollEvery)
+	defer queueTicker.Stop()
+	lastPrune := time.Time{}
+	lastPostPrune := time.Time{}
+	fetchDue := true
 
-	fmt.Printf("Collecting feeds every: %s \n", parsedTime)
+	for {
+		s.metrics.Tick()
 
-	ticker := time.NewTicker(parsedTime)
-	for ; ; <-ticker.C {
-		scrapeFeeds(s)
+		if time.Since(lastPrune) >= fetchRunsPruneEvery {
+			logPruneFetchRuns(fetchCtx, s)
+			lastPrune = time.Now()
+		}
+		if pruneEvery > 0 && time.Since(lastPostPrune) >= pruneEvery {
+			prunePostsInBackground(fetchCtx, s)
+			lastPostPrune = time.Now()
+		}
+
+		queuedFeeds, queueErr := s.db.ClaimQueuedFeeds(fetchCtx, database.ClaimQueuedFeedsParams{
+			MaxFeeds:     dueFeedsBatchSize,
+			InstanceID:   instance.claimID(),
+			LeaseSeconds: int32(feedClaimLease / time.Second),
+		})
+		if queueErr != nil {
+			slog.Error("error claiming queued feeds", "error", queueErr)
+		}
+		summary.recordAll(scrapeFeeds(fetchCtx, s, queuedFeeds, ctx.Done()))
+
+		// Between agg intervals only feeds with cron schedule are checked, so schedules
+		// finer than the interval are still honored
+		dueFeeds, dueErr := s.db.ClaimDueFeeds(fetchCtx, database.ClaimDueFeedsParams{
+			ScheduledOnly: !fetchDue,
+			MaxFeeds:      dueFeedsBatchSize,
+			InstanceID:    instance.claimID(),
+			LeaseSeconds:  int32(feedClaimLease / time.Second),
+		})
+		if dueErr != nil {
+			slog.Error("error claiming feeds due to fetch", "error", dueErr)
+		}
+		summary.recordAll(scrapeFeeds(fetchCtx, s, dueFeeds, ctx.Done()))
+		fetchDue = false
+
+		select {
+		case <-ctx.Done():
+			summary.print()
+			return nil
+		case <-ticker.C:
+			fetchDue = true
+		case <-queueTicker.C:
+		}
 	}
 
 }
 
-func scrapeFeeds(s *state) error {
+// Claims feeds selected by agg --once options
+func claimOnceFeeds(ctx context.Context, s *state, instance aggInstance, opts aggOptions) ([]database.Feed, error) {
+	var feeds []database.Feed
+	var feedsErr error
+	leaseSeconds := int32(feedClaimLease / time.Second)
 
-	nextFeed, err := s.db.GetNextFeedToFetch(context.Background())
-	if err != nil {
-		return fmt.Errorf("error getting next feed to fetch: %v", err)
+	switch {
+	case opts.feedUrl != "":
+		feed, feedErr := s.db.GetFeedByUrl(ctx, opts.feedUrl)
+		if feedErr != nil {
+			return nil, fmt.Errorf("error getting feed %s: %v", opts.feedUrl, feedErr)
+		}
+		feeds = claimFeeds(ctx, s, instance, []database.Feed{feed})
+		if len(feeds) == 0 {
+			return nil, fmt.Errorf("feed %s could not be claimed for fetching", opts.feedUrl)
+		}
+	case opts.all:
+		feeds, feedsErr = s.db.ClaimActiveFeeds(ctx, database.ClaimActiveFeedsParams{
+			InstanceID:   instance.claimID(),
+			LeaseSeconds: leaseSeconds,
+		})
+	default:
+		feeds, feedsErr = s.db.ClaimDueFeeds(ctx, database.ClaimDueFeedsParams{
+			MaxFeeds:     math.MaxInt32,
+			InstanceID:   instance.claimID(),
+			LeaseSeconds: leaseSeconds,
+		})
+	}
+	if feedsErr != nil {
+		return nil, fmt.Errorf("error claiming feeds to fetch: %v", feedsErr)
 	}
 
-	nextFeed, markErr := s.db.MarkFeedFetched(context.Background(), nextFeed.ID)
-	if markErr != nil {
-		return fmt.Errorf("error marking feed as fetched %s: %v", nextFeed.Name, markErr)
+	return feeds, nil
+}
+
+// Single aggregation pass over claimed feeds. Returns error when any of them failed.
+// Fetch history is pruned after the pass as without daemon nothing else does it
+func aggOnce(ctx, fetchCtx context.Context, s *state, feeds []database.Feed) error {
+	summary := aggSummary{}
+	summary.recordAll(scrapeFeeds(fetchCtx, s, feeds, ctx.Done()))
+	summary.print()
+	logPruneFetchRuns(fetchCtx, s)
+
+	if summary.failures > 0 {
+		return fmt.Errorf("%d of %d feeds failed to fetch", summary.failures, summary.fetches)
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("aggregation interrupted before all %d feeds were fetched", len(feeds))
+	}
+
+	return nil
+}
+
+// Claims given feeds one by one, feeds which another instance is fetching right now are skipped
+func claimFeeds(ctx context.Context, s *state, instance aggInstance, feeds []database.Feed) []database.Feed {
+	claimed := make([]database.Feed, 0, len(feeds))
+
+	for _, feed := range feeds {
+		claimedFeed, claimErr := s.db.ClaimFeed(ctx, database.ClaimFeedParams{
+			InstanceID:   instance.claimID(),
+			LeaseSeconds: int32(feedClaimLease / time.Second),
+			ID:           feed.ID,
+		})
+		if errors.Is(claimErr, sql.ErrNoRows) {
+			slog.Warn("skipping feed fetched by another agg instance", "feed", feed.Name)
+			continue
+		}
+		if claimErr != nil {
+			slog.Error("error claiming feed", "feed", feed.Name, "error", claimErr)
+			continue
+		}
+		claimed = append(claimed, claimedFeed)
+	}
+
+	return claimed
+}
+
+// Returns context which is cancelled only after grace period passes since parent was cancelled
+func withShutdownGrace(parent context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	stopAfter := context.AfterFunc(parent, func() {
+		time.AfterFunc(grace, cancel)
+	})
+
+	return ctx, func() {
+		stopAfter()
+		cancel()
+	}
+}
+
+// Scrapes given feeds one by one. No new fetch is started once shutdown is closed and
+// claims of feeds not started yet are released right away, not when the running fetch ends
+func scrapeFeeds(ctx context.Context, s *state, feeds []database.Feed, shutdown <-chan struct{}) []scrapeResult {
+	var mu sync.Mutex
+	started := 0
+
+	done := make(chan struct{})
+	released := make(chan struct{})
+	go func() {
+		defer close(released)
+		select {
+		case <-shutdown:
+		case <-done:
+			return
+		}
+		mu.Lock()
+		unstarted := feeds[started:]
+		mu.Unlock()
+		for _, feed := range unstarted {
+			releaseFeedClaim(ctx, s, feed)
+		}
+	}()
+	defer func() {
+		close(done)
+		<-released
+	}()
+
+	results := make([]scrapeResult, 0, len(feeds))
+	for i, feed := range feeds {
+		mu.Lock()
+		select {
+		case <-shutdown:
+			mu.Unlock()
+			return results
+		default:
+		}
+		started = i + 1
+		mu.Unlock()
+		result := scrapeFeed(ctx, s, feed)
+		s.metrics.ObserveFetch(fetchOutcome(result), time.Since(result.startedAt), result.inserted, result.skipped)
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// Fetches single claimed feed, stores its new posts, schedules its next fetch and releases the claim
+func scrapeFeed(ctx context.Context, s *state, markedFeed database.Feed) scrapeResult {
+	defer releaseFeedClaim(ctx, s, markedFeed)
+
+	result := scrapeResult{feed: markedFeed, startedAt: time.Now()}
+	result.err = storeFeedPosts(ctx, s, &result)
+	recordFetchRun(ctx, s, result)
+
+	if result.err != nil {
+		// Interrupted fetch is not feed's fault, it stays due for next run
+		if ctx.Err() != nil {
+			return result
+		}
+
+		failedFeed, recordErr := recordFetchFailure(ctx, s, markedFeed, result.err)
+		if recordErr != nil {
+			slog.Error("error recording feed failure", "feed", markedFeed.Name, "error", recordErr)
+		}
+		result.feed = failedFeed
+	}
+
+	return result
+}
+
+// Downloads feed and in single transaction inserts its items as posts, records the successful fetch
+// and schedules the next one. Counts of seen, inserted and duplicate posts are stored in result
+func storeFeedPosts(ctx context.Context, s *state, result *scrapeResult) error {
+	nextFeed := result.feed
+
+	creds, credsErr := loadFeedCredentials(ctx, s, nextFeed.ID)
+	if credsErr != nil {
+		return credsErr
 	}
 
-	rssFeed, feedErr := fetchFeed(context.Background(), nextFeed.Url)
+	rssFeed, stats, feedErr := fetchFeed(ctx, nextFeed.Url, creds)
+	result.httpStatus = stats.httpStatus
+	result.bytes = stats.bytes
 	if feedErr != nil {
 		return fmt.Errorf("error fetching feed %s: %v", nextFeed.Url, feedErr)
 	}
+	result.seen = len(rssFeed.Channel.Item)
 
-	fmt.Printf("Aggregated items in Feed:")
-	fmt.Printf("------------------------------------------------------\n")
+	logger := slog.With("feed", nextFeed.Name, "url", nextFeed.Url)
+	logger.Info("aggregating feed items", "items", result.seen)
 
-	for i := range rssFeed.Channel.Item {
-		fmt.Printf("Adding post: %s \n", rssFeed.Channel.Item[i].Title)
-		post, createErr := s.db.CreatePost(context.Background(), database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       rssFeed.Channel.Item[i].Title,
-			Url:         rssFeed.Channel.Item[i].Link,
-			Description: parseToNullString(rssFeed.Channel.Item[i].Description),
-			PublishedAt: parseStringToNullTime(rssFeed.Channel.Item[i].PubDate),
-			FeedID:      nextFeed.ID,
-		})
-		if createErr != nil {
-			// Check if it's a duplicate URL error
-			if strings.Contains(createErr.Error(), "duplicate key") && strings.Contains(createErr.Error(), "url") {
-				// This is a duplicate URL - just ignore it as per requirements
-				fmt.Printf("Skipping duplicate post: %s\n", rssFeed.Channel.Item[i].Link)
-				continue // Continue to the next post
-			}
+	// Links may be resolved over network so it happens before the transaction
+	postUrls := canonicalPostUrls(ctx, s, nextFeed, rssFeed.Channel.Item)
 
-			// It's a different kind of error - log it
-			fmt.Printf("Error creating post %s: %v\n", rssFeed.Channel.Item[i].Title, err)
-			// You might want to continue anyway to process other posts
-			continue
+	var posts []database.Post
+	scheduledFeed := nextFeed
+	txErr := s.db.InTx(ctx, func(qtx database.Querier) error {
+		// Items this feed already stored under same url are skipped by the database,
+		// other feeds linking the same page keep their own copy
+		inserted, insertErr := qtx.CreatePosts(ctx, newPostBatch(nextFeed, rssFeed.Channel.Item, postUrls))
+		if insertErr != nil {
+			return fmt.Errorf("error storing posts of feed %s: %v", nextFeed.Name, insertErr)
 		}
+		posts = inserted
+
+		if successErr := qtx.RecordFeedSuccess(ctx, nextFeed.ID); successErr != nil {
+			return fmt.Errorf("error recording success of feed %s: %v", nextFeed.Name, successErr)
+		}
+
+		var scheduleErr error
+		scheduledFeed, scheduleErr = scheduleNextFetch(ctx, qtx, nextFeed, len(posts))
+		return scheduleErr
+	})
+	if txErr != nil {
+		return txErr
+	}
 
-		fmt.Printf("Successfuly added post: %s \n", post.Title)
+	result.feed = scheduledFeed
+	result.inserted = len(posts)
+	result.skipped = result.seen - result.inserted
+	for _, post := range posts {
+		logger.Debug("successfuly added post", "title", post.Title)
 	}
 
 	return nil
 }
 
+// Parameters inserting all feed items as posts with one statement. Posts are stored under
+// canonical URL given for each item, items with empty URL are left out
+func newPostBatch(feed database.Feed, items []RSSItem, urls []string) database.CreatePostsParams {
+	batch := database.CreatePostsParams{
+		CreatedAt:    time.Now(),
+		FeedID:       feed.ID,
+		Titles:       make([]string, 0, len(items)),
+		Urls:         make([]string, 0, len(items)),
+		Descriptions: make([]string, 0, len(items)),
+		PublishedAts: make([]string, 0, len(items)),
+		Contents:     make([]string, 0, len(items)),
+		OriginalUrls: make([]string, 0, len(items)),
+	}
+
+	for i, item := range items {
+		if urls[i] == "" {
+			continue
+		}
+
+		// Empty description and publish date are stored as NULL, dates are stored in UTC
+		publishedAt := ""
+		if parsedTime := parseStringToNullTime(item.PubDate); parsedTime.Valid {
+			publishedAt = parsedTime.Time.UTC().Format(time.RFC3339Nano)
+		}
+
+		batch.Titles = append(batch.Titles, item.Title)
+		batch.Urls = append(batch.Urls, urls[i])
+		batch.Descriptions = append(batch.Descriptions, item.Description)
+		batch.PublishedAts = append(batch.PublishedAts, publishedAt)
+		batch.Contents = append(batch.Contents, htmlToText(item.Content))
+		batch.OriginalUrls = append(batch.OriginalUrls, item.Link)
+	}
+
+	return batch
+}
+
 func parseToNullString(input string) sql.NullString {
 	if input != "" {
 		return sql.NullString{
@@ -123,7 +542,7 @@ func parseStringToNullTime(input string) sql.NullTime {
 			}
 		} else {
 			// Log the error but continue
-			fmt.Printf("Failed to parse date %s: %v\n", input, err)
+			slog.Warn("failed to parse date", "date", input, "error", err)
 			return sql.NullTime{Valid: false}
 		}
 
@@ -132,31 +551,39 @@ func parseStringToNullTime(input string) sql.NullTime {
 	}
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+func fetchFeed(ctx context.Context, feedURL string, creds credentials.Credentials) (*RSSFeed, fetchStats, error) {
+	stats := fetchStats{}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
-		return &RSSFeed{}, fmt.Errorf("error creating request %v", err)
+		return &RSSFeed{}, stats, fmt.Errorf("error creating request %v", err)
 	}
 	req.Header.Set("User-Agent", "gator")
+	creds.Apply(req)
 
-	httpClient := &http.Client{}
+	httpClient := &http.Client{Timeout: feedFetchTimeout, CheckRedirect: creds.CheckRedirect}
 
 	resp, resp_err := httpClient.Do(req)
 	if resp_err != nil {
-		return &RSSFeed{}, fmt.Errorf("error getting response %v", resp_err)
+		return &RSSFeed{}, stats, fmt.Errorf("error getting response %v", resp_err)
 	}
 	defer resp.Body.Close()
+	stats.httpStatus = resp.StatusCode
 
 	byteArray, read_err := io.ReadAll(resp.Body)
+	stats.bytes = int64(len(byteArray))
 	if read_err != nil {
-		return &RSSFeed{}, fmt.Errorf("error reading response %v", read_err)
+		return &RSSFeed{}, stats, fmt.Errorf("error reading response %v", read_err)
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return &RSSFeed{}, stats, fmt.Errorf("unexpected response status %s", resp.Status)
 	}
 
 	rssFeed := RSSFeed{}
 	unmarshallErr := xml.Unmarshal(byteArray, &rssFeed)
 	if unmarshallErr != nil {
-		return &RSSFeed{}, fmt.Errorf("error unmarshalling response %v", unmarshallErr)
+		return &RSSFeed{}, stats, fmt.Errorf("error unmarshalling response %v", unmarshallErr)
 
 	}
 
@@ -168,5 +595,13 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 		rssFeed.Channel.Item[i].Description = html.UnescapeString(rssFeed.Channel.Item[i].Description)
 	}
 
-	return &rssFeed, nil
+	return &rssFeed, stats, nil
+}
+
+var htmlTagRegexp = regexp.MustCompile(`<[^>]*>`)
+
+// Plain text of HTML content, only words are needed for search and its snippets
+func htmlToText(content string) string {
+	text := html.UnescapeString(htmlTagRegexp.ReplaceAllString(content, " "))
+	return strings.Join(strings.Fields(text), " ")
 }
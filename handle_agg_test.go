@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+<title>Blog</title>
+<link>https://example.com</link>
+<item><title>First</title><link>https://example.com/1?utm_source=rss</link><pubDate>Mon, 01 Jan 2024 00:00:00 +0000</pubDate></item>
+<item><title>Second</title><link>https://example.com/2</link><pubDate>Tue, 02 Jan 2024 00:00:00 +0000</pubDate></item>
+</channel></rss>`
+
+func TestScrapeFeeds(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken.xml" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, testRSS)
+	}))
+	defer server.Close()
+
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+	feed := createTestFeed(t, s, user, "blog", server.URL+"/feed.xml")
+	broken := createTestFeed(t, s, user, "broken", server.URL+"/broken.xml")
+
+	results := scrapeFeeds(ctx, s, []database.Feed{feed, broken}, make(chan struct{}))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].err != nil || results[0].inserted != 2 || results[0].skipped != 0 {
+		t.Errorf("first scrape of feed inserted %d, skipped %d (%v), want 2 new posts", results[0].inserted, results[0].skipped, results[0].err)
+	}
+	if results[1].err == nil || results[1].httpStatus != http.StatusServiceUnavailable {
+		t.Errorf("scrape of broken feed returned status %d (%v), want failure with 503", results[1].httpStatus, results[1].err)
+	}
+
+	posts, err := s.db.GetPostForUser(ctx, database.GetPostForUserParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("error getting posts: %v", err)
+	}
+	if len(posts) != 2 || posts[0].Title != "Second" {
+		t.Fatalf("stored posts %+v, want 2 newest first", posts)
+	}
+	if posts[1].Url != "https://example.com/1" {
+		t.Errorf("post stored under %s, want tracking parameters removed", posts[1].Url)
+	}
+
+	stored, err := s.db.GetFeedById(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("error getting feed: %v", err)
+	}
+	if !stored.NextFetchAt.Valid || !stored.LastSucceededAt.Valid {
+		t.Errorf("scraped feed has next fetch %v and last success %v, want both set", stored.NextFetchAt, stored.LastSucceededAt)
+	}
+	failed, err := s.db.GetFeedById(ctx, broken.ID)
+	if err != nil {
+		t.Fatalf("error getting feed: %v", err)
+	}
+	if failed.ConsecutiveFailures != 1 {
+		t.Errorf("broken feed has %d consecutive failures, want 1", failed.ConsecutiveFailures)
+	}
+
+	// Posts already stored are skipped on the next pass
+	again := scrapeFeeds(ctx, s, []database.Feed{stored}, make(chan struct{}))
+	if len(again) != 1 || again[0].err != nil || again[0].inserted != 0 || again[0].skipped != 2 {
+		t.Errorf("second scrape returned %+v, want both posts skipped", again)
+	}
+}
+
+func TestScrapeFeedsStopsOnShutdown(t *testing.T) {
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+	feed := createTestFeed(t, s, user, "blog", "http://127.0.0.1:1/feed.xml")
+
+	shutdown := make(chan struct{})
+	close(shutdown)
+	if results := scrapeFeeds(context.Background(), s, []database.Feed{feed}, shutdown); len(results) != 0 {
+		t.Errorf("got %d results after shutdown, want none", len(results))
+	}
+}
+
+func TestAggOncePrunesFetchHistory(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	feed := createTestFeed(t, s, createTestUser(t, s, "alice"), "blog", "https://example.com/feed.xml")
+	for _, startedAt := range []time.Time{time.Now().Add(-fetchRunsRetention - time.Hour), time.Now().Add(-time.Hour)} {
+		if _, err := s.db.CreateFetchRun(ctx, database.CreateFetchRunParams{
+			ID:         uuid.New(),
+			FeedID:     feed.ID,
+			StartedAt:  startedAt,
+			FinishedAt: startedAt,
+		}); err != nil {
+			t.Fatalf("error creating fetch run: %v", err)
+		}
+	}
+
+	captureOutput(t, func() {
+		if err := aggOnce(ctx, ctx, s, nil); err != nil {
+			t.Fatalf("agg pass failed: %v", err)
+		}
+	})
+	runs, err := s.db.GetFetchRuns(ctx, database.GetFetchRunsParams{MaxRuns: 10})
+	if err != nil {
+		t.Fatalf("error getting fetch runs: %v", err)
+	}
+	if len(runs) != 1 || runs[0].StartedAt.Before(time.Now().Add(-fetchRunsRetention)) {
+		t.Errorf("fetch history after agg pass is %+v, want only recent run", runs)
+	}
+}
+
+func TestParseAggOptions(t *testing.T) {
+	valid := []struct {
+		args []string
+		want aggOptions
+	}{
+		{[]string{"1m"}, aggOptions{interval: time.Minute}},
+		{[]string{"30s", "--metrics", ":9090", "--prune", "24h"}, aggOptions{interval: 30 * time.Second, metricsAddr: ":9090", pruneEvery: 24 * time.Hour}},
+		{[]string{"--once"}, aggOptions{once: true}},
+		{[]string{"--once", "--all"}, aggOptions{once: true, all: true}},
+		// --feed implies single pass
+		{[]string{"--feed", "https://example.com/feed.xml"}, aggOptions{once: true, feedUrl: "https://example.com/feed.xml"}},
+	}
+	for _, tc := range valid {
+		if got, err := parseAggOptions("agg", tc.args); err != nil || got != tc.want {
+			t.Errorf("%q parsed to %+v (%v), want %+v", tc.args, got, err, tc.want)
+		}
+	}
+
+	invalid := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{}, "usage"},
+		{[]string{"1m", "2m"}, "usage"},
+		{[]string{"--metrics", ":9090"}, "usage"},
+		{[]string{"soon"}, "invalid interval"},
+		{[]string{"-1m"}, "must be positive"},
+		{[]string{"0s"}, "must be positive"},
+		{[]string{"--all"}, "only with --once"},
+		{[]string{"1m", "--all"}, "only with --once"},
+		{[]string{"--all", "--feed", "https://example.com/feed.xml"}, "can not be used together"},
+		{[]string{"--once", "--all", "--feed", "https://example.com/feed.xml"}, "can not be used together"},
+		{[]string{"--once", "--prune", "24h"}, "only when agg runs as daemon"},
+		{[]string{"--feed", "https://example.com/feed.xml", "--prune", "24h"}, "only when agg runs as daemon"},
+		{[]string{"1m", "--prune", "0s"}, "invalid prune interval"},
+		{[]string{"1m", "--prune"}, "expects interval"},
+		{[]string{"--feed"}, "expects feed url"},
+		{[]string{"1m", "--metrics"}, "expects listen address"},
+	}
+	for _, tc := range invalid {
+		if got, err := parseAggOptions("agg", tc.args); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%q parsed to %+v (%v), want error with %q", tc.args, got, err, tc.wantErr)
+		}
+	}
+}
+
+func TestScrapeFeedsReleasesClaimsOnShutdown(t *testing.T) {
+	ctx := context.Background()
+	shutdown := make(chan struct{})
+	finish := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// shutdown starts while the first fetch is running
+		close(shutdown)
+		<-finish
+		fmt.Fprint(w, testRSS)
+	}))
+	defer server.Close()
+
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+	instance := newAggInstance(true)
+	if err := instance.heartbeat(ctx, s); err != nil {
+		t.Fatalf("error registering instance: %v", err)
+	}
+	feeds := claimFeeds(ctx, s, instance, []database.Feed{
+		createTestFeed(t, s, user, "slow", server.URL+"/slow.xml"),
+		createTestFeed(t, s, user, "second", server.URL+"/second.xml"),
+		createTestFeed(t, s, user, "third", server.URL+"/third.xml"),
+	})
+	if len(feeds) != 3 {
+		t.Fatalf("claimed %d feeds, want 3", len(feeds))
+	}
+
+	scraped := make(chan []scrapeResult)
+	go func() { scraped <- scrapeFeeds(ctx, s, feeds, shutdown) }()
+
+	// unstarted feeds are released before the running fetch finishes
+	<-shutdown
+	deadline := time.Now().Add(5 * time.Second)
+	for _, feed := range feeds[1:] {
+		for {
+			stored, err := s.db.GetFeedById(ctx, feed.ID)
+			if err != nil {
+				t.Fatalf("error getting feed: %v", err)
+			}
+			if !stored.ClaimedBy.Valid {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("feed %s is still claimed during shutdown", feed.Name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	running, err := s.db.GetFeedById(ctx, feeds[0].ID)
+	if err != nil || !running.ClaimedBy.Valid {
+		t.Errorf("feed being fetched has claim %v (%v), want it kept until fetch ends", running.ClaimedBy, err)
+	}
+
+	close(finish)
+	results := <-scraped
+	if len(results) != 1 || results[0].err != nil || results[0].inserted != 2 {
+		t.Errorf("got results %+v, want running fetch finished", results)
+	}
+}
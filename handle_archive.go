@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/MichalGul/blog_aggregator/internal/archive"
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+func handleExport(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 || cmd.args[0] != "--out" {
+		return fmt.Errorf("export command expects arguments: --out <archive.json|archive.json.gz>")
+	}
+	path := cmd.args[1]
+
+	// Reading in one transaction keeps rows consistent with each other
+	var exported archive.Archive
+	txErr := s.db.InTx(ctx, func(qtx database.Querier) error {
+		var exportErr error
+		exported, exportErr = archive.Export(ctx, qtx)
+		return exportErr
+	})
+	if txErr != nil {
+		return fmt.Errorf("error exporting database: %v", txErr)
+	}
+
+	if writeErr := archive.WriteFile(path, exported); writeErr != nil {
+		return fmt.Errorf("error writing archive %s: %v", path, writeErr)
+	}
+
+	count := exported.Count()
+	fmt.Printf("Exported %d users, %d feeds, %d posts, %d follows, %d starred posts and %d tokens to %s \n",
+		count.Users, count.Feeds, count.Posts, count.Follows, count.Starred, count.Tokens, path)
+	return nil
+}
+
+func handleImport(ctx context.Context, s *state, cmd command, user database.User) error {
+	usageErr := fmt.Errorf("import command expects arguments: <archive file> [--mode merge|replace]")
+	if len(cmd.args) != 1 && (len(cmd.args) != 3 || cmd.args[1] != "--mode") {
+		return usageErr
+	}
+	path := cmd.args[0]
+	mode := archive.Merge
+	if len(cmd.args) == 3 {
+		mode = archive.Mode(cmd.args[2])
+		if mode != archive.Merge && mode != archive.Replace {
+			return usageErr
+		}
+	}
+
+	imported, readErr := archive.ReadFile(path)
+	if readErr != nil {
+		return fmt.Errorf("error reading archive %s: %v", path, readErr)
+	}
+
+	var added archive.Stats
+	txErr := s.db.InTx(ctx, func(qtx database.Querier) error {
+		var importErr error
+		added, importErr = archive.Import(ctx, qtx, imported, mode)
+		return importErr
+	})
+	if txErr != nil {
+		return fmt.Errorf("error importing archive %s: %v", path, txErr)
+	}
+
+	count := imported.Count()
+	fmt.Printf("Imported archive %s exported at %s (%s mode) \n", path, imported.ExportedAt.Format("2006-01-02 15:04:05"), mode)
+	fmt.Printf("Users: %d of %d added \n", added.Users, count.Users)
+	fmt.Printf("Feeds: %d of %d added, %d with credentials \n", added.Feeds, count.Feeds, added.Credentials)
+	fmt.Printf("Posts: %d of %d added \n", added.Posts, count.Posts)
+	fmt.Printf("Follows: %d of %d added \n", added.Follows, count.Follows)
+	fmt.Printf("Starred posts: %d of %d added \n", added.Starred, count.Starred)
+	fmt.Printf("Tokens: %d of %d added \n", added.Tokens, count.Tokens)
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Maximum number of audit entries printed by audit unless --limit is given
+const auditDefaultLimit = 50
+
+// How long writing audit entry may take after command finished
+const auditWriteTimeout = 5 * time.Second
+
+// Replaces value of secret flags so audit log and debug logs never contain them
+const redacted = "[REDACTED]"
+
+// Flags whose following argument is a secret
+var secretFlags = map[string]bool{
+	"--basic-password": true,
+	"--bearer":         true,
+	"--header":         true,
+}
+
+// Commands whose arguments are all secrets
+var secretCommands = map[string]bool{
+	"login": true,
+}
+
+// Copy of command arguments with secrets redacted. Headers keep their name
+func redactArgs(cmd command) []string {
+	redactedArgs := make([]string, len(cmd.args))
+	copy(redactedArgs, cmd.args)
+	if secretCommands[cmd.name] {
+		for i, arg := range redactedArgs {
+			// - only tells that secret was read from stdin
+			if arg != "-" {
+				redactedArgs[i] = redacted
+			}
+		}
+		return redactedArgs
+	}
+
+	for i := 0; i+1 < len(redactedArgs); i++ {
+		if !secretFlags[redactedArgs[i]] {
+			continue
+		}
+		i++
+		if name, _, isHeader := strings.Cut(redactedArgs[i], ":"); isHeader && redactedArgs[i-1] == "--header" {
+			redactedArgs[i] = name + ": " + redacted
+		} else {
+			redactedArgs[i] = redacted
+		}
+	}
+	return redactedArgs
+}
+
+// Records every command run through the wrapped runner in audit log. migrate is
+// skipped as audit_log table may not exist yet. Failure to record is only reported
+func middlewareAudit(run func(context.Context, *state, command) error) func(context.Context, *state, command) error {
+
+	return func(ctx context.Context, s *state, cmd command) error {
+		if cmd.name == "migrate" {
+			return run(ctx, s, cmd)
+		}
+
+		// User is taken before running as login changes it, commands run without session have none.
+		// Entry is linked by id so it stays with the user after rename, and keeps only the name
+		// when the command deleted the user
+		userID, userName := uuid.NullUUID{}, sql.NullString{}
+		if user, userErr := sessionUser(ctx, s); userErr == nil {
+			userID, userName = uuid.NullUUID{UUID: user.ID, Valid: true}, parseToNullString(user.Name)
+		}
+		startedAt := time.Now()
+		cmdErr := run(ctx, s, cmd)
+
+		args, marshalErr := json.Marshal(redactArgs(cmd))
+		if marshalErr != nil {
+			slog.Error("error encoding audited arguments", "command", cmd.name, "error", marshalErr)
+			return cmdErr
+		}
+		entryErr := sql.NullString{}
+		if cmdErr != nil {
+			entryErr = parseToNullString(cmdErr.Error())
+		}
+
+		// Interrupted commands are recorded too, so the write does not use cancelled context
+		writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditWriteTimeout)
+		defer cancel()
+		createErr := s.db.CreateAuditEntry(writeCtx, database.CreateAuditEntryParams{
+			ID:         uuid.New(),
+			CreatedAt:  startedAt,
+			UserID:     userID,
+			UserName:   userName,
+			Command:    cmd.name,
+			Args:       string(args),
+			Succeeded:  cmdErr == nil,
+			Error:      entryErr,
+			DurationMs: time.Since(startedAt).Milliseconds(),
+		})
+		if createErr != nil {
+			slog.Warn("error recording audit entry", "command", cmd.name, "error", createErr)
+		}
+		return cmdErr
+	}
+}
+
+func handleAudit(ctx context.Context, s *state, cmd command, user database.User) error {
+	usageErr := fmt.Errorf("audit command expects arguments: [--user <name>] [--since <24h|2006-01-02>] [--limit <n>]")
+	userName := sql.NullString{}
+	since := time.Time{}
+	limit := auditDefaultLimit
+
+	for i := 0; i < len(cmd.args); i++ {
+		flag := cmd.args[i]
+		if i+1 >= len(cmd.args) {
+			return usageErr
+		}
+		i++
+
+		switch flag {
+		case "--user":
+			userName = parseToNullString(cmd.args[i])
+		case "--since":
+			parsedSince, sinceErr := parseSince(cmd.args[i])
+			if sinceErr != nil {
+				return sinceErr
+			}
+			since = parsedSince
+		case "--limit":
+			parsedLimit, parseErr := strconv.Atoi(cmd.args[i])
+			if parseErr != nil || parsedLimit < 1 {
+				return fmt.Errorf("invalid --limit value %s, expected positive number", cmd.args[i])
+			}
+			limit = parsedLimit
+		default:
+			return usageErr
+		}
+	}
+
+	entries, err := s.db.GetAuditEntries(ctx, database.GetAuditEntriesParams{
+		UserName:   userName,
+		Since:      since,
+		MaxEntries: int32(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("error getting audit log: %v", err)
+	}
+
+	fmt.Printf("-----------------------------------\n")
+	for _, entry := range entries {
+		user := "-"
+		if entry.UserName.Valid {
+			user = entry.UserName.String
+		}
+		fmt.Printf("%s %s by %s, took %dms \n", entry.CreatedAt.Format("2006-01-02 15:04:05"), entry.Command, user, entry.DurationMs)
+		fmt.Printf("Arguments: %s \n", entry.Args)
+		if entry.Succeeded {
+			fmt.Printf("Result: succeeded \n")
+		} else {
+			fmt.Printf("Result: failed: %s \n", entry.Error.String)
+		}
+		fmt.Printf("-----------------------------------\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+func TestRedactArgs(t *testing.T) {
+	cases := []struct {
+		cmd  command
+		want []string
+	}{
+		{
+			command{name: "addfeed", args: []string{"blog", "https://example.com", "--header", "X-Api-Key: secret"}},
+			[]string{"blog", "https://example.com", "--header", "X-Api-Key: " + redacted},
+		},
+		{
+			command{name: "feedauth", args: []string{"https://example.com", "--basic-user", "alice", "--basic-password", "secret"}},
+			[]string{"https://example.com", "--basic-user", "alice", "--basic-password", redacted},
+		},
+		{
+			command{name: "feedauth", args: []string{"https://example.com", "--bearer", "token"}},
+			[]string{"https://example.com", "--bearer", redacted},
+		},
+		// header without name separator is redacted whole
+		{
+			command{name: "feedauth", args: []string{"https://example.com", "--header", "secret"}},
+			[]string{"https://example.com", "--header", redacted},
+		},
+		// flag without value has nothing to redact
+		{
+			command{name: "feedauth", args: []string{"https://example.com", "--bearer"}},
+			[]string{"https://example.com", "--bearer"},
+		},
+		{command{name: "login", args: []string{"gator_token"}}, []string{redacted}},
+		{command{name: "login", args: []string{"-"}}, []string{"-"}},
+		{command{name: "follow", args: []string{"--header"}}, []string{"--header"}},
+		{command{name: "users"}, []string{}},
+	}
+
+	for _, tc := range cases {
+		if got := redactArgs(tc.cmd); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s %v redacted to %v, want %v", tc.cmd.name, tc.cmd.args, got, tc.want)
+		}
+	}
+
+	cmd := command{name: "feedauth", args: []string{"https://example.com", "--bearer", "token"}}
+	redactArgs(cmd)
+	if cmd.args[2] != "token" {
+		t.Error("redactArgs changed arguments of command")
+	}
+}
+
+func TestMiddlewareAudit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+	token, err := issueApiToken(ctx, s.db, user.ID, defaultTokenName, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	s.config.SESSION_TOKEN = token
+
+	// command renaming its own user is still linked to it
+	rename := middlewareAudit(func(ctx context.Context, s *state, cmd command) error {
+		_, err := s.db.RenameUser(ctx, database.RenameUserParams{NewName: "alicia", UpdatedAt: time.Now(), Name: "alice"})
+		return err
+	})
+	if err := rename(ctx, s, command{name: "user", args: []string{"rename", "alice", "alicia"}}); err != nil {
+		t.Fatalf("audited rename failed: %v", err)
+	}
+	failing := middlewareAudit(func(ctx context.Context, s *state, cmd command) error {
+		return errors.New("feed not found")
+	})
+	if err := failing(ctx, s, command{name: "feedauth", args: []string{"https://example.com", "--bearer", "token"}}); err == nil {
+		t.Fatal("audit middleware swallowed error of command")
+	}
+	migrated := false
+	migrate := middlewareAudit(func(ctx context.Context, s *state, cmd command) error {
+		migrated = true
+		return nil
+	})
+	if err := migrate(ctx, s, command{name: "migrate", args: []string{"up"}}); err != nil || !migrated {
+		t.Fatalf("audited migrate returned %v and ran %t, want it run", err, migrated)
+	}
+
+	entries, err := s.db.GetAuditEntries(ctx, database.GetAuditEntriesParams{
+		UserName:   sql.NullString{String: "alicia", Valid: true},
+		MaxEntries: 10,
+	})
+	if err != nil {
+		t.Fatalf("error getting audit entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got audit entries %+v, want rename and failed feedauth without migrate", entries)
+	}
+
+	failed, renamed := entries[0], entries[1]
+	if failed.Command == "user" {
+		failed, renamed = renamed, failed
+	}
+	if !renamed.Succeeded || renamed.UserName.String != "alice" || renamed.UserID.UUID != user.ID {
+		t.Errorf("rename recorded as %+v, want success by alice linked to her id", renamed)
+	}
+	if failed.Succeeded || failed.Error.String != "feed not found" {
+		t.Errorf("failed command recorded as %+v, want its error", failed)
+	}
+	if failed.Args != `["https://example.com","--bearer","[REDACTED]"]` {
+		t.Errorf("failed command recorded with arguments %s, want bearer token redacted", failed.Args)
+	}
+}
+
+func TestMiddlewareAuditWithoutSession(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	createTestUser(t, s, "alice")
+
+	register := middlewareAudit(func(ctx context.Context, s *state, cmd command) error { return nil })
+	if err := register(ctx, s, command{name: "register", args: []string{"bob"}}); err != nil {
+		t.Fatalf("audited register failed: %v", err)
+	}
+
+	entries, err := s.db.GetAuditEntries(ctx, database.GetAuditEntriesParams{MaxEntries: 10})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("got audit entries %+v (%v), want one", entries, err)
+	}
+	if entries[0].UserID.Valid || entries[0].UserName.Valid {
+		t.Errorf("command without session recorded as %+v, want no user", entries[0])
+	}
+}
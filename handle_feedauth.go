@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/credentials"
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Key used to encrypt feed credentials, environment takes precedence over config file
+func secretKey(s *state) string {
+	if key := os.Getenv(credentials.KeyEnv); key != "" {
+		return key
+	}
+	return s.config.SECRET_KEY
+}
+
+// Parses --header, --basic-user, --basic-password and --bearer flags
+func parseCredentialFlags(args []string) (credentials.Credentials, error) {
+	creds := credentials.Credentials{}
+
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if i+1 >= len(args) {
+			return credentials.Credentials{}, fmt.Errorf("%s expects a value", flag)
+		}
+		i++
+
+		switch flag {
+		case "--header":
+			name, value, headerErr := credentials.ParseHeader(args[i])
+			if headerErr != nil {
+				return credentials.Credentials{}, headerErr
+			}
+			if creds.Headers == nil {
+				creds.Headers = map[string]string{}
+			}
+			creds.Headers[name] = value
+		case "--basic-user":
+			creds.BasicUser = args[i]
+		case "--basic-password":
+			creds.BasicPassword = args[i]
+		case "--bearer":
+			creds.BearerToken = args[i]
+		default:
+			return credentials.Credentials{}, fmt.Errorf("unknown flag %s", flag)
+		}
+	}
+
+	if creds.BasicPassword != "" && creds.BasicUser == "" {
+		return credentials.Credentials{}, fmt.Errorf("--basic-password requires --basic-user")
+	}
+	if creds.BasicUser != "" && creds.BearerToken != "" {
+		return credentials.Credentials{}, fmt.Errorf("--basic-user and --bearer can not be used together")
+	}
+
+	return creds, nil
+}
+
+// Encrypts credentials and stores them for feed, replacing previous ones
+func storeFeedCredentials(ctx context.Context, s *state, q database.Querier, feedID uuid.UUID, creds credentials.Credentials) error {
+	sealed, sealErr := credentials.Seal(secretKey(s), creds)
+	if sealErr != nil {
+		return fmt.Errorf("error encrypting feed credentials: %v", sealErr)
+	}
+
+	setErr := q.SetFeedCredentials(ctx, database.SetFeedCredentialsParams{
+		FeedID:    feedID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Sealed:    sealed,
+	})
+	if setErr != nil {
+		return fmt.Errorf("error storing feed credentials: %v", setErr)
+	}
+
+	return nil
+}
+
+// Credentials of feed, empty for public feeds
+func loadFeedCredentials(ctx context.Context, s *state, feedID uuid.UUID) (credentials.Credentials, error) {
+	sealed, getErr := s.db.GetFeedCredentials(ctx, feedID)
+	if errors.Is(getErr, sql.ErrNoRows) {
+		return credentials.Credentials{}, nil
+	}
+	if getErr != nil {
+		return credentials.Credentials{}, fmt.Errorf("error getting feed credentials: %v", getErr)
+	}
+
+	creds, openErr := credentials.Open(secretKey(s), sealed)
+	if openErr != nil {
+		return credentials.Credentials{}, fmt.Errorf("error decrypting feed credentials: %v", openErr)
+	}
+
+	return creds, nil
+}
+
+// Sets, shows or clears authentication of feed added by logged in user
+func handleFeedAuth(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) < 1 {
+		return fmt.Errorf("usage: feedauth <feed url> [--header \"Name: value\"]... [--basic-user <user> --basic-password <password>] [--bearer <token>] | feedauth <feed url> --clear")
+	}
+
+	feed, feedErr := s.db.GetFeedByUrl(ctx, cmd.args[0])
+	if feedErr != nil {
+		return fmt.Errorf("error getting feed %s: %v", cmd.args[0], feedErr)
+	}
+	if feed.UserID != user.ID {
+		return fmt.Errorf("only user who added feed %s can change its credentials", feed.Name)
+	}
+
+	flags := cmd.args[1:]
+	switch {
+	case len(flags) == 0:
+		creds, loadErr := loadFeedCredentials(ctx, s, feed.ID)
+		if loadErr != nil {
+			return loadErr
+		}
+		fmt.Printf("Feed %s authentication: %s \n", feed.Name, creds.Summary())
+		return nil
+	case len(flags) == 1 && flags[0] == "--clear":
+		deleted, deleteErr := s.db.DeleteFeedCredentials(ctx, feed.ID)
+		if deleteErr != nil {
+			return fmt.Errorf("error clearing credentials of feed %s: %v", feed.Name, deleteErr)
+		}
+		if deleted == 0 {
+			fmt.Printf("Feed %s has no credentials \n", feed.Name)
+			return nil
+		}
+		fmt.Printf("Credentials of feed %s were cleared \n", feed.Name)
+		return nil
+	}
+
+	creds, parseErr := parseCredentialFlags(flags)
+	if parseErr != nil {
+		return parseErr
+	}
+	if storeErr := storeFeedCredentials(ctx, s, s.db, feed.ID, creds); storeErr != nil {
+		return storeErr
+	}
+
+	fmt.Printf("Feed %s authentication set to: %s \n", feed.Name, creds.Summary())
+	return nil
+}
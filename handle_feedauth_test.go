@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MichalGul/blog_aggregator/internal/credentials"
+)
+
+func TestParseCredentialFlags(t *testing.T) {
+	cases := []struct {
+		args  []string
+		want  credentials.Credentials
+		valid bool
+	}{
+		{
+			[]string{"--header", "x-api-key: secret", "--header", "Cookie: a=1"},
+			credentials.Credentials{Headers: map[string]string{"X-Api-Key": "secret", "Cookie": "a=1"}},
+			true,
+		},
+		{
+			[]string{"--basic-user", "alice", "--basic-password", "password"},
+			credentials.Credentials{BasicUser: "alice", BasicPassword: "password"},
+			true,
+		},
+		{[]string{"--bearer", "token"}, credentials.Credentials{BearerToken: "token"}, true},
+		{nil, credentials.Credentials{}, true},
+		{[]string{"--bearer"}, credentials.Credentials{}, false},
+		{[]string{"--header", "no colon"}, credentials.Credentials{}, false},
+		{[]string{"--basic-password", "password"}, credentials.Credentials{}, false},
+		{[]string{"--basic-user", "alice", "--bearer", "token"}, credentials.Credentials{}, false},
+		{[]string{"--token", "value"}, credentials.Credentials{}, false},
+	}
+
+	for _, tc := range cases {
+		got, err := parseCredentialFlags(tc.args)
+		if tc.valid && (err != nil || !reflect.DeepEqual(got, tc.want)) {
+			t.Errorf("%v parsed to %+v (%v), want %+v", tc.args, got, err, tc.want)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%v parsed to %+v, want error", tc.args, got)
+		}
+	}
+}
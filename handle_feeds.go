@@ -2,34 +2,67 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/MichalGul/blog_aggregator/internal/credentials"
 	"github.com/MichalGul/blog_aggregator/internal/database"
 	"github.com/google/uuid"
 )
 
-func handleFeeds(s *state, cmd command) error {
+func handleFeeds(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) > 0 && cmd.args[0] == "--broken" {
+		return handleBrokenFeeds(ctx, s)
+	}
 
-	feeds, err := s.db.GetFeeds(context.Background())
+	feeds, err := s.db.GetFeeds(ctx)
 	if err != nil {
 		return fmt.Errorf("error getting all feeds from database %v", err)
 	}
 
 	fmt.Printf("-----------------------------------\n")
 	for i := range feeds {
-		printFeedDB(feeds[i], s)
+		printFeedDB(ctx, feeds[i], s)
 	}
 
 	return nil
 
 }
 
-func printFeedDB(feed database.GetFeedsRow, s *state) {
+// Lists feeds which failed recently or were disabled because of errors
+func handleBrokenFeeds(ctx context.Context, s *state) error {
+	feeds, err := s.db.GetBrokenFeeds(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting broken feeds from database %v", err)
+	}
+
+	fmt.Printf("-----------------------------------\n")
+	for _, feed := range feeds {
+		fmt.Printf("Feed Name: %v \n", feed.Name)
+		fmt.Printf("Feed Url: %v \n", feed.Url)
+		fmt.Printf("Failures in a row: %v \n", feed.ConsecutiveFailures)
+		fmt.Printf("Last error: %v \n", feed.LastError.String)
+		if feed.LastSucceededAt.Valid {
+			fmt.Printf("Last success: %v \n", feed.LastSucceededAt.Time)
+		} else {
+			fmt.Printf("Last success: never \n")
+		}
+		if feed.DisabledAt.Valid {
+			fmt.Printf("Disabled at: %v \n", feed.DisabledAt.Time)
+		}
+		fmt.Printf("-----------------------------------\n")
+	}
+
+	return nil
+}
 
-	creatorName, err := s.db.GetUsernameById(context.Background(), feed.UserID)
+func printFeedDB(ctx context.Context, feed database.GetFeedsRow, s *state) {
+
+	creatorName, err := s.db.GetUsernameById(ctx, feed.UserID)
 	if err != nil {
-		fmt.Printf("Error while parsing user id to name for feed: %s, %w", feed.Name, err)
+		slog.Warn("error while parsing user id to name for feed", "feed", feed.Name, "error", err)
 	}
 
 	fmt.Printf("Feed Name: %v \n", feed.Name)
@@ -40,35 +73,57 @@ func printFeedDB(feed database.GetFeedsRow, s *state) {
 
 }
 
-func handleAddFeed(s *state, cmd command, user database.User) error {
+func handleAddFeed(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) < 2 {
-		return fmt.Errorf("addfeed command expects two arguments of feed name and url")
+		return fmt.Errorf("addfeed command expects two arguments of feed name and url, optionally followed by feedauth flags")
 	}
 
 	feedName := cmd.args[0]
 	feedUrl := cmd.args[1]
 
-	createdFeed, create_error := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      feedName,
-		Url:       feedUrl,
-		UserID:    user.ID,
-	})
-	if create_error != nil {
-		return fmt.Errorf("error adding feed: %s to database: %v", feedName, create_error)
+	creds, credsErr := parseCredentialFlags(cmd.args[2:])
+	if credsErr != nil {
+		return credsErr
 	}
+	// Key is checked before feed is created so private feed is not left without credentials
+	if !creds.IsEmpty() && secretKey(s) == "" {
+		return credentials.ErrNoKey
+	}
+
+	// Feed, its follow and credentials are stored together so failure leaves no orphan feed
+	var createdFeed database.Feed
+	txErr := s.db.InTx(ctx, func(qtx database.Querier) error {
+		var create_error error
+		createdFeed, create_error = qtx.CreateFeed(ctx, database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      feedName,
+			Url:       feedUrl,
+			UserID:    user.ID,
+		})
+		if create_error != nil {
+			return fmt.Errorf("error adding feed: %s to database: %v", feedName, create_error)
+		}
 
-	_, errorFeedFollow := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		UserID:    user.ID,
-		FeedID:    createdFeed.ID,
+		_, errorFeedFollow := qtx.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    createdFeed.ID,
+		})
+		if errorFeedFollow != nil {
+			return fmt.Errorf("error adding feed follow for user %s to database %v", user.Name, errorFeedFollow)
+		}
+
+		if !creds.IsEmpty() {
+			return storeFeedCredentials(ctx, s, qtx, createdFeed.ID, creds)
+		}
+		return nil
 	})
-	if errorFeedFollow != nil {
-		return fmt.Errorf("error adding feed follow for user %s to database %v", user.Name, errorFeedFollow)
+	if txErr != nil {
+		return txErr
 	}
 
 	fmt.Printf("Feed was successfuly created \n")
@@ -78,6 +133,145 @@ func handleAddFeed(s *state, cmd command, user database.User) error {
 	fmt.Printf("Feed Name: %v \n", createdFeed.Name)
 	fmt.Printf("Feed Url: %v \n", createdFeed.Url)
 	fmt.Printf("Feed User id: %v \n", createdFeed.UserID)
+	if !creds.IsEmpty() {
+		fmt.Printf("Feed authentication: %v \n", creds.Summary())
+	}
+
+	return nil
+}
+
+// Feed which user changes by url. Owner may change own feed, feeds of other users require admin
+func feedToChange(ctx context.Context, s *state, user database.User, feedUrl, action string) (database.Feed, error) {
+	feed, feedErr := s.db.GetFeedByUrl(ctx, feedUrl)
+	if feedErr != nil {
+		return database.Feed{}, fmt.Errorf("error getting feed from db by url %s: %v", feedUrl, feedErr)
+	}
+	if feed.UserID != user.ID && user.Role != roleAdmin {
+		return database.Feed{}, fmt.Errorf("feed %s belongs to another user, %s it requires admin role", feed.Name, action)
+	}
+	return feed, nil
+}
+
+func handleFeedInterval(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 3 {
+		return fmt.Errorf("feedinterval command expects three arguments of feed url, min and max interval eg. 5m 12h")
+	}
+
+	feedUrl := cmd.args[0]
+	minInterval, minErr := time.ParseDuration(cmd.args[1])
+	if minErr != nil {
+		return fmt.Errorf("invalid min interval %s: %v", cmd.args[1], minErr)
+	}
+	maxInterval, maxErr := time.ParseDuration(cmd.args[2])
+	if maxErr != nil {
+		return fmt.Errorf("invalid max interval %s: %v", cmd.args[2], maxErr)
+	}
+	if minInterval < time.Second || maxInterval < minInterval {
+		return fmt.Errorf("intervals must be at least 1s and min can not exceed max")
+	}
+	if maxInterval > maxStoredDuration {
+		return fmt.Errorf("max interval %s is too long, at most %s is supported", cmd.args[2], maxStoredDuration)
+	}
+	if _, ownerErr := feedToChange(ctx, s, user, feedUrl, "changing interval of"); ownerErr != nil {
+		return ownerErr
+	}
+
+	updatedFeed, updateErr := s.db.SetFeedFetchBounds(ctx, database.SetFeedFetchBoundsParams{
+		Url:                     feedUrl,
+		MinFetchIntervalSeconds: int32(minInterval / time.Second),
+		MaxFetchIntervalSeconds: int32(maxInterval / time.Second),
+	})
+	if updateErr != nil {
+		return fmt.Errorf("error updating polling interval of feed %s: %v", feedUrl, updateErr)
+	}
+
+	fmt.Printf("Feed %s will be polled every %s to %s \n", updatedFeed.Name,
+		time.Duration(updatedFeed.MinFetchIntervalSeconds)*time.Second,
+		time.Duration(updatedFeed.MaxFetchIntervalSeconds)*time.Second)
 
 	return nil
-}
\ No newline at end of file
+}
+
+func handleEnableFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("enablefeed command expects one argument of feed url")
+	}
+	feedUrl := cmd.args[0]
+	if _, ownerErr := feedToChange(ctx, s, user, feedUrl, "enabling"); ownerErr != nil {
+		return ownerErr
+	}
+
+	enabledFeed, enableErr := s.db.EnableFeed(ctx, feedUrl)
+	if enableErr != nil {
+		return fmt.Errorf("error enabling feed %s: %v", feedUrl, enableErr)
+	}
+
+	fmt.Printf("Feed %s was enabled and will be fetched on next agg run \n", enabledFeed.Name)
+
+	return nil
+}
+
+// Owner may delete own feed, feeds of other users require admin. Posts, follows
+// and credentials of feed go with it
+func handleDeleteFeed(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("deletefeed command expects one argument of feed url")
+	}
+	feedUrl := cmd.args[0]
+
+	feed, feedErr := feedToChange(ctx, s, user, feedUrl, "deleting")
+	if feedErr != nil {
+		return feedErr
+	}
+
+	deleted, deleteErr := s.db.DeleteFeed(ctx, feed.ID)
+	if deleteErr != nil {
+		return fmt.Errorf("error deleting feed %s: %v", feed.Name, deleteErr)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("feed %s was already deleted", feed.Name)
+	}
+
+	fmt.Printf("Feed %s was deleted \n", feed.Name)
+
+	return nil
+}
+
+func handleSchedule(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 2 {
+		return fmt.Errorf("schedule command expects two arguments of feed url and cron expression eg. \"*/5 9-18 * * 1-5\" or --clear")
+	}
+	feedUrl := cmd.args[0]
+	if _, ownerErr := feedToChange(ctx, s, user, feedUrl, "scheduling"); ownerErr != nil {
+		return ownerErr
+	}
+
+	cronSchedule := sql.NullString{}
+	// Without schedule feed is due right away and falls back to adaptive polling
+	nextFetchAt := sql.NullTime{}
+	if cmd.args[1] != "--clear" {
+		scheduledAt, cronErr := nextCronFetch(cmd.args[1], time.Now())
+		if cronErr != nil {
+			return fmt.Errorf("invalid schedule: %v", cronErr)
+		}
+		cronSchedule = parseToNullString(cmd.args[1])
+		nextFetchAt = sql.NullTime{Time: scheduledAt, Valid: true}
+	}
+
+	scheduledFeed, scheduleErr := s.db.SetFeedSchedule(ctx, database.SetFeedScheduleParams{
+		Url:          feedUrl,
+		CronSchedule: cronSchedule,
+		NextFetchAt:  nextFetchAt,
+	})
+	if scheduleErr != nil {
+		return fmt.Errorf("error setting schedule of feed %s: %v", feedUrl, scheduleErr)
+	}
+
+	if !scheduledFeed.CronSchedule.Valid {
+		fmt.Printf("Schedule of feed %s was cleared, it is polled adaptively again \n", scheduledFeed.Name)
+		return nil
+	}
+	fmt.Printf("Feed %s is scheduled with %q, next fetch at %v \n", scheduledFeed.Name, scheduledFeed.CronSchedule.String, scheduledFeed.NextFetchAt.Time)
+
+	return nil
+}
@@ -9,19 +9,19 @@ import (
 	"github.com/google/uuid"
 )
 
-func handleFollow(s *state, cmd command, user database.User) error {
+func handleFollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) == 0 {
 		return fmt.Errorf("follow command expects one argument of url")
 	}
 
 	feedUrl := cmd.args[0]
 
-	feed, feedErr := s.db.GetFeedByUrl(context.Background(), feedUrl)
+	feed, feedErr := s.db.GetFeedByUrl(ctx, feedUrl)
 	if feedErr != nil {
 		return fmt.Errorf("error getting feed from db by url %s: %v", feedUrl, feedErr)
 	}
 
-	createdFeedFollow, createError := s.db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+	createdFeedFollow, createError := s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -41,13 +41,13 @@ func handleFollow(s *state, cmd command, user database.User) error {
 
 }
 
-func handleUnfollow(s *state, cmd command, user database.User) error {
+func handleUnfollow(ctx context.Context, s *state, cmd command, user database.User) error {
 	if len(cmd.args) == 0 {
 		return fmt.Errorf("unfollow command expects one argument of url")
 	}
 	feedUrl := cmd.args[0]
 
-	delErr := s.db.DeleteFeedsFollow(context.Background(), database.DeleteFeedsFollowParams{
+	delErr := s.db.DeleteFeedsFollow(ctx, database.DeleteFeedsFollowParams{
 		Url:    feedUrl,
 		UserID: user.ID,
 	})
@@ -60,9 +60,9 @@ func handleUnfollow(s *state, cmd command, user database.User) error {
 
 }
 
-func handleFollowing(s *state, cmd command, user database.User) error {
+func handleFollowing(ctx context.Context, s *state, cmd command, user database.User) error {
 
-	feedFollowsForUser, feedFollowsErr := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	feedFollowsForUser, feedFollowsErr := s.db.GetFeedFollowsForUser(ctx, user.ID)
 	if feedFollowsErr != nil {
 		return fmt.Errorf("error getting feed follow for user %s: %v", user.Name, feedFollowsErr)
 	}
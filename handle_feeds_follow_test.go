@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestHandleFollow(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	owner := createTestUser(t, s, "alice")
+	follower := createTestUser(t, s, "bob")
+	feed := createTestFeed(t, s, owner, "blog", "https://example.com/feed.xml")
+
+	output := captureOutput(t, func() {
+		if err := handleFollow(ctx, s, command{name: "follow", args: []string{feed.Url}}, follower); err != nil {
+			t.Fatalf("follow failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "FeedFollow Name: blog") {
+		t.Errorf("follow printed %q, want name of followed feed", output)
+	}
+
+	follows, err := s.db.GetFeedFollowsForUser(ctx, follower.ID)
+	if err != nil {
+		t.Fatalf("error getting follows: %v", err)
+	}
+	if len(follows) != 1 || follows[0].FeedID != feed.ID {
+		t.Fatalf("follower has follows %+v, want one of feed %s", follows, feed.Name)
+	}
+
+	captureOutput(t, func() {
+		if err := handleFollow(ctx, s, command{name: "follow", args: []string{feed.Url}}, follower); err == nil {
+			t.Error("following the same feed twice succeeded")
+		}
+	})
+}
+
+func TestHandleFollowErrors(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+
+	if err := handleFollow(ctx, s, command{name: "follow"}, user); err == nil {
+		t.Error("follow without url succeeded")
+	}
+	if err := handleFollow(ctx, s, command{name: "follow", args: []string{"https://example.com/missing.xml"}}, user); err == nil {
+		t.Error("follow of unknown feed succeeded")
+	}
+}
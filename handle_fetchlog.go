@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Fetch history older than this is removed by agg
+const fetchRunsRetention = 30 * 24 * time.Hour
+
+// How often agg prunes old fetch history
+const fetchRunsPruneEvery = time.Hour
+
+// Maximum number of fetch runs printed by fetchlog
+const fetchLogLimit = 100
+
+// Stores single fetch attempt in fetch history. Failure to store is only reported
+func recordFetchRun(ctx context.Context, s *state, result scrapeResult) {
+	httpStatus := sql.NullInt32{}
+	if result.httpStatus != 0 {
+		httpStatus = sql.NullInt32{Int32: int32(result.httpStatus), Valid: true}
+	}
+
+	fetchErr := sql.NullString{}
+	if result.err != nil {
+		fetchErr = parseToNullString(result.err.Error())
+	}
+
+	_, createErr := s.db.CreateFetchRun(ctx, database.CreateFetchRunParams{
+		ID:            uuid.New(),
+		FeedID:        result.feed.ID,
+		StartedAt:     result.startedAt,
+		FinishedAt:    time.Now(),
+		HttpStatus:    httpStatus,
+		Bytes:         result.bytes,
+		ItemsSeen:     int32(result.seen),
+		ItemsInserted: int32(result.inserted),
+		ItemsSkipped:  int32(result.skipped),
+		Error:         fetchErr,
+	})
+	if createErr != nil {
+		slog.Error("error recording fetch run", "feed", result.feed.Name, "error", createErr)
+	}
+}
+
+// Removes fetch history older than retention period
+func pruneFetchRuns(ctx context.Context, s *state) (int64, error) {
+	removed, err := s.db.DeleteFetchRunsBefore(ctx, time.Now().Add(-fetchRunsRetention))
+	if err != nil {
+		return 0, fmt.Errorf("error pruning fetch history: %v", err)
+	}
+	return removed, nil
+}
+
+// Prunes fetch history for agg, failure is only logged so fetching goes on
+func logPruneFetchRuns(ctx context.Context, s *state) {
+	if removed, pruneErr := pruneFetchRuns(ctx, s); pruneErr != nil {
+		slog.Error("error pruning fetch history", "error", pruneErr)
+	} else if removed > 0 {
+		slog.Info("removed old fetch history entries", "count", removed)
+	}
+}
+
+// Parses --since value given either as duration back from now (eg. 24h) or date (2006-01-02)
+func parseSince(value string) (time.Time, error) {
+	if duration, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-duration), nil
+	}
+	if date, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value %s, expected duration like 24h or date like 2006-01-02", value)
+}
+
+func handleFetchLog(ctx context.Context, s *state, cmd command) error {
+	feedUrl := sql.NullString{}
+	since := time.Now().Add(-fetchRunsRetention)
+
+	for i := 0; i < len(cmd.args); i++ {
+		switch arg := cmd.args[i]; {
+		case arg == "--since":
+			if i+1 >= len(cmd.args) {
+				return fmt.Errorf("--since expects a value")
+			}
+			i++
+			parsedSince, sinceErr := parseSince(cmd.args[i])
+			if sinceErr != nil {
+				return sinceErr
+			}
+			since = parsedSince
+		case !feedUrl.Valid:
+			feedUrl = parseToNullString(arg)
+		default:
+			return fmt.Errorf("fetchlog command expects arguments: [feed url] [--since <24h|2006-01-02>]")
+		}
+	}
+
+	runs, err := s.db.GetFetchRuns(ctx, database.GetFetchRunsParams{
+		FeedUrl: feedUrl,
+		Since:   since,
+		MaxRuns: fetchLogLimit,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting fetch history: %v", err)
+	}
+
+	fmt.Printf("-----------------------------------\n")
+	for _, run := range runs {
+		fmt.Printf("Feed: %s (%s) \n", run.FeedName, run.FeedUrl)
+		fmt.Printf("Started: %v, took: %v \n", run.StartedAt, run.FinishedAt.Sub(run.StartedAt))
+		if run.HttpStatus.Valid {
+			fmt.Printf("HTTP status: %d, bytes: %d \n", run.HttpStatus.Int32, run.Bytes)
+		}
+		fmt.Printf("Items seen: %d, inserted: %d, duplicates: %d \n", run.ItemsSeen, run.ItemsInserted, run.ItemsSkipped)
+		if run.Error.Valid {
+			fmt.Printf("Error: %s \n", run.Error.String)
+		}
+		fmt.Printf("-----------------------------------\n")
+	}
+
+	return nil
+}
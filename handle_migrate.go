@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"strings"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/MichalGul/blog_aggregator/internal/migrate"
+	"github.com/MichalGul/blog_aggregator/internal/storage"
+)
+
+//go:embed sql/schema/*.sql sql/sqlite/schema/*.sql
+var schemaFS embed.FS
+
+// Migrations of the backend selected by db_url
+func newMigrationRunner(s *state) (*migrate.Runner, error) {
+	schemaDir, dialect := "sql/schema", migrate.Postgres
+	if s.db.Backend() == storage.SQLite {
+		schemaDir, dialect = "sql/sqlite/schema", migrate.SQLite
+	}
+
+	migrations, loadErr := migrate.Load(schemaFS, schemaDir)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return migrate.NewRunner(s.db.DB(), dialect, migrations), nil
+}
+
+// Refuses to run commands against database whose schema is older than the binary
+func checkSchema(ctx context.Context, s *state) error {
+	runner, runnerErr := newMigrationRunner(s)
+	if runnerErr != nil {
+		return runnerErr
+	}
+
+	pending, pendingErr := runner.Pending(ctx)
+	if pendingErr != nil {
+		return fmt.Errorf("error checking database schema: %v", pendingErr)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind by %d migrations starting with %s, run gator migrate up", len(pending), pending[0].Name)
+	}
+
+	return nil
+}
+
+func handleMigrate(ctx context.Context, s *state, cmd command) error {
+	confirmed := len(cmd.args) == 2 && cmd.args[1] == "--yes" && (cmd.args[0] == "down" || cmd.args[0] == "redo")
+	if len(cmd.args) != 1 && !confirmed {
+		return fmt.Errorf("usage: migrate up|down [--yes]|status|redo [--yes]")
+	}
+
+	runner, runnerErr := newMigrationRunner(s)
+	if runnerErr != nil {
+		return runnerErr
+	}
+
+	switch cmd.args[0] {
+	case "up":
+		applied, upErr := runner.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %s \n", migration.Name)
+		}
+		if upErr != nil {
+			return upErr
+		}
+		if len(applied) == 0 {
+			fmt.Printf("Database schema is up to date \n")
+		}
+		for _, migration := range applied {
+			if strings.HasSuffix(migration.Name, "_api_tokens.sql") {
+				return issueMigratedTokens(ctx, s)
+			}
+		}
+	case "down", "redo":
+		// up works without users, so a new database can be set up, rolling back drops data
+		rolesApplied, rolesErr := userRolesApplied(ctx, runner)
+		if rolesErr != nil {
+			return rolesErr
+		}
+		if rolesApplied {
+			return middlewareAdmin(func(ctx context.Context, s *state, cmd command, user database.User) error {
+				return rollbackMigration(ctx, runner, cmd.args[0])
+			})(ctx, s, cmd)
+		}
+		if !confirmed {
+			return fmt.Errorf("database schema has no user roles to check, confirm rolling back with: migrate %s --yes", cmd.args[0])
+		}
+		return rollbackMigration(ctx, runner, cmd.args[0])
+	case "status":
+		statuses, statusErr := runner.Statuses(ctx)
+		if statusErr != nil {
+			return statusErr
+		}
+		fmt.Printf("%-24s %s \n", "Applied At", "Migration")
+		for _, status := range statuses {
+			appliedAt := "Pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-24s %s \n", appliedAt, status.Migration.Name)
+		}
+	default:
+		return fmt.Errorf("unknown migrate subcommand %s, expected up, down, status or redo", cmd.args[0])
+	}
+
+	return nil
+}
+
+// Admin role can only be checked while schema has user roles, below them any user
+// may roll back after confirming it
+func userRolesApplied(ctx context.Context, runner *migrate.Runner) (bool, error) {
+	statuses, statusErr := runner.Statuses(ctx)
+	if statusErr != nil {
+		return false, statusErr
+	}
+	for _, status := range statuses {
+		if strings.HasSuffix(status.Migration.Name, "_user_roles.sql") {
+			return status.Applied, nil
+		}
+	}
+	return false, nil
+}
+
+// Rolls back latest migration, redo applies it again afterwards
+func rollbackMigration(ctx context.Context, runner *migrate.Runner, subcommand string) error {
+	if subcommand == "redo" {
+		migration, redoErr := runner.Redo(ctx)
+		if redoErr != nil {
+			return redoErr
+		}
+		fmt.Printf("Reapplied %s \n", migration.Name)
+		return nil
+	}
+
+	migration, rolledBack, downErr := runner.Down(ctx)
+	if downErr != nil {
+		return downErr
+	}
+	if !rolledBack {
+		fmt.Printf("No migrations to roll back \n")
+		return nil
+	}
+	fmt.Printf("Rolled back %s \n", migration.Name)
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/config"
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/MichalGul/blog_aggregator/internal/storage"
+)
+
+// State backed by empty SQLite database, migrations run against the real schema
+func newSQLiteTestState(t *testing.T) *state {
+	t.Helper()
+	store, err := storage.Open("sqlite://" + filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatalf("error opening store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return &state{db: store, config: &config.Config{}}
+}
+
+func runMigrate(t *testing.T, ctx context.Context, s *state, args ...string) (string, error) {
+	t.Helper()
+	var err error
+	output := captureOutput(t, func() {
+		err = handleMigrate(ctx, s, command{name: "migrate", args: args})
+	})
+	return output, err
+}
+
+func TestMigrateDownThroughUserRoles(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	if _, err := runMigrate(t, ctx, s, "up"); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+
+	admin := createTestUser(t, s, "alice")
+	if _, err := s.db.SetUserRole(ctx, database.SetUserRoleParams{Name: "alice", Role: roleAdmin, UpdatedAt: time.Now()}); err != nil {
+		t.Fatalf("error promoting alice: %v", err)
+	}
+	member := createTestUser(t, s, "bob")
+	memberToken, err := issueApiToken(ctx, s.db, member.ID, defaultTokenName, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	adminToken, err := issueApiToken(ctx, s.db, admin.ID, defaultTokenName, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+
+	s.config.SESSION_TOKEN = memberToken
+	if _, err := runMigrate(t, ctx, s, "down", "--yes"); err == nil {
+		t.Fatal("member rolled back schema with user roles")
+	}
+
+	s.config.SESSION_TOKEN = adminToken
+	var rolledBack []string
+	for {
+		output, err := runMigrate(t, ctx, s, "down")
+		if err != nil && strings.Contains(err.Error(), "--yes") {
+			output, err = runMigrate(t, ctx, s, "down", "--yes")
+		}
+		if err != nil {
+			t.Fatalf("migrate down failed after rolling back %v: %v", rolledBack, err)
+		}
+		if strings.Contains(output, "No migrations to roll back") {
+			break
+		}
+		rolledBack = append(rolledBack, strings.TrimSpace(strings.TrimPrefix(output, "Rolled back")))
+	}
+
+	if len(rolledBack) < 3 || !strings.HasSuffix(rolledBack[1], "_user_roles.sql") {
+		t.Fatalf("rolled back %v, want user roles second", rolledBack)
+	}
+	if !strings.HasPrefix(rolledBack[len(rolledBack)-1], "001_") {
+		t.Errorf("rolled back %v, want down to the first migration", rolledBack)
+	}
+
+	// rolled back database can be set up again
+	if _, err := runMigrate(t, ctx, s, "up"); err != nil {
+		t.Errorf("migrate up after rolling back failed: %v", err)
+	}
+}
+
+func TestMigrateDownRequiresConfirmationWithoutRoles(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	if _, err := runMigrate(t, ctx, s, "up"); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+	runner, err := newMigrationRunner(s)
+	if err != nil {
+		t.Fatalf("error creating runner: %v", err)
+	}
+	// roll back to before user roles directly, nobody is logged in
+	for {
+		migration, _, err := runner.Down(ctx)
+		if err != nil {
+			t.Fatalf("error rolling back: %v", err)
+		}
+		if strings.HasSuffix(migration.Name, "_user_roles.sql") {
+			break
+		}
+	}
+
+	if _, err := runMigrate(t, ctx, s, "down"); err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Errorf("unconfirmed rollback returned %v, want request to confirm with --yes", err)
+	}
+	if _, err := runMigrate(t, ctx, s, "status", "--yes"); err == nil {
+		t.Error("status accepted --yes")
+	}
+	if output, err := runMigrate(t, ctx, s, "down", "--yes"); err != nil || !strings.Contains(output, "Rolled back") {
+		t.Errorf("confirmed rollback printed %q (%v), want migration rolled back", output, err)
+	}
+}
+
+func TestMigrateDownSchemaV2ListsConflictingFeeds(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	if _, err := runMigrate(t, ctx, s, "up"); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+	createTestFeed(t, s, createTestUser(t, s, "alice"), "blog", "https://example.com/alice.xml")
+	createTestFeed(t, s, createTestUser(t, s, "bob"), "blog", "https://example.com/bob.xml")
+
+	runner, err := newMigrationRunner(s)
+	if err != nil {
+		t.Fatalf("error creating runner: %v", err)
+	}
+	for {
+		migration, _, err := runner.Down(ctx)
+		if err != nil {
+			t.Fatalf("error rolling back: %v", err)
+		}
+		if strings.HasSuffix(migration.Name, "_audit_log.sql") {
+			break
+		}
+	}
+
+	// feed names were unique across users before schema v2
+	if _, _, err := runner.Down(ctx); err == nil || !strings.Contains(err.Error(), "feed name blog is used by 2 users") {
+		t.Fatalf("rolling back schema v2 returned %v, want conflicting feed listed", err)
+	}
+	pending, err := runner.Pending(ctx)
+	if err != nil || len(pending) == 0 || !strings.HasSuffix(pending[0].Name, "_audit_log.sql") {
+		t.Fatalf("pending after failed rollback %v (%v), want schema v2 still applied", pending, err)
+	}
+
+	if _, err := s.db.DB().ExecContext(ctx, "UPDATE feeds SET name = 'bob blog' WHERE url = 'https://example.com/bob.xml'"); err != nil {
+		t.Fatalf("error renaming feed: %v", err)
+	}
+	if migration, _, err := runner.Down(ctx); err != nil || !strings.HasSuffix(migration.Name, "_schema_v2.sql") {
+		t.Fatalf("rolled back %s (%v), want schema v2 after renaming feed", migration.Name, err)
+	}
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil || len(feeds) != 2 {
+		t.Errorf("got %d feeds after rollback (%v), want both kept", len(feeds), err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+// Longest duration stored in seconds of INTEGER column, about 68 years. Larger values
+// would wrap to negative ages, which put the prune cutoff in the future
+const maxStoredDuration = math.MaxInt32 * time.Second
+
+// Parses retention age given either as duration (eg. 720h) or number of days (eg. 30d),
+// between one second and maxStoredDuration
+func parseRetentionAge(value string) (time.Duration, error) {
+	age := time.Duration(0)
+	if days, found := strings.CutSuffix(value, "d"); found {
+		if count, err := strconv.Atoi(days); err == nil && count > 0 && count <= int(maxStoredDuration/(24*time.Hour)) {
+			age = time.Duration(count) * 24 * time.Hour
+		}
+	} else if duration, err := time.ParseDuration(value); err == nil && duration <= maxStoredDuration {
+		age = duration
+	}
+	if age < time.Second {
+		return 0, fmt.Errorf("invalid age %s, expected duration like 720h or days like 30d between 1s and %dd", value, maxStoredDuration/(24*time.Hour))
+	}
+	return age, nil
+}
+
+// Posts created before this time are pruned from feeds without own max age,
+// NULL when post_max_age is not configured
+func globalPruneCutoff(s *state) (sql.NullTime, error) {
+	if s.config.POST_MAX_AGE == "" {
+		return sql.NullTime{}, nil
+	}
+	maxAge, err := parseRetentionAge(s.config.POST_MAX_AGE)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("error in post_max_age config: %v", err)
+	}
+	return sql.NullTime{Time: time.Now().Add(-maxAge), Valid: true}, nil
+}
+
+// Removes posts beyond retention policies, returns number of removed posts per feed
+func prunePosts(ctx context.Context, s *state) ([]database.PrunePostsRow, error) {
+	cutoff, cutoffErr := globalPruneCutoff(s)
+	if cutoffErr != nil {
+		return nil, cutoffErr
+	}
+	pruned, err := s.db.PrunePosts(ctx, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("error pruning posts: %v", err)
+	}
+	return pruned, nil
+}
+
+// Prune run by agg, report goes to log
+func prunePostsInBackground(ctx context.Context, s *state) {
+	pruned, err := prunePosts(ctx, s)
+	if err != nil {
+		slog.Error("error applying post retention", "error", err)
+		return
+	}
+	for _, row := range pruned {
+		slog.Info("pruned posts", "feed", row.FeedName, "count", row.Posts)
+	}
+}
+
+func handlePrune(ctx context.Context, s *state, cmd command, user database.User) error {
+	dryRun := false
+	switch {
+	case len(cmd.args) == 1 && cmd.args[0] == "--dry-run":
+		dryRun = true
+	case len(cmd.args) != 0:
+		return fmt.Errorf("prune command expects arguments: [--dry-run]")
+	}
+
+	var counts []database.GetPrunablePostCountsRow
+	if dryRun {
+		cutoff, cutoffErr := globalPruneCutoff(s)
+		if cutoffErr != nil {
+			return cutoffErr
+		}
+		prunable, countErr := s.db.GetPrunablePostCounts(ctx, cutoff)
+		if countErr != nil {
+			return fmt.Errorf("error counting posts to prune: %v", countErr)
+		}
+		counts = prunable
+	} else {
+		pruned, pruneErr := prunePosts(ctx, s)
+		if pruneErr != nil {
+			return pruneErr
+		}
+		for _, row := range pruned {
+			counts = append(counts, database.GetPrunablePostCountsRow(row))
+		}
+	}
+
+	verb := "removed"
+	if dryRun {
+		verb = "would be removed"
+	}
+	total := int64(0)
+	for _, row := range counts {
+		fmt.Printf("Feed %s (%s): %d posts %s \n", row.FeedName, row.FeedUrl, row.Posts, verb)
+		total += row.Posts
+	}
+	fmt.Printf("Total: %d posts %s \n", total, verb)
+
+	return nil
+}
+
+func handleRetention(ctx context.Context, s *state, cmd command, user database.User) error {
+	usageErr := fmt.Errorf("retention command expects arguments: <feed url> [--max-posts <n>] [--max-age <30d|720h>] | <feed url> --clear")
+	if len(cmd.args) == 0 {
+		return usageErr
+	}
+	feedUrl := cmd.args[0]
+
+	feed, feedErr := s.db.GetFeedByUrl(ctx, feedUrl)
+	if feedErr != nil {
+		return fmt.Errorf("error getting feed %s: %v", feedUrl, feedErr)
+	}
+
+	if len(cmd.args) > 1 {
+		// Short retention on feed of another user followed by prune would remove its posts
+		if _, ownerErr := feedToChange(ctx, s, user, feedUrl, "changing retention of"); ownerErr != nil {
+			return ownerErr
+		}
+		params := database.SetFeedRetentionParams{
+			Url:                    feedUrl,
+			RetentionMaxPosts:      feed.RetentionMaxPosts,
+			RetentionMaxAgeSeconds: feed.RetentionMaxAgeSeconds,
+		}
+		for i := 1; i < len(cmd.args); i++ {
+			arg := cmd.args[i]
+			if arg == "--clear" {
+				params.RetentionMaxPosts = sql.NullInt32{}
+				params.RetentionMaxAgeSeconds = sql.NullInt32{}
+				continue
+			}
+			if (arg != "--max-posts" && arg != "--max-age") || i+1 >= len(cmd.args) {
+				return usageErr
+			}
+			i++
+
+			if arg == "--max-posts" {
+				maxPosts, parseErr := strconv.Atoi(cmd.args[i])
+				if parseErr != nil || maxPosts < 1 {
+					return fmt.Errorf("invalid --max-posts value %s, expected positive number", cmd.args[i])
+				}
+				params.RetentionMaxPosts = sql.NullInt32{Int32: int32(maxPosts), Valid: true}
+			} else {
+				maxAge, parseErr := parseRetentionAge(cmd.args[i])
+				if parseErr != nil {
+					return parseErr
+				}
+				params.RetentionMaxAgeSeconds = sql.NullInt32{Int32: int32(maxAge / time.Second), Valid: true}
+			}
+		}
+
+		updatedFeed, updateErr := s.db.SetFeedRetention(ctx, params)
+		if updateErr != nil {
+			return fmt.Errorf("error updating retention of feed %s: %v", feedUrl, updateErr)
+		}
+		feed = updatedFeed
+	}
+
+	fmt.Printf("Retention of feed %s: \n", feed.Name)
+	if feed.RetentionMaxPosts.Valid {
+		fmt.Printf("Max posts: %d \n", feed.RetentionMaxPosts.Int32)
+	} else {
+		fmt.Printf("Max posts: unlimited \n")
+	}
+	switch {
+	case feed.RetentionMaxAgeSeconds.Valid:
+		fmt.Printf("Max age: %s \n", time.Duration(feed.RetentionMaxAgeSeconds.Int32)*time.Second)
+	case s.config.POST_MAX_AGE != "":
+		fmt.Printf("Max age: %s (post_max_age config) \n", s.config.POST_MAX_AGE)
+	default:
+		fmt.Printf("Max age: unlimited \n")
+	}
+
+	return nil
+}
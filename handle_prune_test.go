@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetentionAge(t *testing.T) {
+	cases := []struct {
+		value string
+		want  time.Duration
+		valid bool
+	}{
+		{"30d", 30 * 24 * time.Hour, true},
+		{"720h", 720 * time.Hour, true},
+		{"24855d", 24855 * 24 * time.Hour, true},
+		// would wrap to negative seconds in INTEGER column
+		{"25000d", 0, false},
+		{"600000h", 0, false},
+		{"1ms", 0, false},
+		{"0d", 0, false},
+		{"-1h", 0, false},
+		{"month", 0, false},
+	}
+
+	for _, tc := range cases {
+		got, err := parseRetentionAge(tc.value)
+		if tc.valid && (err != nil || got != tc.want) {
+			t.Errorf("%s parsed to %s (%v), want %s", tc.value, got, err, tc.want)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%s parsed to %s, want error", tc.value, got)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Queue priority of single feed refresh, served before bulk refresh of followed feeds
+const (
+	refreshFeedPriority     = 10
+	refreshFollowedPriority = 5
+)
+
+func handleRefresh(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("refresh command expects one argument of feed url or --all-followed")
+	}
+
+	feeds, priority, feedsErr := refreshTargets(ctx, s, cmd.args[0], user)
+	if feedsErr != nil {
+		return feedsErr
+	}
+	if len(feeds) == 0 {
+		fmt.Printf("No feeds to refresh \n")
+		return nil
+	}
+
+	// agg --once and other refreshes register instances too, but only daemon serves the queue
+	liveDaemons, liveErr := s.db.CountLiveAggDaemons(ctx, int32(instanceStaleAfter/time.Second))
+	if liveErr != nil {
+		return fmt.Errorf("error checking for running agg daemons: %v", liveErr)
+	}
+
+	// Running daemon fetches queued feeds before regular due ones
+	if liveDaemons > 0 {
+		txErr := s.db.InTx(ctx, func(qtx database.Querier) error {
+			for _, feed := range feeds {
+				enqueueErr := qtx.EnqueueFeedFetch(ctx, database.EnqueueFeedFetchParams{
+					ID:          uuid.New(),
+					FeedID:      feed.ID,
+					Priority:    priority,
+					RequestedAt: time.Now(),
+					RequestedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+				})
+				if enqueueErr != nil {
+					return fmt.Errorf("error queueing refresh of feed %s: %v", feed.Name, enqueueErr)
+				}
+			}
+			return nil
+		})
+		if txErr != nil {
+			return txErr
+		}
+		fmt.Printf("Queued %d feeds for refresh, running agg will fetch them shortly \n", len(feeds))
+		return nil
+	}
+
+	fetchCtx, cancelFetch := withShutdownGrace(ctx, aggShutdownGrace)
+	defer cancelFetch()
+
+	return runAggInstance(ctx, fetchCtx, s, false, func(instance aggInstance) error {
+		return aggOnce(ctx, fetchCtx, s, claimFeeds(fetchCtx, s, instance, feeds))
+	})
+}
+
+// Resolves refresh argument to feeds and their queue priority
+func refreshTargets(ctx context.Context, s *state, target string, user database.User) ([]database.Feed, int32, error) {
+	if target != "--all-followed" {
+		feed, feedErr := s.db.GetFeedByUrl(ctx, target)
+		if feedErr != nil {
+			return nil, 0, fmt.Errorf("error getting feed from db by url %s: %v", target, feedErr)
+		}
+		return []database.Feed{feed}, refreshFeedPriority, nil
+	}
+
+	follows, followsErr := s.db.GetFeedFollowsForUser(ctx, user.ID)
+	if followsErr != nil {
+		return nil, 0, fmt.Errorf("error getting feed follow for user %s: %v", user.Name, followsErr)
+	}
+
+	feeds := make([]database.Feed, 0, len(follows))
+	for _, follow := range follows {
+		feed, feedErr := s.db.GetFeedById(ctx, follow.FeedID)
+		if feedErr != nil {
+			return nil, 0, fmt.Errorf("error getting followed feed %s: %v", follow.FeedName, feedErr)
+		}
+		feeds = append(feeds, feed)
+	}
+
+	return feeds, refreshFollowedPriority, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+// Number of results printed by search without --limit
+const searchDefaultLimit = 10
+
+const searchUsage = "search command expects arguments: <query> [--feed <feed url>] [--since <24h|2006-01-02>] [--limit <n>]"
+
+// Searches posts of followed feeds, query uses web search syntax: "exact phrase", -excluded, or
+func handleSearch(ctx context.Context, s *state, cmd command, user database.User) error {
+	var queryWords []string
+	feedUrl := sql.NullString{}
+	since := time.Time{}
+	limit := searchDefaultLimit
+
+	for i := 0; i < len(cmd.args); i++ {
+		arg := cmd.args[i]
+		if arg != "--feed" && arg != "--since" && arg != "--limit" {
+			queryWords = append(queryWords, arg)
+			continue
+		}
+		if i+1 >= len(cmd.args) {
+			return fmt.Errorf("%s expects a value", arg)
+		}
+		i++
+
+		switch arg {
+		case "--feed":
+			feedUrl = parseToNullString(cmd.args[i])
+		case "--since":
+			parsedSince, sinceErr := parseSince(cmd.args[i])
+			if sinceErr != nil {
+				return sinceErr
+			}
+			since = parsedSince
+		case "--limit":
+			parsedLimit, parseErr := strconv.Atoi(cmd.args[i])
+			if parseErr != nil || parsedLimit < 1 {
+				return fmt.Errorf("invalid --limit value %s, expected positive number", cmd.args[i])
+			}
+			limit = parsedLimit
+		}
+	}
+
+	query := strings.Join(queryWords, " ")
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf(searchUsage)
+	}
+
+	results, err := s.db.SearchPosts(ctx, database.SearchPostsParams{
+		Query:      query,
+		UserID:     user.ID,
+		FeedUrl:    feedUrl,
+		Since:      since,
+		MaxResults: int32(limit),
+	})
+	if err != nil {
+		return fmt.Errorf("error searching posts for user %s: %v", user.Name, err)
+	}
+
+	fmt.Printf("Found %d posts for query: %s \n", len(results), query)
+	fmt.Printf("==================== \n")
+	for _, result := range results {
+		fmt.Printf("Title: %s \n", result.Title)
+		fmt.Printf("Url: %s \n", result.Url)
+		if result.PublishedAt.Valid {
+			fmt.Printf("Published: %s \n", result.PublishedAt.Time)
+		}
+		fmt.Printf("Feed source: %s, rank: %.3g \n", result.FeedName, result.Rank)
+		fmt.Printf("%s \n", result.Snippet)
+		fmt.Printf("==================== \n")
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/canonical"
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+// Finds post by its link, any tracking variant of the link stored post came from works.
+// Same link posted by several feeds resolves to the copy from a feed followed by user
+func findPostByUrl(ctx context.Context, s *state, user database.User, postUrl string) (database.Post, error) {
+	lookupUrl, normalizeErr := canonical.Normalize(postUrl, trackingParams(s))
+	if normalizeErr != nil {
+		lookupUrl = postUrl
+	}
+
+	post, postErr := s.db.GetPostByUrl(ctx, database.GetPostByUrlParams{UserID: user.ID, Url: lookupUrl})
+	if errors.Is(postErr, sql.ErrNoRows) {
+		return database.Post{}, fmt.Errorf("post %s not found", postUrl)
+	}
+	if postErr != nil {
+		return database.Post{}, fmt.Errorf("error getting post %s: %v", postUrl, postErr)
+	}
+	return post, nil
+}
+
+// Stars post with optional note, starred posts are never pruned
+func handleStar(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) == 0 {
+		return fmt.Errorf("star command expects arguments: <post url> [note]")
+	}
+	postUrl := cmd.args[0]
+	note := parseToNullString(strings.Join(cmd.args[1:], " "))
+
+	post, postErr := findPostByUrl(ctx, s, user, postUrl)
+	if postErr != nil {
+		return postErr
+	}
+
+	starErr := s.db.StarPost(ctx, database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    post.ID,
+		CreatedAt: time.Now(),
+		Note:      note,
+	})
+	if starErr != nil {
+		return fmt.Errorf("error starring post %s: %v", postUrl, starErr)
+	}
+
+	fmt.Printf("Post %s starred by user %s \n", post.Title, user.Name)
+	return nil
+}
+
+func handleUnstar(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("unstar command expects one argument of post url")
+	}
+	postUrl := cmd.args[0]
+
+	post, postErr := findPostByUrl(ctx, s, user, postUrl)
+	if postErr != nil {
+		return postErr
+	}
+
+	removed, unstarErr := s.db.UnstarPost(ctx, database.UnstarPostParams{UserID: user.ID, PostID: post.ID})
+	if unstarErr != nil {
+		return fmt.Errorf("error unstarring post %s: %v", postUrl, unstarErr)
+	}
+	if removed == 0 {
+		return fmt.Errorf("post %s is not starred by user %s", postUrl, user.Name)
+	}
+
+	fmt.Printf("Post %s unstarred \n", post.Title)
+	return nil
+}
+
+func handleStarred(ctx context.Context, s *state, cmd command, user database.User) error {
+	starred, err := s.db.GetStarredPosts(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting starred posts of user %s: %v", user.Name, err)
+	}
+
+	fmt.Printf("Starred posts of user: %s \n", user.Name)
+	fmt.Printf("==================== \n")
+	for _, post := range starred {
+		fmt.Printf("Title: %s \n", post.Title)
+		fmt.Printf("Url: %s \n", post.Url)
+		fmt.Printf("Feed source: %s, starred: %s \n", post.FeedName, post.StarredAt)
+		if post.Note.Valid {
+			fmt.Printf("Note: %s \n", post.Note.String)
+		}
+		fmt.Printf("==================== \n")
+	}
+
+	return nil
+}
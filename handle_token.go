@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Marks gator tokens so they are easy to recognise, eg. by secret scanners
+const tokenPrefix = "gator_"
+
+// Name of token issued when user registers
+const defaultTokenName = "default"
+
+// New random token and SHA-256 hash of it, only the hash is stored
+func newApiToken() (string, []byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("error generating token: %v", err)
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+// Creates token of user, returned value is the only time the token is known
+func issueApiToken(ctx context.Context, q database.Querier, userID uuid.UUID, name string, expiresAt sql.NullTime) (string, error) {
+	token, hash, err := newApiToken()
+	if err != nil {
+		return "", err
+	}
+	_, createErr := q.CreateApiToken(ctx, database.CreateApiTokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hash,
+		ExpiresAt: expiresAt,
+	})
+	if createErr != nil {
+		return "", fmt.Errorf("error creating token %s: %v", name, createErr)
+	}
+	return token, nil
+}
+
+// Resolves user of session token from config. Expired or revoked token ends the session
+func sessionUser(ctx context.Context, s *state) (database.User, error) {
+	if s.config.SESSION_TOKEN == "" && s.config.CURRENT_USER_NAME != "" {
+		return database.User{}, fmt.Errorf("session of %s from version before API tokens is no longer valid, log in with token printed by migrate up or issued by an admin: login <token>", s.config.CURRENT_USER_NAME)
+	}
+	if s.config.SESSION_TOKEN == "" {
+		return database.User{}, fmt.Errorf("not logged in, log in with: login <token>")
+	}
+	user, err := s.db.GetUserByTokenHash(ctx, database.GetUserByTokenHashParams{
+		TokenHash: hashToken(s.config.SESSION_TOKEN),
+		ValidAt:   time.Now(),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("session token is expired or revoked, log in with: login <token>")
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("error getting user of session: %v", err)
+	}
+	return user, nil
+}
+
+// Users get tokens for themselves, admins also for other users eg. ones who lost all their tokens
+func handleToken(ctx context.Context, s *state, cmd command, user database.User) error {
+	usageErr := fmt.Errorf("token command expects arguments: create <name> [--expires <30d|720h>] [--for <user>] | list | revoke <name>")
+	if len(cmd.args) == 0 {
+		return usageErr
+	}
+
+	switch cmd.args[0] {
+	case "create":
+		if len(cmd.args) < 2 {
+			return usageErr
+		}
+		name := cmd.args[1]
+		expiresAt := sql.NullTime{}
+		owner := user
+		for i := 2; i < len(cmd.args); i++ {
+			flag := cmd.args[i]
+			if i+1 >= len(cmd.args) {
+				return usageErr
+			}
+			i++
+
+			switch flag {
+			case "--expires":
+				validFor, parseErr := parseRetentionAge(cmd.args[i])
+				if parseErr != nil {
+					return fmt.Errorf("invalid --expires value: %v", parseErr)
+				}
+				expiresAt = sql.NullTime{Time: time.Now().Add(validFor), Valid: true}
+			case "--for":
+				if cmd.args[i] == user.Name {
+					continue
+				}
+				if user.Role != roleAdmin {
+					return fmt.Errorf("creating token for another user requires admin role, %s is %s", user.Name, user.Role)
+				}
+				forUser, forErr := s.db.GetUser(ctx, cmd.args[i])
+				if errors.Is(forErr, sql.ErrNoRows) {
+					return fmt.Errorf("user %s does not exist", cmd.args[i])
+				}
+				if forErr != nil {
+					return fmt.Errorf("error getting user %s: %v", cmd.args[i], forErr)
+				}
+				owner = forUser
+			default:
+				return usageErr
+			}
+		}
+
+		token, issueErr := issueApiToken(ctx, s.db, owner.ID, name, expiresAt)
+		if issueErr != nil {
+			return issueErr
+		}
+		fmt.Printf("Token %s created for %s, it is shown only once: \n%s \n", name, owner.Name, token)
+		return nil
+
+	case "list":
+		if len(cmd.args) != 1 {
+			return usageErr
+		}
+		tokens, err := s.db.GetApiTokensForUser(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("error getting tokens of %s: %v", user.Name, err)
+		}
+
+		sessionHash := hashToken(s.config.SESSION_TOKEN)
+		for _, token := range tokens {
+			expires := "never expires"
+			if token.ExpiresAt.Valid && token.ExpiresAt.Time.After(time.Now()) {
+				expires = "expires " + token.ExpiresAt.Time.Format("2006-01-02 15:04")
+			} else if token.ExpiresAt.Valid {
+				expires = "expired " + token.ExpiresAt.Time.Format("2006-01-02 15:04")
+			}
+			current := ""
+			if subtle.ConstantTimeCompare(token.TokenHash, sessionHash) == 1 {
+				current = " (current)"
+			}
+			fmt.Printf("* %s%s created %s, %s \n", token.Name, current, token.CreatedAt.Format("2006-01-02 15:04"), expires)
+		}
+		return nil
+
+	case "revoke":
+		if len(cmd.args) != 2 {
+			return usageErr
+		}
+		name := cmd.args[1]
+		revoked, err := s.db.DeleteApiToken(ctx, database.DeleteApiTokenParams{UserID: user.ID, Name: name})
+		if err != nil {
+			return fmt.Errorf("error revoking token %s: %v", name, err)
+		}
+		if revoked == 0 {
+			return fmt.Errorf("%s has no token named %s", user.Name, name)
+		}
+		fmt.Printf("Token %s revoked \n", name)
+		if _, sessionErr := sessionUser(ctx, s); sessionErr != nil {
+			fmt.Printf("It was the token of this session, log in again with: login <token> \n")
+		}
+		return nil
+	}
+
+	return usageErr
+}
+
+// Users created before API tokens have none and could not log in, so migration adding
+// tokens is followed by issuing one to each of them. Only whoever can migrate the
+// database sees them, which replaces trusting user name in config of older versions
+func issueMigratedTokens(ctx context.Context, s *state) error {
+	type issued struct{ user, token string }
+	var tokens []issued
+	err := s.db.InTx(ctx, func(qtx database.Querier) error {
+		users, usersErr := qtx.GetUsers(ctx)
+		if usersErr != nil {
+			return usersErr
+		}
+		for _, user := range users {
+			existing, tokensErr := qtx.GetApiTokensForUser(ctx, user.ID)
+			if tokensErr != nil {
+				return tokensErr
+			}
+			if len(existing) > 0 {
+				continue
+			}
+			token, issueErr := issueApiToken(ctx, qtx, user.ID, defaultTokenName, sql.NullTime{})
+			if issueErr != nil {
+				return issueErr
+			}
+			tokens = append(tokens, issued{user: user.Name, token: token})
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error issuing tokens to existing users: %v", err)
+	}
+
+	for _, t := range tokens {
+		fmt.Printf("Token %s issued for %s, it is shown only once: %s \n", defaultTokenName, t.user, t.token)
+	}
+	if len(tokens) > 0 {
+		fmt.Printf("Hand the tokens to their users, they log in with: login <token> \n")
+	}
+	return nil
+}
+
+// Token of login command is read from its argument, or from stdin when it is -
+// so it does not end up in shell history
+func readLoginToken(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("login command expects single argument of API token, or - to read it from stdin")
+	}
+	if args[0] != "-" {
+		return args[0], nil
+	}
+
+	var token string
+	if _, err := fmt.Scanln(&token); err != nil {
+		return "", fmt.Errorf("error reading token from stdin: %v", err)
+	}
+	return strings.TrimSpace(token), nil
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+var tokenRegexp = regexp.MustCompile(tokenPrefix + `[A-Za-z0-9_-]+`)
+
+// Token printed by command, tokens are only ever shown in output
+func printedToken(t *testing.T, output string) string {
+	t.Helper()
+	token := tokenRegexp.FindString(output)
+	if token == "" {
+		t.Fatalf("no token in output %q", output)
+	}
+	return token
+}
+
+func TestSessionUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+
+	valid, err := issueApiToken(ctx, s.db, user.ID, "laptop", sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true})
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	expired, err := issueApiToken(ctx, s.db, user.ID, "old", sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true})
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		token    string
+		userName string
+		wantErr  string
+	}{
+		{"valid token", valid, "", ""},
+		{"expired token", expired, "", "expired or revoked"},
+		{"unknown token", tokenPrefix + "unknown", "", "expired or revoked"},
+		{"no session", "", "", "not logged in"},
+		// config of versions before tokens trusted the user name
+		{"legacy user name", "", "alice", "no longer valid"},
+	}
+	for _, tc := range cases {
+		s.config.SESSION_TOKEN, s.config.CURRENT_USER_NAME = tc.token, tc.userName
+		got, err := sessionUser(ctx, s)
+		if tc.wantErr == "" && (err != nil || got.ID != user.ID) {
+			t.Errorf("%s: session user is %+v (%v), want alice", tc.name, got, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%s: session user is %+v (%v), want error with %q", tc.name, got, err, tc.wantErr)
+		}
+	}
+}
+
+func TestHandleTokenRevoke(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+	token, err := issueApiToken(ctx, s.db, user.ID, defaultTokenName, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	s.config.SESSION_TOKEN = token
+
+	output := captureOutput(t, func() {
+		if err := handleToken(ctx, s, command{name: "token", args: []string{"revoke", defaultTokenName}}, user); err != nil {
+			t.Fatalf("revoke failed: %v", err)
+		}
+	})
+	if !strings.Contains(output, "log in again") {
+		t.Errorf("revoking token of session printed %q, want hint to log in again", output)
+	}
+	if _, err := sessionUser(ctx, s); err == nil {
+		t.Error("revoked token still grants session")
+	}
+	if err := handleToken(ctx, s, command{name: "token", args: []string{"revoke", defaultTokenName}}, user); err == nil {
+		t.Error("revoking token twice succeeded")
+	}
+}
+
+func TestHandleTokenCreateFor(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	member := createTestUser(t, s, "alice")
+	other := createTestUser(t, s, "bob")
+	createTestUser(t, s, "carol")
+	admin, err := s.db.SetUserRole(ctx, database.SetUserRoleParams{Name: "carol", Role: roleAdmin, UpdatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("error promoting carol: %v", err)
+	}
+
+	// member can not take over another user by issuing token for them
+	if err := handleToken(ctx, s, command{name: "token", args: []string{"create", "stolen", "--for", "bob"}}, member); err == nil {
+		t.Fatal("member created token for another user")
+	}
+	tokens, err := s.db.GetApiTokensForUser(ctx, other.ID)
+	if err != nil || len(tokens) != 0 {
+		t.Fatalf("bob has tokens %+v (%v), want none", tokens, err)
+	}
+
+	// --for naming the user itself is allowed to anyone
+	captureOutput(t, func() {
+		if err := handleToken(ctx, s, command{name: "token", args: []string{"create", "laptop", "--for", "alice"}}, member); err != nil {
+			t.Errorf("member creating token for themselves failed: %v", err)
+		}
+	})
+
+	output := captureOutput(t, func() {
+		if err := handleToken(ctx, s, command{name: "token", args: []string{"create", "recovery", "--for", "bob", "--expires", "1d"}}, admin); err != nil {
+			t.Fatalf("admin creating token for bob failed: %v", err)
+		}
+	})
+	s.config.SESSION_TOKEN = printedToken(t, output)
+	if user, err := sessionUser(ctx, s); err != nil || user.ID != other.ID {
+		t.Errorf("token created for bob logs in as %+v (%v), want bob", user, err)
+	}
+
+	if err := handleToken(ctx, s, command{name: "token", args: []string{"create", "x", "--for", "nobody"}}, admin); err == nil {
+		t.Error("creating token for unknown user succeeded")
+	}
+	if err := handleToken(ctx, s, command{name: "token", args: []string{"create", "x", "--expires", "soon"}}, admin); err == nil {
+		t.Error("creating token with invalid expiry succeeded")
+	}
+}
+
+func TestHandlerLogin(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ctx := context.Background()
+	s := newTestState(t)
+	user := createTestUser(t, s, "alice")
+	token, err := issueApiToken(ctx, s.db, user.ID, defaultTokenName, sql.NullTime{})
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	expired, err := issueApiToken(ctx, s.db, user.ID, "old", sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true})
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	s.config.CURRENT_USER_NAME = "alice"
+
+	for _, invalid := range []string{expired, tokenPrefix + "unknown", "alice"} {
+		if err := handlerLogin(ctx, s, command{name: "login", args: []string{invalid}}); err == nil {
+			t.Errorf("login with %q succeeded", invalid)
+		}
+	}
+	if s.config.SESSION_TOKEN != "" {
+		t.Fatalf("failed login stored session %q", s.config.SESSION_TOKEN)
+	}
+
+	captureOutput(t, func() {
+		if err := handlerLogin(ctx, s, command{name: "login", args: []string{token}}); err != nil {
+			t.Fatalf("login failed: %v", err)
+		}
+	})
+	if s.config.SESSION_TOKEN != token || s.config.CURRENT_USER_NAME != "" {
+		t.Errorf("login left session %q and user name %q, want token and no user name", s.config.SESSION_TOKEN, s.config.CURRENT_USER_NAME)
+	}
+	written, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".gatorconfig.json"))
+	if err != nil {
+		t.Fatalf("error reading written config: %v", err)
+	}
+	if bytes.Contains(written, []byte("current_user_name")) {
+		t.Errorf("written config %s still holds legacy user name", written)
+	}
+}
+
+// Tokens are only known when created, database keeps just their SHA-256
+func TestTokensStoredAsHash(t *testing.T) {
+	ctx := context.Background()
+	s := newSQLiteTestState(t)
+	if _, err := runMigrate(t, ctx, s, "up"); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+
+	output := captureOutput(t, func() {
+		if err := handleRegister(ctx, s, command{name: "register", args: []string{"alice", "--admin"}}); err != nil {
+			t.Fatalf("register failed: %v", err)
+		}
+	})
+	token := printedToken(t, output)
+
+	// legacy user without token gets one when tokens are introduced
+	createTestUser(t, s, "bob")
+	output = captureOutput(t, func() {
+		if err := issueMigratedTokens(ctx, s); err != nil {
+			t.Fatalf("error issuing migrated tokens: %v", err)
+		}
+	})
+	if strings.Count(output, tokenPrefix) != 1 || !strings.Contains(output, "issued for bob") {
+		t.Fatalf("issuing migrated tokens printed %q, want single token for bob", output)
+	}
+	migrated := printedToken(t, output)
+
+	rows, err := s.db.DB().QueryContext(ctx, "SELECT * FROM api_tokens")
+	if err != nil {
+		t.Fatalf("error reading tokens table: %v", err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatalf("error reading columns: %v", err)
+	}
+	stored := 0
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			t.Fatalf("error scanning token row: %v", err)
+		}
+		for i, value := range values {
+			var raw []byte
+			switch v := value.(type) {
+			case []byte:
+				raw = v
+			case string:
+				raw = []byte(v)
+			}
+			for _, plain := range []string{token, migrated} {
+				if bytes.Contains(raw, []byte(strings.TrimPrefix(plain, tokenPrefix))) {
+					t.Errorf("column %s holds plaintext token", columns[i])
+				}
+			}
+		}
+		stored++
+	}
+	if stored != 2 {
+		t.Errorf("got %d stored tokens, want 2", stored)
+	}
+
+	for _, plain := range []string{token, migrated} {
+		hash := sha256.Sum256([]byte(plain))
+		var count int
+		if err := s.db.DB().QueryRowContext(ctx, "SELECT count(*) FROM api_tokens WHERE token_hash = ?", hash[:]).Scan(&count); err != nil || count != 1 {
+			t.Errorf("found %d tokens by hash (%v), want 1", count, err)
+		}
+	}
+}
@@ -2,46 +2,59 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 	"github.com/MichalGul/blog_aggregator/internal/database"
 	"github.com/google/uuid"
 )
 
-func handlerLogin(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("login command expects single argument")
+func handlerLogin(ctx context.Context, s *state, cmd command) error {
+	token, tokenErr := readLoginToken(cmd.args)
+	if tokenErr != nil {
+		return tokenErr
 	}
-	username := cmd.args[0]
 
-	user, db_err := s.db.GetUser(context.Background(), username)
+	user, db_err := s.db.GetUserByTokenHash(ctx, database.GetUserByTokenHashParams{
+		TokenHash: hashToken(token),
+		ValidAt:   time.Now(),
+	})
+	if errors.Is(db_err, sql.ErrNoRows) {
+		return fmt.Errorf("token is invalid, expired or revoked")
+	}
 	if db_err != nil {
-		return fmt.Errorf("error getting user from database %v", db_err)
+		return fmt.Errorf("error getting user of token from database %v", db_err)
 	}
 
-	err := s.config.SetUser(user.Name)
+	err := s.config.SetSession(token)
 	if err != nil {
-		return fmt.Errorf("error while seting user to %s: %w", cmd.args[0], err)
+		return fmt.Errorf("error while storing session of %s: %w", user.Name, err)
 	}
 
-	fmt.Printf("User has been set to %s \n", username)
+	fmt.Printf("Logged in as %s \n", user.Name)
 
 	return nil
 
 }
 
-func handlerUsers(s *state, cmd command) error {
-	users, db_err := s.db.GetUsers(context.Background())
+func handlerUsers(ctx context.Context, s *state, cmd command) error {
+	users, db_err := s.db.GetUsers(ctx)
 	if db_err != nil {
 		return fmt.Errorf("error while listring users from database: %v", db_err)
 	}
 
-	loggedUser := s.config.CURRENT_USER_NAME
+	// Listing works without session, then no user is marked as current
+	loggedUser, _ := sessionUser(ctx, s)
 	for _, user := range users {
-		if user.Name == loggedUser {
-			fmt.Printf("* %s (current)\n", user.Name)
+		role := ""
+		if user.Role == roleAdmin {
+			role = " (admin)"
+		}
+		if user.ID == loggedUser.ID {
+			fmt.Printf("* %s%s (current)\n", user.Name, role)
 		} else {
-			fmt.Printf("* %s\n", user.Name)
+			fmt.Printf("* %s%s\n", user.Name, role)
 		}
 	}
 
@@ -49,25 +62,63 @@ func handlerUsers(s *state, cmd command) error {
 
 }
 
-func handleRegister(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("register command expects single argument of user name")
+func handleRegister(ctx context.Context, s *state, cmd command) error {
+	if len(cmd.args) != 1 && (len(cmd.args) != 2 || cmd.args[1] != "--admin") {
+		return fmt.Errorf("register command expects arguments: <name> [--admin]")
 	}
 	username := cmd.args[0]
+	asAdmin := len(cmd.args) == 2
+
+	// New user gets first token right away, otherwise nobody could log in as them
+	var createdUser database.User
+	var token string
+	errNotFirst := fmt.Errorf("--admin is allowed only for the first user, ask an admin to run: promote %s", username)
+	db_err := s.db.InTx(ctx, func(qtx database.Querier) error {
+		// Only the first user may register as admin, later ones are promoted by an admin
+		if asAdmin {
+			users, countErr := qtx.CountUsers(ctx)
+			if countErr != nil {
+				return countErr
+			}
+			if users > 0 {
+				return errNotFirst
+			}
+		}
 
-	createdUser, db_err := s.db.CreateUser(context.Background(), database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      username,
+		var createErr error
+		createdUser, createErr = qtx.CreateUser(ctx, database.CreateUserParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      username,
+		})
+		if createErr != nil {
+			return createErr
+		}
+		if asAdmin {
+			createdUser, createErr = qtx.SetUserRole(ctx, database.SetUserRoleParams{
+				Name:      username,
+				Role:      roleAdmin,
+				UpdatedAt: time.Now(),
+			})
+			if createErr != nil {
+				return createErr
+			}
+		}
+		var issueErr error
+		token, issueErr = issueApiToken(ctx, qtx, createdUser.ID, defaultTokenName, sql.NullTime{})
+		return issueErr
 	})
+	if errors.Is(db_err, errNotFirst) {
+		return errNotFirst
+	}
 	if db_err != nil {
 		return fmt.Errorf("error while adding user %s to database %w", username, db_err)
 	}
 
-	err := s.config.SetUser(username)
+	err := s.config.SetSession(token)
 	if err != nil {
-		return fmt.Errorf("error while seting user to %s: %w", cmd.args[0], err)
+		return fmt.Errorf("error while storing session of %s: %w", cmd.args[0], err)
 	}
 
 	fmt.Printf("User was successfuly created \n")
@@ -75,6 +126,30 @@ func handleRegister(s *state, cmd command) error {
 	fmt.Printf("Created at: %v \n", createdUser.CreatedAt)
 	fmt.Printf("Updated at: %v \n", createdUser.UpdatedAt)
 	fmt.Printf("Name: %v \n", createdUser.Name)
+	fmt.Printf("Role: %v \n", createdUser.Role)
+	fmt.Printf("Token %s, it is shown only once: %s \n", defaultTokenName, token)
+
+	return nil
+}
+
+func handlePromote(ctx context.Context, s *state, cmd command, user database.User) error {
+	if len(cmd.args) != 1 {
+		return fmt.Errorf("promote command expects single argument of user name")
+	}
+	username := cmd.args[0]
+
+	promoted, db_err := s.db.SetUserRole(ctx, database.SetUserRoleParams{
+		Name:      username,
+		Role:      roleAdmin,
+		UpdatedAt: time.Now(),
+	})
+	if errors.Is(db_err, sql.ErrNoRows) {
+		return fmt.Errorf("user %s does not exist", username)
+	}
+	if db_err != nil {
+		return fmt.Errorf("error while promoting user %s: %v", username, db_err)
+	}
 
+	fmt.Printf("User %s is now %s \n", promoted.Name, promoted.Role)
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+func handleUser(ctx context.Context, s *state, cmd command, user database.User) error {
+	usageErr := fmt.Errorf("user command expects arguments: show <name> | rename <name> <new name> | delete <name> [--transfer-to <user>] [--yes] | transfer-feeds <from> <to>")
+	if len(cmd.args) == 0 {
+		return usageErr
+	}
+
+	switch cmd.args[0] {
+	case "show":
+		if len(cmd.args) != 2 {
+			return usageErr
+		}
+		return showUser(ctx, s, user, cmd.args[1])
+	case "rename":
+		if len(cmd.args) != 3 {
+			return usageErr
+		}
+		return renameUser(ctx, s, user, cmd.args[1], cmd.args[2])
+	case "delete":
+		if len(cmd.args) < 2 {
+			return usageErr
+		}
+		transferTo, confirmed := "", false
+		for i := 2; i < len(cmd.args); i++ {
+			switch {
+			case cmd.args[i] == "--yes":
+				confirmed = true
+			case cmd.args[i] == "--transfer-to" && i+1 < len(cmd.args):
+				i++
+				transferTo = cmd.args[i]
+			default:
+				return usageErr
+			}
+		}
+		return deleteUser(ctx, s, user, cmd.args[1], transferTo, confirmed)
+	case "transfer-feeds":
+		if len(cmd.args) != 3 {
+			return usageErr
+		}
+		return transferFeeds(ctx, s, user, cmd.args[1], cmd.args[2])
+	}
+
+	return usageErr
+}
+
+// User the command acts on. Users may manage themselves, other users only admins
+func managedUser(ctx context.Context, s *state, user database.User, name, action string) (database.User, error) {
+	if name != user.Name && user.Role != roleAdmin {
+		return database.User{}, fmt.Errorf("%s of another user requires admin role, %s is %s", action, user.Name, user.Role)
+	}
+	target, err := s.db.GetUser(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("user %s does not exist", name)
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("error getting user %s: %v", name, err)
+	}
+	return target, nil
+}
+
+func showUser(ctx context.Context, s *state, user database.User, name string) error {
+	target, targetErr := managedUser(ctx, s, user, name, "showing")
+	if targetErr != nil {
+		return targetErr
+	}
+	activity, activityErr := s.db.GetUserActivity(ctx, target.ID)
+	if activityErr != nil {
+		return fmt.Errorf("error getting activity of %s: %v", target.Name, activityErr)
+	}
+	feeds, feedsErr := s.db.GetOwnedFeeds(ctx, target.ID)
+	if feedsErr != nil {
+		return fmt.Errorf("error getting feeds of %s: %v", target.Name, feedsErr)
+	}
+
+	fmt.Printf("Name: %s \n", target.Name)
+	fmt.Printf("Role: %s \n", target.Role)
+	fmt.Printf("Created at: %s \n", target.CreatedAt.Format("2006-01-02 15:04"))
+	fmt.Printf("Tokens: %d \n", activity.Tokens)
+	fmt.Printf("Follows: %d feeds with %d posts \n", activity.Follows, activity.FollowedPosts)
+	fmt.Printf("Starred: %d posts \n", activity.Starred)
+	fmt.Printf("Feeds created: %d \n", len(feeds))
+	for _, feed := range feeds {
+		fmt.Printf("* %s %s, %d posts, followed by %d other users \n", feed.Name, feed.Url, feed.Posts, feed.OtherFollowers)
+	}
+	return nil
+}
+
+func renameUser(ctx context.Context, s *state, user database.User, name, newName string) error {
+	target, targetErr := managedUser(ctx, s, user, name, "renaming")
+	if targetErr != nil {
+		return targetErr
+	}
+	if _, takenErr := s.db.GetUser(ctx, newName); takenErr == nil {
+		return fmt.Errorf("user name %s is already taken", newName)
+	} else if !errors.Is(takenErr, sql.ErrNoRows) {
+		return fmt.Errorf("error checking user name %s: %v", newName, takenErr)
+	}
+
+	renamed, err := s.db.RenameUser(ctx, database.RenameUserParams{
+		NewName:   newName,
+		UpdatedAt: time.Now(),
+		Name:      target.Name,
+	})
+	if err != nil {
+		return fmt.Errorf("error renaming user %s to %s: %v", target.Name, newName, err)
+	}
+
+	// Session is bound to token, so renamed user stays logged in
+	fmt.Printf("User %s renamed to %s \n", target.Name, renamed.Name)
+	return nil
+}
+
+// Deleting user cascades to its tokens, follows, stars and feeds with their posts, so
+// it is previewed first and has to be confirmed by typing user name unless --yes is given.
+// Feeds followed by other users are never deleted with their creator, they have to be
+// transferred to another user with --transfer-to
+func deleteUser(ctx context.Context, s *state, user database.User, name, transferTo string, confirmed bool) error {
+	target, targetErr := managedUser(ctx, s, user, name, "deleting")
+	if targetErr != nil {
+		return targetErr
+	}
+
+	if target.Role == roleAdmin {
+		users, usersErr := s.db.GetUsers(ctx)
+		if usersErr != nil {
+			return fmt.Errorf("error getting users: %v", usersErr)
+		}
+		admins := 0
+		for _, other := range users {
+			if other.Role == roleAdmin {
+				admins++
+			}
+		}
+		if admins == 1 && len(users) > 1 {
+			return fmt.Errorf("%s is the last admin, promote another user before deleting them", target.Name)
+		}
+	}
+
+	var heir database.User
+	if transferTo != "" {
+		var heirErr error
+		heir, heirErr = feedsHeir(ctx, s, target, transferTo)
+		if heirErr != nil {
+			return heirErr
+		}
+	}
+
+	activity, activityErr := s.db.GetUserActivity(ctx, target.ID)
+	if activityErr != nil {
+		return fmt.Errorf("error getting activity of %s: %v", target.Name, activityErr)
+	}
+	feeds, feedsErr := s.db.GetOwnedFeeds(ctx, target.ID)
+	if feedsErr != nil {
+		return fmt.Errorf("error getting feeds of %s: %v", target.Name, feedsErr)
+	}
+
+	followedByOthers := 0
+	for _, feed := range feeds {
+		if feed.OtherFollowers > 0 {
+			followedByOthers++
+		}
+	}
+	if followedByOthers > 0 && transferTo == "" {
+		return fmt.Errorf("%d feeds of %s are followed by other users, keep them with: user delete %s --transfer-to <user>", followedByOthers, target.Name, target.Name)
+	}
+
+	fmt.Printf("Deleting user %s also deletes: \n", target.Name)
+	fmt.Printf("* %d tokens, %d follows and %d starred posts \n", activity.Tokens, activity.Follows, activity.Starred)
+	for _, feed := range feeds {
+		if transferTo != "" {
+			fmt.Printf("* feed %s %s with %d posts and %d other followers is kept, it is transferred to %s \n", feed.Name, feed.Url, feed.Posts, feed.OtherFollowers, heir.Name)
+		} else {
+			fmt.Printf("* feed %s %s with %d posts \n", feed.Name, feed.Url, feed.Posts)
+		}
+	}
+
+	if !confirmed {
+		fmt.Printf("Type %s to confirm: ", target.Name)
+		answer, readErr := bufio.NewReader(os.Stdin).ReadString('\n')
+		if readErr != nil && answer == "" {
+			return fmt.Errorf("error reading confirmation: %v", readErr)
+		}
+		if strings.TrimSpace(answer) != target.Name {
+			return fmt.Errorf("deleting user %s was not confirmed", target.Name)
+		}
+	}
+
+	// Feeds may have been followed since the preview, so the check is repeated with the delete
+	var deleted int64
+	err := s.db.InTx(ctx, func(qtx database.Querier) error {
+		if transferTo != "" {
+			if _, moveErr := moveFeeds(ctx, qtx, target, heir); moveErr != nil {
+				return moveErr
+			}
+		} else {
+			owned, ownedErr := qtx.GetOwnedFeeds(ctx, target.ID)
+			if ownedErr != nil {
+				return ownedErr
+			}
+			for _, feed := range owned {
+				if feed.OtherFollowers > 0 {
+					return fmt.Errorf("feed %s is now followed by other users, keep it with --transfer-to <user>", feed.Name)
+				}
+			}
+		}
+
+		var deleteErr error
+		deleted, deleteErr = qtx.DeleteUser(ctx, target.ID)
+		return deleteErr
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting user %s: %v", target.Name, err)
+	}
+	if deleted == 0 {
+		return fmt.Errorf("user %s was already deleted", target.Name)
+	}
+	fmt.Printf("User %s was deleted \n", target.Name)
+
+	// Tokens of the session were deleted with the user
+	if target.ID == user.ID {
+		if sessionErr := s.config.SetSession(""); sessionErr != nil {
+			return fmt.Errorf("error clearing session: %v", sessionErr)
+		}
+		fmt.Printf("You were logged out \n")
+	}
+	return nil
+}
+
+// User receiving feeds of another one
+func feedsHeir(ctx context.Context, s *state, from database.User, name string) (database.User, error) {
+	heir, err := s.db.GetUser(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, fmt.Errorf("user %s does not exist", name)
+	}
+	if err != nil {
+		return database.User{}, fmt.Errorf("error getting user %s: %v", name, err)
+	}
+	if heir.ID == from.ID {
+		return database.User{}, fmt.Errorf("feeds of %s cannot be transferred to themselves", from.Name)
+	}
+	return heir, nil
+}
+
+// Makes heir creator of all feeds of user. Feed names are unique per user, so clashing
+// ones have to be renamed or deleted first
+func moveFeeds(ctx context.Context, qtx database.Querier, from, heir database.User) (int64, error) {
+	fromFeeds, feedsErr := qtx.GetOwnedFeeds(ctx, from.ID)
+	if feedsErr != nil {
+		return 0, feedsErr
+	}
+	heirFeeds, feedsErr := qtx.GetOwnedFeeds(ctx, heir.ID)
+	if feedsErr != nil {
+		return 0, feedsErr
+	}
+	heirNames := map[string]bool{}
+	for _, feed := range heirFeeds {
+		heirNames[feed.Name] = true
+	}
+	var clashing []string
+	for _, feed := range fromFeeds {
+		if heirNames[feed.Name] {
+			clashing = append(clashing, feed.Name)
+		}
+	}
+	if len(clashing) > 0 {
+		return 0, fmt.Errorf("%s already has feeds named %s, delete or re-add them under other names first", heir.Name, strings.Join(clashing, ", "))
+	}
+
+	return qtx.TransferFeeds(ctx, database.TransferFeedsParams{
+		ToUserID:   heir.ID,
+		UpdatedAt:  time.Now(),
+		FromUserID: from.ID,
+	})
+}
+
+// Moves all feeds created by one user to another, so they outlive deleted user
+func transferFeeds(ctx context.Context, s *state, user database.User, fromName, toName string) error {
+	from, fromErr := managedUser(ctx, s, user, fromName, "transferring feeds")
+	if fromErr != nil {
+		return fromErr
+	}
+	heir, heirErr := feedsHeir(ctx, s, from, toName)
+	if heirErr != nil {
+		return heirErr
+	}
+
+	var transferred int64
+	err := s.db.InTx(ctx, func(qtx database.Querier) error {
+		var moveErr error
+		transferred, moveErr = moveFeeds(ctx, qtx, from, heir)
+		return moveErr
+	})
+	if err != nil {
+		return fmt.Errorf("error transferring feeds of %s to %s: %v", from.Name, heir.Name, err)
+	}
+
+	fmt.Printf("Transferred %d feeds from %s to %s \n", transferred, from.Name, heir.Name)
+	return nil
+}
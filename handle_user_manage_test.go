@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestDeleteUserKeepsFeedsOthersFollow(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	createTestUser(t, s, "alice")
+	admin, err := s.db.SetUserRole(ctx, database.SetUserRoleParams{Name: "alice", Role: roleAdmin, UpdatedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("error promoting alice: %v", err)
+	}
+	owner := createTestUser(t, s, "bob")
+	feed := createTestFeed(t, s, owner, "blog", "https://example.com/feed.xml")
+	if _, err := s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    admin.ID,
+		FeedID:    feed.ID,
+	}); err != nil {
+		t.Fatalf("error following feed: %v", err)
+	}
+
+	captureOutput(t, func() {
+		if err := handleUser(ctx, s, command{name: "user", args: []string{"delete", "bob", "--yes"}}, admin); err == nil {
+			t.Error("deleting user whose feed is followed by others succeeded")
+		}
+	})
+	if _, err := s.db.GetUser(ctx, "bob"); err != nil {
+		t.Fatalf("refused delete removed user: %v", err)
+	}
+
+	captureOutput(t, func() {
+		if err := handleUser(ctx, s, command{name: "user", args: []string{"delete", "bob", "--transfer-to", "alice", "--yes"}}, admin); err != nil {
+			t.Fatalf("delete with transfer failed: %v", err)
+		}
+	})
+	if _, err := s.db.GetUser(ctx, "bob"); err == nil {
+		t.Error("user still exists after delete with transfer")
+	}
+	kept, err := s.db.GetFeedById(ctx, feed.ID)
+	if err != nil {
+		t.Fatalf("followed feed was deleted with its creator: %v", err)
+	}
+	if kept.UserID != admin.ID {
+		t.Errorf("feed is created by %s, want it transferred to alice", kept.UserID)
+	}
+}
+
+func TestDeleteUserTransferClash(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t)
+	owner := createTestUser(t, s, "bob")
+	heir := createTestUser(t, s, "carol")
+	feed := createTestFeed(t, s, owner, "blog", "https://example.com/feed.xml")
+	createTestFeed(t, s, heir, "blog", "https://example.com/other.xml")
+
+	captureOutput(t, func() {
+		if err := handleUser(ctx, s, command{name: "user", args: []string{"delete", "bob", "--transfer-to", "carol", "--yes"}}, owner); err == nil {
+			t.Error("delete with clashing feed names succeeded")
+		}
+	})
+	// Transfer and delete run in one transaction, so nothing changed
+	if _, err := s.db.GetUser(ctx, "bob"); err != nil {
+		t.Errorf("user was deleted although transfer failed: %v", err)
+	}
+	if kept, err := s.db.GetFeedById(ctx, feed.ID); err != nil || kept.UserID != owner.ID {
+		t.Errorf("feed %+v (%v) changed although transfer failed", kept, err)
+	}
+}
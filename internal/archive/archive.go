@@ -0,0 +1,539 @@
+// Package archive moves the whole database between gator installs. Rows are
+// written as JSON keyed by natural keys (user name, feed url, post url) instead
+// of ids, so archives do not depend on the backend they were exported from.
+package archive
+
+import (
+	"bufio"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Version of archive format written by Export, bumped on incompatible changes
+const Version = 1
+
+type Archive struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Users      []User    `json:"users"`
+	Feeds      []Feed    `json:"feeds"`
+}
+
+type User struct {
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Follows   []Follow  `json:"follows,omitempty"`
+	Starred   []Star    `json:"starred,omitempty"`
+	Tokens    []Token   `json:"tokens,omitempty"`
+}
+
+type Follow struct {
+	FeedUrl   string    `json:"feed_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Star struct {
+	FeedUrl   string    `json:"feed_url"`
+	PostUrl   string    `json:"post_url"`
+	Note      *string   `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// API token of user, only its SHA-256 hash is known so the token keeps working after import
+type Token struct {
+	Name      string     `json:"name"`
+	Hash      []byte     `json:"hash"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Feed with its settings and fetch state, claims of running agg instances are left out.
+// Credentials stay sealed and can only be opened with the same secret key
+type Feed struct {
+	Name                    string     `json:"name"`
+	Url                     string     `json:"url"`
+	Owner                   string     `json:"owner"`
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+	FetchIntervalSeconds    int32      `json:"fetch_interval_seconds"`
+	MinFetchIntervalSeconds int32      `json:"min_fetch_interval_seconds"`
+	MaxFetchIntervalSeconds int32      `json:"max_fetch_interval_seconds"`
+	CronSchedule            *string    `json:"cron_schedule,omitempty"`
+	RetentionMaxPosts       *int32     `json:"retention_max_posts,omitempty"`
+	RetentionMaxAgeSeconds  *int32     `json:"retention_max_age_seconds,omitempty"`
+	LastFetchedAt           *time.Time `json:"last_fetched_at,omitempty"`
+	NextFetchAt             *time.Time `json:"next_fetch_at,omitempty"`
+	LastSucceededAt         *time.Time `json:"last_succeeded_at,omitempty"`
+	DisabledAt              *time.Time `json:"disabled_at,omitempty"`
+	ConsecutiveFailures     int32      `json:"consecutive_failures,omitempty"`
+	LastError               *string    `json:"last_error,omitempty"`
+	Credentials             []byte     `json:"credentials,omitempty"`
+	Posts                   []Post     `json:"posts,omitempty"`
+}
+
+type Post struct {
+	Title       string     `json:"title"`
+	Url         string     `json:"url"`
+	OriginalUrl string     `json:"original_url"`
+	Description *string    `json:"description,omitempty"`
+	Content     *string    `json:"content,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// How Import treats rows already in the database
+type Mode string
+
+const (
+	// Merge keeps existing rows and adds the ones missing, matched by natural keys
+	Merge Mode = "merge"
+	// Replace deletes all users and feeds before importing
+	Replace Mode = "replace"
+)
+
+// Number of rows of each kind, for Import the rows actually added
+type Stats struct {
+	Users       int64
+	Feeds       int64
+	Credentials int64
+	Posts       int64
+	Follows     int64
+	Starred     int64
+	Tokens      int64
+}
+
+// Count returns number of rows of each kind in the archive
+func (a Archive) Count() Stats {
+	stats := Stats{Users: int64(len(a.Users)), Feeds: int64(len(a.Feeds))}
+	for _, user := range a.Users {
+		stats.Follows += int64(len(user.Follows))
+		stats.Starred += int64(len(user.Starred))
+		stats.Tokens += int64(len(user.Tokens))
+	}
+	for _, feed := range a.Feeds {
+		stats.Posts += int64(len(feed.Posts))
+		if len(feed.Credentials) > 0 {
+			stats.Credentials++
+		}
+	}
+	return stats
+}
+
+// Export reads every user, feed, follow, post, starred post and api token
+func Export(ctx context.Context, q database.Querier) (Archive, error) {
+	archive := Archive{Version: Version, ExportedAt: time.Now().UTC()}
+
+	users, err := q.GetUsers(ctx)
+	if err != nil {
+		return Archive{}, fmt.Errorf("error reading users: %v", err)
+	}
+	slices.SortFunc(users, func(a, b database.User) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.Name, b.Name))
+	})
+	userIndex := map[uuid.UUID]int{}
+	for _, user := range users {
+		userIndex[user.ID] = len(archive.Users)
+		archive.Users = append(archive.Users, User{Name: user.Name, Role: user.Role, CreatedAt: user.CreatedAt, UpdatedAt: user.UpdatedAt})
+	}
+
+	feeds, err := q.GetAllFeeds(ctx)
+	if err != nil {
+		return Archive{}, fmt.Errorf("error reading feeds: %v", err)
+	}
+	credentials, err := q.GetAllFeedCredentials(ctx)
+	if err != nil {
+		return Archive{}, fmt.Errorf("error reading feed credentials: %v", err)
+	}
+	sealed := map[uuid.UUID][]byte{}
+	for _, credential := range credentials {
+		sealed[credential.FeedID] = credential.Sealed
+	}
+	feedIndex := map[uuid.UUID]int{}
+	for _, feed := range feeds {
+		feedIndex[feed.ID] = len(archive.Feeds)
+		archive.Feeds = append(archive.Feeds, Feed{
+			Name:                    feed.Name,
+			Url:                     feed.Url,
+			Owner:                   archive.Users[userIndex[feed.UserID]].Name,
+			CreatedAt:               feed.CreatedAt,
+			UpdatedAt:               feed.UpdatedAt,
+			FetchIntervalSeconds:    feed.FetchIntervalSeconds,
+			MinFetchIntervalSeconds: feed.MinFetchIntervalSeconds,
+			MaxFetchIntervalSeconds: feed.MaxFetchIntervalSeconds,
+			CronSchedule:            fromNullString(feed.CronSchedule),
+			RetentionMaxPosts:       fromNullInt32(feed.RetentionMaxPosts),
+			RetentionMaxAgeSeconds:  fromNullInt32(feed.RetentionMaxAgeSeconds),
+			LastFetchedAt:           fromNullTime(feed.LastFetchedAt),
+			NextFetchAt:             fromNullTime(feed.NextFetchAt),
+			LastSucceededAt:         fromNullTime(feed.LastSucceededAt),
+			DisabledAt:              fromNullTime(feed.DisabledAt),
+			ConsecutiveFailures:     feed.ConsecutiveFailures,
+			LastError:               fromNullString(feed.LastError),
+			Credentials:             sealed[feed.ID],
+		})
+	}
+
+	posts, err := q.GetAllPosts(ctx)
+	if err != nil {
+		return Archive{}, fmt.Errorf("error reading posts: %v", err)
+	}
+	postKeys := map[uuid.UUID][2]string{}
+	for _, post := range posts {
+		feed := &archive.Feeds[feedIndex[post.FeedID]]
+		postKeys[post.ID] = [2]string{feed.Url, post.Url}
+		feed.Posts = append(feed.Posts, Post{
+			Title:       post.Title,
+			Url:         post.Url,
+			OriginalUrl: post.OriginalUrl,
+			Description: fromNullString(post.Description),
+			Content:     fromNullString(post.Content),
+			PublishedAt: fromNullTime(post.PublishedAt),
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+		})
+	}
+
+	follows, err := q.GetAllFeedFollows(ctx)
+	if err != nil {
+		return Archive{}, fmt.Errorf("error reading feed follows: %v", err)
+	}
+	for _, follow := range follows {
+		user := &archive.Users[userIndex[follow.UserID]]
+		user.Follows = append(user.Follows, Follow{FeedUrl: archive.Feeds[feedIndex[follow.FeedID]].Url, CreatedAt: follow.CreatedAt})
+	}
+
+	starred, err := q.GetAllStarredPosts(ctx)
+	if err != nil {
+		return Archive{}, fmt.Errorf("error reading starred posts: %v", err)
+	}
+	for _, star := range starred {
+		user := &archive.Users[userIndex[star.UserID]]
+		key := postKeys[star.PostID]
+		user.Starred = append(user.Starred, Star{FeedUrl: key[0], PostUrl: key[1], Note: fromNullString(star.Note), CreatedAt: star.CreatedAt})
+	}
+
+	tokens, err := q.GetAllApiTokens(ctx)
+	if err != nil {
+		return Archive{}, fmt.Errorf("error reading api tokens: %v", err)
+	}
+	for _, token := range tokens {
+		user := &archive.Users[userIndex[token.UserID]]
+		user.Tokens = append(user.Tokens, Token{Name: token.Name, Hash: token.TokenHash, CreatedAt: token.CreatedAt, ExpiresAt: fromNullTime(token.ExpiresAt)})
+	}
+
+	return archive, nil
+}
+
+// Import writes archive rows into the database and returns how many were added.
+// It should run in a transaction so a failure leaves the database untouched
+func Import(ctx context.Context, q database.Querier, archive Archive, mode Mode) (Stats, error) {
+	if archive.Version != Version {
+		return Stats{}, fmt.Errorf("unsupported archive version %d, expected %d", archive.Version, Version)
+	}
+
+	switch mode {
+	case Merge:
+	case Replace:
+		// Users own feeds so deleting them cascades to every other table
+		if err := q.DeleteUsers(ctx); err != nil {
+			return Stats{}, fmt.Errorf("error deleting users: %v", err)
+		}
+	default:
+		return Stats{}, fmt.Errorf("unknown import mode %q", mode)
+	}
+
+	var stats Stats
+	userIDs := map[string]uuid.UUID{}
+	for _, user := range archive.Users {
+		existing, err := q.GetUser(ctx, user.Name)
+		if err == nil {
+			userIDs[user.Name] = existing.ID
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return stats, fmt.Errorf("error getting user %s: %v", user.Name, err)
+		}
+
+		created, err := q.CreateUser(ctx, database.CreateUserParams{
+			ID:        uuid.New(),
+			CreatedAt: user.CreatedAt,
+			UpdatedAt: user.UpdatedAt,
+			Name:      user.Name,
+		})
+		if err != nil {
+			return stats, fmt.Errorf("error creating user %s: %v", user.Name, err)
+		}
+		// Users of archives written before roles are members
+		if user.Role != "" && user.Role != created.Role {
+			_, err := q.SetUserRole(ctx, database.SetUserRoleParams{Name: user.Name, Role: user.Role, UpdatedAt: user.UpdatedAt})
+			if err != nil {
+				return stats, fmt.Errorf("error setting role of user %s: %v", user.Name, err)
+			}
+		}
+		userIDs[user.Name] = created.ID
+		stats.Users++
+	}
+
+	feedIDs := map[string]uuid.UUID{}
+	for _, feed := range archive.Feeds {
+		ownerID, ok := userIDs[feed.Owner]
+		if !ok {
+			return stats, fmt.Errorf("feed %s is owned by user %s missing from archive", feed.Url, feed.Owner)
+		}
+
+		added, err := q.ImportFeed(ctx, database.ImportFeedParams{
+			ID:                      uuid.New(),
+			CreatedAt:               feed.CreatedAt,
+			UpdatedAt:               feed.UpdatedAt,
+			Name:                    feed.Name,
+			Url:                     feed.Url,
+			UserID:                  ownerID,
+			LastFetchedAt:           toNullTime(feed.LastFetchedAt),
+			NextFetchAt:             toNullTime(feed.NextFetchAt),
+			FetchIntervalSeconds:    feed.FetchIntervalSeconds,
+			MinFetchIntervalSeconds: feed.MinFetchIntervalSeconds,
+			MaxFetchIntervalSeconds: feed.MaxFetchIntervalSeconds,
+			ConsecutiveFailures:     feed.ConsecutiveFailures,
+			LastError:               toNullString(feed.LastError),
+			LastSucceededAt:         toNullTime(feed.LastSucceededAt),
+			DisabledAt:              toNullTime(feed.DisabledAt),
+			CronSchedule:            toNullString(feed.CronSchedule),
+			RetentionMaxPosts:       toNullInt32(feed.RetentionMaxPosts),
+			RetentionMaxAgeSeconds:  toNullInt32(feed.RetentionMaxAgeSeconds),
+		})
+		if err != nil {
+			return stats, fmt.Errorf("error importing feed %s: %v", feed.Url, err)
+		}
+		stats.Feeds += added
+
+		// Feed already in the database keeps its own settings and credentials
+		stored, err := q.GetFeedByUrl(ctx, feed.Url)
+		if err != nil {
+			return stats, fmt.Errorf("error getting feed %s: %v", feed.Url, err)
+		}
+		feedIDs[feed.Url] = stored.ID
+
+		if added > 0 && len(feed.Credentials) > 0 {
+			err := q.SetFeedCredentials(ctx, database.SetFeedCredentialsParams{
+				FeedID:    stored.ID,
+				CreatedAt: feed.UpdatedAt,
+				UpdatedAt: feed.UpdatedAt,
+				Sealed:    feed.Credentials,
+			})
+			if err != nil {
+				return stats, fmt.Errorf("error importing credentials of feed %s: %v", feed.Url, err)
+			}
+			stats.Credentials++
+		}
+
+		for _, post := range feed.Posts {
+			added, err := q.ImportPost(ctx, database.ImportPostParams{
+				ID:          uuid.New(),
+				CreatedAt:   post.CreatedAt,
+				UpdatedAt:   post.UpdatedAt,
+				Title:       post.Title,
+				Url:         post.Url,
+				Description: toNullString(post.Description),
+				PublishedAt: toNullTime(post.PublishedAt),
+				FeedID:      stored.ID,
+				Content:     toNullString(post.Content),
+				OriginalUrl: post.OriginalUrl,
+			})
+			if err != nil {
+				return stats, fmt.Errorf("error importing post %s of feed %s: %v", post.Url, feed.Url, err)
+			}
+			stats.Posts += added
+		}
+	}
+
+	feedID := func(url string) (uuid.UUID, error) {
+		if id, ok := feedIDs[url]; ok {
+			return id, nil
+		}
+		feed, err := q.GetFeedByUrl(ctx, url)
+		if err != nil {
+			return uuid.UUID{}, fmt.Errorf("error getting feed %s: %v", url, err)
+		}
+		feedIDs[url] = feed.ID
+		return feed.ID, nil
+	}
+
+	for _, user := range archive.Users {
+		for _, follow := range user.Follows {
+			id, err := feedID(follow.FeedUrl)
+			if err != nil {
+				return stats, err
+			}
+			added, err := q.ImportFeedFollow(ctx, database.ImportFeedFollowParams{
+				ID:        uuid.New(),
+				CreatedAt: follow.CreatedAt,
+				UpdatedAt: follow.CreatedAt,
+				UserID:    userIDs[user.Name],
+				FeedID:    id,
+			})
+			if err != nil {
+				return stats, fmt.Errorf("error importing follow of feed %s by user %s: %v", follow.FeedUrl, user.Name, err)
+			}
+			stats.Follows += added
+		}
+
+		for _, star := range user.Starred {
+			id, err := feedID(star.FeedUrl)
+			if err != nil {
+				return stats, err
+			}
+			postID, err := q.GetFeedPostId(ctx, database.GetFeedPostIdParams{FeedID: id, Url: star.PostUrl})
+			if err != nil {
+				return stats, fmt.Errorf("error getting starred post %s of feed %s: %v", star.PostUrl, star.FeedUrl, err)
+			}
+			added, err := q.ImportStarredPost(ctx, database.ImportStarredPostParams{
+				UserID:    userIDs[user.Name],
+				PostID:    postID,
+				CreatedAt: star.CreatedAt,
+				Note:      toNullString(star.Note),
+			})
+			if err != nil {
+				return stats, fmt.Errorf("error importing star of post %s by user %s: %v", star.PostUrl, user.Name, err)
+			}
+			stats.Starred += added
+		}
+
+		for _, token := range user.Tokens {
+			added, err := q.ImportApiToken(ctx, database.ImportApiTokenParams{
+				ID:        uuid.New(),
+				CreatedAt: token.CreatedAt,
+				UserID:    userIDs[user.Name],
+				Name:      token.Name,
+				TokenHash: token.Hash,
+				ExpiresAt: toNullTime(token.ExpiresAt),
+			})
+			if err != nil {
+				return stats, fmt.Errorf("error importing token %s of user %s: %v", token.Name, user.Name, err)
+			}
+			stats.Tokens += added
+		}
+	}
+
+	return stats, nil
+}
+
+// WriteFile stores archive as indented JSON, gzip compressed when path ends with .gz.
+// Archive is written to a temporary file first so an existing one is never left half written
+func WriteFile(path string, archive Archive) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var w io.Writer = tmp
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(tmp)
+		w = gz
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(archive); err != nil {
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReadFile loads archive written by WriteFile, gzip is detected from file contents
+func ReadFile(path string) (Archive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Archive{}, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var r io.Reader = reader
+	if magic, _ := reader.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return Archive{}, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	var archive Archive
+	if err := json.NewDecoder(r).Decode(&archive); err != nil {
+		return Archive{}, fmt.Errorf("error decoding archive: %v", err)
+	}
+	if archive.Version == 0 {
+		return Archive{}, errors.New("file is not a gator archive, version is missing")
+	}
+	return archive, nil
+}
+
+func fromNullString(value sql.NullString) *string {
+	if !value.Valid {
+		return nil
+	}
+	return &value.String
+}
+
+func fromNullInt32(value sql.NullInt32) *int32 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Int32
+}
+
+func fromNullTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+func toNullString(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
+
+func toNullInt32(value *int32) sql.NullInt32 {
+	if value == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *value, Valid: true}
+}
+
+func toNullTime(value *time.Time) sql.NullTime {
+	if value == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *value, Valid: true}
+}
@@ -0,0 +1,168 @@
+// Package canonical turns the many URLs an article is linked under into one,
+// so the same post is not stored again for each tracking variant of its link.
+package canonical
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Query parameters added by newsletters, ads and analytics. Entry ending
+// with * matches every parameter with that prefix
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"fbclid",
+	"gclid",
+	"dclid",
+	"msclkid",
+	"mc_cid",
+	"mc_eid",
+	"_hsenc",
+	"_hsmi",
+	"mkt_tok",
+	"igshid",
+	"yclid",
+	"ref_src",
+}
+
+// Normalize lowercases scheme and host, drops default port, fragment and
+// tracking parameters. Path and remaining query are kept as they are, servers
+// may tell apart paths with trailing slash or parameters in different order
+func Normalize(rawURL string, trackingParams []string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+	if !parsed.IsAbs() || parsed.Host == "" {
+		return "", fmt.Errorf("url %q is not absolute", rawURL)
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	host := strings.ToLower(parsed.Hostname())
+	port := parsed.Port()
+	if (parsed.Scheme == "http" && port == "80") || (parsed.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		parsed.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		parsed.Host = "[" + host + "]"
+	default:
+		parsed.Host = host
+	}
+	parsed.User = nil
+	parsed.Fragment = ""
+	parsed.RawFragment = ""
+
+	if parsed.Path == "" {
+		parsed.Path = "/"
+		parsed.RawPath = ""
+	}
+
+	// Parameters are removed from raw query, so the rest keeps its order and encoding
+	var kept []string
+	for _, param := range strings.Split(parsed.RawQuery, "&") {
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, unescapeErr := url.QueryUnescape(name); unescapeErr == nil {
+			name = unescaped
+		}
+		if !isTracking(name, trackingParams) {
+			kept = append(kept, param)
+		}
+	}
+	parsed.RawQuery = strings.Join(kept, "&")
+	parsed.ForceQuery = false
+
+	return parsed.String(), nil
+}
+
+func isTracking(name string, trackingParams []string) bool {
+	name = strings.ToLower(name)
+	for _, param := range trackingParams {
+		param = strings.ToLower(param)
+		if prefix, found := strings.CutSuffix(param, "*"); found {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == param {
+			return true
+		}
+	}
+	return false
+}
+
+// Only the head of page is needed to find canonical link
+const resolveReadLimit = 256 << 10
+
+var (
+	linkTagRegexp   = regexp.MustCompile(`(?is)<link\s[^>]*>`)
+	relRegexp       = regexp.MustCompile(`(?is)\srel\s*=\s*["']?\s*canonical\s*["'\s>/]`)
+	hrefRegexp      = regexp.MustCompile(`(?is)\shref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	headEndRegexp   = regexp.MustCompile(`(?i)</head>`)
+	supportedScheme = map[string]bool{"http": true, "https": true}
+)
+
+// Resolve follows redirects of rawURL, eg. feedproxy links, and returns
+// <link rel="canonical"> of the page it ends on or the final URL when page has none
+func Resolve(ctx context.Context, client *http.Client, rawURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "gator")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("unexpected response status %s", resp.Status)
+	}
+
+	finalURL := resp.Request.URL
+	if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return finalURL.String(), nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, resolveReadLimit))
+	if err != nil {
+		return "", err
+	}
+	if canonical := findCanonical(body, finalURL); canonical != "" {
+		return canonical, nil
+	}
+	return finalURL.String(), nil
+}
+
+// Href of canonical link in head of HTML page resolved against page URL
+func findCanonical(page []byte, pageURL *url.URL) string {
+	if end := headEndRegexp.FindIndex(page); end != nil {
+		page = page[:end[0]]
+	}
+
+	for _, tag := range linkTagRegexp.FindAll(page, -1) {
+		if !relRegexp.Match(tag) {
+			continue
+		}
+		match := hrefRegexp.FindSubmatch(tag)
+		if match == nil {
+			continue
+		}
+		href := string(match[1]) + string(match[2]) + string(match[3])
+
+		canonical, err := pageURL.Parse(strings.TrimSpace(href))
+		if err != nil || !supportedScheme[canonical.Scheme] {
+			continue
+		}
+		return canonical.String()
+	}
+	return ""
+}
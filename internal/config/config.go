@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 )
@@ -15,7 +16,17 @@ const intermediatePath = "/home/michal/workspace/blog_aggregator_project/blog_ag
 
 type Config struct {
 	DB_URL            string `json:"db_url"`
-	CURRENT_USER_NAME string `json:"current_user_name"`
+	// Session of user logged in with API token
+	SESSION_TOKEN string `json:"session_token,omitempty"`
+	// Left by versions before API tokens, no longer grants a session and is cleared on login
+	CURRENT_USER_NAME string `json:"current_user_name,omitempty"`
+	SECRET_KEY        string `json:"secret_key,omitempty"`
+	// Posts older than this are pruned unless feed has own max age, eg. 90d or 2160h
+	POST_MAX_AGE string `json:"post_max_age,omitempty"`
+	// Query parameters removed from post links, replaces the default list
+	TRACKING_PARAMS []string `json:"tracking_params,omitempty"`
+	// Follow post links to <link rel="canonical"> of the page when storing posts
+	RESOLVE_CANONICAL_URLS bool `json:"resolve_canonical_urls,omitempty"`
 }
 
 func Read() (Config, error) {
@@ -26,7 +37,6 @@ func Read() (Config, error) {
 
 	jsonFile, err := os.Open(confiFilePath)
 	if err != nil {
-		fmt.Println(err)
 		return Config{}, err
 	}
 	defer jsonFile.Close()
@@ -48,25 +58,25 @@ func Read() (Config, error) {
 
 }
 
-func (c *Config) SetUser(username string) error {
-	c.CURRENT_USER_NAME = username
+// Stores session token replacing the session before it
+func (c *Config) SetSession(token string) error {
+	c.SESSION_TOKEN = token
+	c.CURRENT_USER_NAME = ""
 	return write(*c)
-
 }
 
 func getConfigFilePath() (string, error) {
 	// uncomment this on final
 	basePath, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Println("Error reading config file:", err)
-		return "", err
+		return "", fmt.Errorf("error finding home directory: %w", err)
 	}
 	fullPath := filepath.Join(basePath, configFileName)
 
 
 	// fullPath := filepath.Join(intermediatePath, configFileName)
-	fmt.Println(fullPath)
-	
+	slog.Debug("using config file", "path", fullPath)
+
 	return fullPath, nil
 }
 
@@ -77,12 +87,15 @@ func write(cfg Config) error {
 		return fmt.Errorf("error getting config path: %w", pathError)
 	}
 
-	// Open a file for writing
-	file, err := os.Create(confiFilePath)
+	// Open a file for writing, readable only by owner as it holds session token
+	file, err := os.OpenFile(confiFilePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
-		return fmt.Errorf("Error creating file:", err)
+		return fmt.Errorf("error creating file: %w", err)
 	}
 	defer file.Close()
+	if chmodErr := file.Chmod(0o600); chmodErr != nil {
+		return fmt.Errorf("error restricting file permissions: %w", chmodErr)
+	}
 
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", " ")
@@ -0,0 +1,162 @@
+// Package credentials holds authentication settings of private feeds and
+// seals them with AES-GCM so they are stored encrypted at rest.
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Name of environment variable with key used to encrypt feed credentials
+const KeyEnv = "GATOR_SECRET_KEY"
+
+// Same limit as default policy of http.Client
+const maxRedirects = 10
+
+var ErrNoKey = errors.New("no secret key configured, set " + KeyEnv + " or secret_key in config")
+
+// Authentication sent along with feed requests
+type Credentials struct {
+	Headers       map[string]string `json:"headers,omitempty"`
+	BasicUser     string            `json:"basic_user,omitempty"`
+	BasicPassword string            `json:"basic_password,omitempty"`
+	BearerToken   string            `json:"bearer_token,omitempty"`
+}
+
+func (c Credentials) IsEmpty() bool {
+	return len(c.Headers) == 0 && c.BasicUser == "" && c.BearerToken == ""
+}
+
+// Sets authentication of request, custom headers are applied last so they can override anything
+func (c Credentials) Apply(req *http.Request) {
+	if c.BasicUser != "" {
+		req.SetBasicAuth(c.BasicUser, c.BasicPassword)
+	}
+	if c.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	}
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+}
+
+// Removes headers set by Apply
+func (c Credentials) Strip(header http.Header) {
+	if c.BasicUser != "" || c.BearerToken != "" {
+		header.Del("Authorization")
+	}
+	for name := range c.Headers {
+		header.Del(name)
+	}
+}
+
+// Redirect policy of client fetching feed. Credentials belong to the host of the feed,
+// redirects to other hosts are followed without them
+func (c Credentials) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if !strings.EqualFold(req.URL.Host, via[0].URL.Host) {
+		c.Strip(req.Header)
+	}
+	return nil
+}
+
+// Description of configured authentication without any secret values
+func (c Credentials) Summary() string {
+	parts := []string{}
+	if c.BasicUser != "" {
+		parts = append(parts, "basic auth")
+	}
+	if c.BearerToken != "" {
+		parts = append(parts, "bearer token")
+	}
+	if len(c.Headers) > 0 {
+		names := make([]string, 0, len(c.Headers))
+		for name := range c.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		parts = append(parts, "headers "+strings.Join(names, ", "))
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Parses header given as "Name: value"
+func ParseHeader(header string) (string, string, error) {
+	name, value, found := strings.Cut(header, ":")
+	name = strings.TrimSpace(name)
+	if !found || name == "" {
+		return "", "", fmt.Errorf("header %q must have form \"Name: value\"", header)
+	}
+	return http.CanonicalHeaderKey(name), strings.TrimSpace(value), nil
+}
+
+// Encrypts credentials with key, nonce is prepended to the ciphertext
+func Seal(key string, c Credentials) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding credentials: %v", err)
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error generating nonce: %v", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypts credentials sealed by Seal
+func Open(key string, sealed []byte) (Credentials, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return Credentials{}, errors.New("sealed credentials are too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return Credentials{}, errors.New("error decrypting credentials, secret key does not match")
+	}
+
+	c := Credentials{}
+	if err := json.Unmarshal(plaintext, &c); err != nil {
+		return Credentials{}, fmt.Errorf("error decoding credentials: %v", err)
+	}
+	return c, nil
+}
+
+// Any passphrase is accepted, it is stretched to AES-256 key with SHA-256
+func newGCM(key string) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, ErrNoKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,141 @@
+package credentials
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	creds := Credentials{
+		Headers:       map[string]string{"X-Api-Key": "secret"},
+		BasicUser:     "alice",
+		BasicPassword: "password",
+	}
+
+	sealed, err := Seal("key", creds)
+	if err != nil {
+		t.Fatalf("error sealing credentials: %v", err)
+	}
+	opened, err := Open("key", sealed)
+	if err != nil {
+		t.Fatalf("error opening credentials: %v", err)
+	}
+	if !reflect.DeepEqual(opened, creds) {
+		t.Errorf("opened %+v, want %+v", opened, creds)
+	}
+
+	// nonce is random, so sealing twice gives different ciphertext
+	again, err := Seal("key", creds)
+	if err != nil {
+		t.Fatalf("error sealing credentials: %v", err)
+	}
+	if reflect.DeepEqual(again, sealed) {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+}
+
+func TestOpenFailures(t *testing.T) {
+	sealed, err := Seal("key", Credentials{BearerToken: "token"})
+	if err != nil {
+		t.Fatalf("error sealing credentials: %v", err)
+	}
+	tampered := append([]byte{}, sealed...)
+	tampered[len(tampered)-1] ^= 1
+
+	cases := []struct {
+		name   string
+		key    string
+		sealed []byte
+	}{
+		{"wrong key", "other key", sealed},
+		{"tampered", "key", tampered},
+		{"too short", "key", sealed[:4]},
+	}
+	for _, tc := range cases {
+		if opened, err := Open(tc.key, tc.sealed); err == nil {
+			t.Errorf("%s: opened %+v, want error", tc.name, opened)
+		}
+	}
+
+	if _, err := Seal("", Credentials{}); !errors.Is(err, ErrNoKey) {
+		t.Errorf("sealing without key returned %v, want ErrNoKey", err)
+	}
+	if _, err := Open("", sealed); !errors.Is(err, ErrNoKey) {
+		t.Errorf("opening without key returned %v, want ErrNoKey", err)
+	}
+}
+
+func TestParseHeader(t *testing.T) {
+	cases := []struct {
+		header      string
+		name, value string
+		valid       bool
+	}{
+		{"X-Api-Key: secret", "X-Api-Key", "secret", true},
+		{"x-api-key:secret:with:colons", "X-Api-Key", "secret:with:colons", true},
+		{"Cookie:  session=1 ", "Cookie", "session=1", true},
+		{"X-Api-Key", "", "", false},
+		{": value", "", "", false},
+	}
+
+	for _, tc := range cases {
+		name, value, err := ParseHeader(tc.header)
+		if tc.valid && (err != nil || name != tc.name || value != tc.value) {
+			t.Errorf("%q parsed to %q, %q (%v), want %q, %q", tc.header, name, value, err, tc.name, tc.value)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("%q parsed to %q, %q, want error", tc.header, name, value)
+		}
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	received := map[string]http.Header{}
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received["other"] = r.Header.Clone()
+	}))
+	defer other.Close()
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/feed.xml", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, other.URL+"/feed.xml", http.StatusFound)
+		default:
+			received["feed"] = r.Header.Clone()
+		}
+	}))
+	defer feed.Close()
+
+	creds := Credentials{BearerToken: "token", Headers: map[string]string{"X-Api-Key": "secret"}}
+	client := &http.Client{CheckRedirect: creds.CheckRedirect}
+	get := func(url string) {
+		t.Helper()
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("error creating request: %v", err)
+		}
+		creds.Apply(req)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("error getting %s: %v", url, err)
+		}
+		resp.Body.Close()
+	}
+
+	get(feed.URL + "/moved")
+	if received["feed"].Get("X-Api-Key") != "secret" || received["feed"].Get("Authorization") != "Bearer token" {
+		t.Errorf("redirect within host sent headers %v, want credentials kept", received["feed"])
+	}
+
+	get(feed.URL + "/away")
+	if received["other"] == nil {
+		t.Fatal("redirect to other host was not followed")
+	}
+	if received["other"].Get("X-Api-Key") != "" || received["other"].Get("Authorization") != "" {
+		t.Errorf("redirect to other host sent headers %v, want credentials stripped", received["other"])
+	}
+}
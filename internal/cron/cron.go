@@ -0,0 +1,259 @@
+// Package cron parses standard five field cron expressions
+// (minute hour day-of-month month day-of-week) and computes their next activation.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is parsed cron expression. Each field is bitset of allowed values
+type Schedule struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// Day matches when either day of month or day of week matches, unless one of them is *
+	domAny bool
+	dowAny bool
+	// Minute or hour is wildcard, such schedules keep their rhythm when clocks change.
+	// Others fire once in repeated hour and right after skipped one
+	wildcard bool
+}
+
+type field struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is accepted as Sunday and folded to 0
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Next activation is searched at most this far ahead, e.g. for "0 0 30 2 *" which never fires
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// Parse parses cron expression like "*/5 9-18 * * 1-5" or macro like "@daily"
+func Parse(expr string) (Schedule, error) {
+	normalized := strings.TrimSpace(expr)
+	if macro, ok := macros[strings.ToLower(normalized)]; ok {
+		normalized = macro
+	}
+
+	fields := strings.Fields(normalized)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("cron expression %q must have 5 fields, got %d", expr, len(fields))
+	}
+
+	schedule := Schedule{expr: strings.TrimSpace(expr)}
+	var err error
+	if schedule.minute, err = parseField(fields[0], minuteField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.hour, err = parseField(fields[1], hourField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.dom, err = parseField(fields[2], domField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.month, err = parseField(fields[3], monthField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.dow, err = parseField(fields[4], dowField); err != nil {
+		return Schedule{}, err
+	}
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+	schedule.domAny = fields[2] == "*"
+	schedule.dowAny = fields[4] == "*"
+	schedule.wildcard = strings.HasPrefix(fields[0], "*") || strings.HasPrefix(fields[1], "*")
+
+	return schedule, nil
+}
+
+// Parses comma separated list of values, ranges and steps into bitset
+func parseField(value string, f field) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			parsedStep, err := strconv.Atoi(stepPart)
+			if err != nil || parsedStep < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, f.name)
+			}
+			step = parsedStep
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = f.min, f.max
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseValue(lowPart, f); err != nil {
+				return 0, err
+			}
+			if high, err = parseValue(highPart, f); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		default:
+			single, err := parseValue(rangePart, f)
+			if err != nil {
+				return 0, err
+			}
+			low, high = single, single
+			// "5/10" means every 10th value starting at 5
+			if hasStep {
+				high = f.max
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	if number, ok := f.names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", value, f.name)
+	}
+	if number < f.min || number > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d in %s field", number, f.min, f.max, f.name)
+	}
+
+	return number, nil
+}
+
+// String returns expression the schedule was parsed from
+func (s Schedule) String() string {
+	return s.expr
+}
+
+// Next returns first activation strictly after given time, in its location.
+// Zero time is returned when expression never fires.
+func (s Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(searchLimit)
+
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case !has(s.month, int(t.Month())):
+			next = firstOccurrence(time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()))
+		case !s.dayMatches(t):
+			next = firstOccurrence(time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()))
+		case !has(s.hour, t.Hour()):
+			next = firstOccurrence(time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location()))
+		case !has(s.minute, t.Minute()), !s.wildcard && !firstOccurrence(t).Equal(t):
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+
+		if !s.wildcard && s.firesInGap(t, next) {
+			return next
+		}
+		t = next
+	}
+
+	return time.Time{}
+}
+
+func (s Schedule) matches(t time.Time) bool {
+	return has(s.month, int(t.Month())) && s.dayMatches(t) && has(s.hour, t.Hour()) && has(s.minute, t.Minute())
+}
+
+// Reports whether schedule fires at wall clock time skipped when clocks went forward
+// between from and to
+func (s Schedule) firesInGap(from, to time.Time) bool {
+	for w := wallClock(from).Add(to.Sub(from)); w.Before(wallClock(to)); w = w.Add(time.Minute) {
+		if s.matches(w) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wall clock time of t in UTC, where every day has 24 hours
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+// Earliest instant showing the same wall clock time as t. It is earlier than t in the
+// hour repeated when clocks go back, time.Date resolves such times to the later one
+func firstOccurrence(t time.Time) time.Time {
+	_, offset := t.Zone()
+	_, earlierOffset := t.Add(-3 * time.Hour).Zone()
+	shift := time.Duration(earlierOffset-offset) * time.Second
+	if shift <= 0 {
+		return t
+	}
+	if earlier := t.Add(-shift); wallClock(earlier) == wallClock(t) {
+		return earlier
+	}
+	return t
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	domMatch := has(s.dom, t.Day())
+	dowMatch := has(s.dow, int(t.Weekday()))
+
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowMatch
+	case s.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+func has(bits uint64, value int) bool {
+	return bits&(1<<uint(value)) != 0
+}
@@ -0,0 +1,201 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/-1 * * * *",
+		"*/x * * * *",
+		"30-10 * * * *",
+		"* * * dec-jan *",
+		"* * * * fri-mon",
+		"a * * * *",
+		"1,,2 * * * *",
+		"@weekdays",
+	} {
+		if schedule, err := Parse(expr); err == nil {
+			t.Errorf("%q parsed to %+v, want error", expr, schedule)
+		}
+	}
+}
+
+func TestParseMacros(t *testing.T) {
+	cases := []struct {
+		macro string
+		expr  string
+	}{
+		{"@yearly", "0 0 1 1 *"},
+		{"@annually", "0 0 1 1 *"},
+		{"@monthly", "0 0 1 * *"},
+		{"@weekly", "0 0 * * 0"},
+		{"@daily", "0 0 * * *"},
+		{"@midnight", "0 0 * * *"},
+		{"@hourly", "0 * * * *"},
+		{" @DAILY ", "0 0 * * *"},
+	}
+
+	for _, tc := range cases {
+		macro, err := Parse(tc.macro)
+		if err != nil {
+			t.Errorf("%q failed to parse: %v", tc.macro, err)
+			continue
+		}
+		expanded, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("%q failed to parse: %v", tc.expr, err)
+		}
+		if macro.String() != strings.TrimSpace(tc.macro) {
+			t.Errorf("%q is shown as %q, want expression as given", tc.macro, macro.String())
+		}
+		macro.expr = expanded.expr
+		if macro != expanded {
+			t.Errorf("%q parsed to %+v, want same as %q", tc.macro, macro, tc.expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Monday
+	after := time.Date(2024, 1, 1, 10, 7, 30, 0, time.UTC)
+
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2024, 1, 1, 10, 25, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"0,30 8,22 * * *", time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)},
+		{"0 0 * * *", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * sat,sun", time.Date(2024, 1, 6, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 1, 7, 12, 0, 0, 0, time.UTC)},
+		{"0 0 1 feb *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+		// either day of month or day of week matches when both are restricted
+		{"0 0 15 * fri", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 3 * sun", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		// only * leaves day of month out, so */1 still matches every day
+		{"0 0 */1 * fri", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tc := range cases {
+		schedule, err := Parse(tc.expr)
+		if err != nil {
+			t.Errorf("%q failed to parse: %v", tc.expr, err)
+			continue
+		}
+		if got := schedule.Next(after); !got.Equal(tc.want) {
+			t.Errorf("next activation of %q after %s is %s, want %s", tc.expr, after, got, tc.want)
+		}
+	}
+}
+
+func TestNextIsStrictlyAfter(t *testing.T) {
+	schedule, err := Parse("30 * * * *")
+	if err != nil {
+		t.Fatalf("error parsing: %v", err)
+	}
+	at := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+	if got := schedule.Next(at); !got.Equal(at.Add(time.Hour)) {
+		t.Errorf("next activation after %s is %s, want an hour later", at, got)
+	}
+}
+
+func TestNextAcrossClockChanges(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone database is not available: %v", err)
+	}
+	cet, cest := time.FixedZone("CET", 3600), time.FixedZone("CEST", 2*3600)
+
+	cases := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  []time.Time
+	}{
+		{
+			// 02:00-03:00 does not exist on 31 March 2024, skipped time fires right after the jump
+			"spring forward fixed time",
+			"30 2 * * *",
+			time.Date(2024, 3, 30, 12, 0, 0, 0, berlin),
+			[]time.Time{
+				time.Date(2024, 3, 31, 3, 0, 0, 0, cest),
+				time.Date(2024, 4, 1, 2, 30, 0, 0, cest),
+			},
+		},
+		{
+			"spring forward wildcard",
+			"*/30 * * * *",
+			time.Date(2024, 3, 31, 1, 15, 0, 0, berlin),
+			[]time.Time{
+				time.Date(2024, 3, 31, 1, 30, 0, 0, cet),
+				time.Date(2024, 3, 31, 3, 0, 0, 0, cest),
+			},
+		},
+		{
+			// 02:00-03:00 is shown twice on 27 October 2024, fixed time fires once
+			"fall back fixed time",
+			"30 2 * * *",
+			time.Date(2024, 10, 26, 12, 0, 0, 0, berlin),
+			[]time.Time{
+				time.Date(2024, 10, 27, 2, 30, 0, 0, cest),
+				time.Date(2024, 10, 28, 2, 30, 0, 0, cet),
+			},
+		},
+		{
+			"fall back fixed time from repeated hour",
+			"45 2 * * *",
+			time.Date(2024, 10, 27, 2, 50, 0, 0, cest),
+			[]time.Time{time.Date(2024, 10, 28, 2, 45, 0, 0, cet)},
+		},
+		{
+			"fall back wildcard",
+			"0,30 * * * *",
+			time.Date(2024, 10, 27, 2, 15, 0, 0, cest),
+			[]time.Time{
+				time.Date(2024, 10, 27, 2, 30, 0, 0, cest),
+				time.Date(2024, 10, 27, 2, 0, 0, 0, cet),
+				time.Date(2024, 10, 27, 2, 30, 0, 0, cet),
+				time.Date(2024, 10, 27, 3, 0, 0, 0, cet),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		schedule, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: error parsing %q: %v", tc.name, tc.expr, err)
+		}
+		at := tc.after.In(berlin)
+		for _, want := range tc.want {
+			got := schedule.Next(at)
+			if !got.Equal(want) {
+				t.Errorf("%s: next activation of %q after %s is %s, want %s", tc.name, tc.expr, at, got, want)
+				break
+			}
+			if got.Location() != berlin {
+				t.Errorf("%s: activation %s is not in location of given time", tc.name, got)
+			}
+			at = got
+		}
+	}
+}
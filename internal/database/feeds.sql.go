@@ -7,11 +7,214 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimActiveFeeds = `-- name: ClaimActiveFeeds :many
+WITH claimable AS (
+    SELECT feeds.id FROM feeds
+    WHERE disabled_at IS NULL
+    AND (claimed_by IS NULL OR claimed_until < NOW())
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE feeds
+SET claimed_by = $1,
+claimed_until = NOW() + ($2::integer * INTERVAL '1 second'),
+last_fetched_at = NOW(),
+updated_at = NOW()
+FROM claimable
+WHERE feeds.id = claimable.id
+RETURNING feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.next_fetch_at, feeds.fetch_interval_seconds, feeds.min_fetch_interval_seconds, feeds.max_fetch_interval_seconds, feeds.consecutive_failures, feeds.last_error, feeds.last_succeeded_at, feeds.disabled_at, feeds.claimed_by, feeds.claimed_until, feeds.cron_schedule, feeds.retention_max_posts, feeds.retention_max_age_seconds
+`
+
+type ClaimActiveFeedsParams struct {
+	InstanceID   uuid.NullUUID
+	LeaseSeconds int32
+}
+
+func (q *Queries) ClaimActiveFeeds(ctx context.Context, arg ClaimActiveFeedsParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimActiveFeeds, arg.InstanceID, arg.LeaseSeconds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.FetchIntervalSeconds,
+			&i.MinFetchIntervalSeconds,
+			&i.MaxFetchIntervalSeconds,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSucceededAt,
+			&i.DisabledAt,
+			&i.ClaimedBy,
+			&i.ClaimedUntil,
+			&i.CronSchedule,
+			&i.RetentionMaxPosts,
+			&i.RetentionMaxAgeSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimDueFeeds = `-- name: ClaimDueFeeds :many
+WITH claimable AS (
+    SELECT feeds.id FROM feeds
+    WHERE disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+    AND (claimed_by IS NULL OR claimed_until < NOW())
+    AND (NOT $1::boolean OR cron_schedule IS NOT NULL)
+    ORDER BY next_fetch_at ASC NULLS FIRST
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE feeds
+SET claimed_by = $3,
+claimed_until = NOW() + ($4::integer * INTERVAL '1 second'),
+last_fetched_at = NOW(),
+updated_at = NOW()
+FROM claimable
+WHERE feeds.id = claimable.id
+RETURNING feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at, feeds.next_fetch_at, feeds.fetch_interval_seconds, feeds.min_fetch_interval_seconds, feeds.max_fetch_interval_seconds, feeds.consecutive_failures, feeds.last_error, feeds.last_succeeded_at, feeds.disabled_at, feeds.claimed_by, feeds.claimed_until, feeds.cron_schedule, feeds.retention_max_posts, feeds.retention_max_age_seconds
+`
+
+type ClaimDueFeedsParams struct {
+	ScheduledOnly bool
+	MaxFeeds      int32
+	InstanceID    uuid.NullUUID
+	LeaseSeconds  int32
+}
+
+func (q *Queries) ClaimDueFeeds(ctx context.Context, arg ClaimDueFeedsParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimDueFeeds,
+		arg.ScheduledOnly,
+		arg.MaxFeeds,
+		arg.InstanceID,
+		arg.LeaseSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.FetchIntervalSeconds,
+			&i.MinFetchIntervalSeconds,
+			&i.MaxFetchIntervalSeconds,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSucceededAt,
+			&i.DisabledAt,
+			&i.ClaimedBy,
+			&i.ClaimedUntil,
+			&i.CronSchedule,
+			&i.RetentionMaxPosts,
+			&i.RetentionMaxAgeSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const claimFeed = `-- name: ClaimFeed :one
+UPDATE feeds
+SET claimed_by = $1,
+claimed_until = NOW() + ($2::integer * INTERVAL '1 second'),
+last_fetched_at = NOW(),
+updated_at = NOW()
+WHERE id = $3
+AND (claimed_by IS NULL OR claimed_until < NOW())
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
+`
+
+type ClaimFeedParams struct {
+	InstanceID   uuid.NullUUID
+	LeaseSeconds int32
+	ID           uuid.UUID
+}
+
+func (q *Queries) ClaimFeed(ctx context.Context, arg ClaimFeedParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimFeed, arg.InstanceID, arg.LeaseSeconds, arg.ID)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
+	)
+	return i, err
+}
+
+const countOverdueFeeds = `-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE disabled_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+AND (claimed_by IS NULL OR claimed_until < NOW())
+`
+
+func (q *Queries) CountOverdueFeeds(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOverdueFeeds)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES (
@@ -22,7 +225,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
 `
 
 type CreateFeedParams struct {
@@ -52,6 +255,19 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
 	)
 	return i, err
 }
@@ -115,6 +331,18 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 	return i, err
 }
 
+const deleteFeed = `-- name: DeleteFeed :execrows
+DELETE FROM feeds WHERE id = $1
+`
+
+func (q *Queries) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteFeed, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteFeeds = `-- name: DeleteFeeds :exec
 DELETE from feeds
 `
@@ -141,8 +369,115 @@ func (q *Queries) DeleteFeedsFollow(ctx context.Context, arg DeleteFeedsFollowPa
 	return err
 }
 
+const enableFeed = `-- name: EnableFeed :one
+UPDATE feeds
+SET disabled_at = NULL,
+consecutive_failures = 0,
+next_fetch_at = NULL,
+updated_at = NOW()
+WHERE url = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
+`
+
+func (q *Queries) EnableFeed(ctx context.Context, url string) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, enableFeed, url)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
+	)
+	return i, err
+}
+
+const extendFeedClaims = `-- name: ExtendFeedClaims :execrows
+UPDATE feeds
+SET claimed_until = NOW() + ($1::integer * INTERVAL '1 second')
+WHERE claimed_by = $2
+`
+
+type ExtendFeedClaimsParams struct {
+	LeaseSeconds int32
+	InstanceID   uuid.NullUUID
+}
+
+func (q *Queries) ExtendFeedClaims(ctx context.Context, arg ExtendFeedClaimsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, extendFeedClaims, arg.LeaseSeconds, arg.InstanceID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getBrokenFeeds = `-- name: GetBrokenFeeds :many
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds FROM feeds
+WHERE disabled_at IS NOT NULL OR consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name
+`
+
+func (q *Queries) GetBrokenFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getBrokenFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.NextFetchAt,
+			&i.FetchIntervalSeconds,
+			&i.MinFetchIntervalSeconds,
+			&i.MaxFetchIntervalSeconds,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.LastSucceededAt,
+			&i.DisabledAt,
+			&i.ClaimedBy,
+			&i.ClaimedUntil,
+			&i.CronSchedule,
+			&i.RetentionMaxPosts,
+			&i.RetentionMaxAgeSeconds,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedById = `-- name: GetFeedById :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE feeds.id = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds FROM feeds WHERE feeds.id = $1
 `
 
 func (q *Queries) GetFeedById(ctx context.Context, id uuid.UUID) (Feed, error) {
@@ -156,12 +491,25 @@ func (q *Queries) GetFeedById(ctx context.Context, id uuid.UUID) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
 	)
 	return i, err
 }
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE feeds.url=$1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds FROM feeds WHERE feeds.url=$1
 `
 
 func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
@@ -175,6 +523,19 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
 	)
 	return i, err
 }
@@ -263,12 +624,80 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1
+const getOwnedFeeds = `-- name: GetOwnedFeeds :many
+SELECT  feeds.id,
+        feeds.name,
+        feeds.url,
+        (SELECT count(*) FROM feed_follows
+         WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> feeds.user_id) AS other_followers,
+        (SELECT count(*) FROM posts WHERE posts.feed_id = feeds.id) AS posts
+FROM feeds
+WHERE feeds.user_id = $1
+ORDER BY feeds.created_at, feeds.name
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+type GetOwnedFeedsRow struct {
+	ID             uuid.UUID
+	Name           string
+	Url            string
+	OtherFollowers int64
+	Posts          int64
+}
+
+func (q *Queries) GetOwnedFeeds(ctx context.Context, userID uuid.UUID) ([]GetOwnedFeedsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOwnedFeeds, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOwnedFeedsRow
+	for rows.Next() {
+		var i GetOwnedFeedsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Url,
+			&i.OtherFollowers,
+			&i.Posts,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const recordFeedFailure = `-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+last_error = $2,
+next_fetch_at = $3,
+disabled_at = CASE WHEN consecutive_failures + 1 >= $4::integer THEN NOW() ELSE disabled_at END,
+updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
+`
+
+type RecordFeedFailureParams struct {
+	ID          uuid.UUID
+	LastError   sql.NullString
+	NextFetchAt sql.NullTime
+	MaxFailures int32
+}
+
+func (q *Queries) RecordFeedFailure(ctx context.Context, arg RecordFeedFailureParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedFailure,
+		arg.ID,
+		arg.LastError,
+		arg.NextFetchAt,
+		arg.MaxFailures,
+	)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -278,20 +707,66 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
 	)
 	return i, err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :one
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+last_error = NULL,
+last_succeeded_at = NOW(),
+updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, id)
+	return err
+}
+
+const releaseFeedClaim = `-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_by = NULL,
+claimed_until = NULL
+WHERE id = $1
+`
+
+func (q *Queries) ReleaseFeedClaim(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedClaim, id)
+	return err
+}
+
+const scheduleFeedFetch = `-- name: ScheduleFeedFetch :one
 UPDATE feeds
-SET last_fetched_at = NOW(),
+SET fetch_interval_seconds = $2,
+next_fetch_at = $3,
 updated_at = NOW()
 WHERE id = $1
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, markFeedFetched, id)
+type ScheduleFeedFetchParams struct {
+	ID                   uuid.UUID
+	FetchIntervalSeconds int32
+	NextFetchAt          sql.NullTime
+}
+
+func (q *Queries) ScheduleFeedFetch(ctx context.Context, arg ScheduleFeedFetchParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, scheduleFeedFetch, arg.ID, arg.FetchIntervalSeconds, arg.NextFetchAt)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -301,6 +776,168 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) (Feed, erro
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
 	)
 	return i, err
 }
+
+const setFeedFetchBounds = `-- name: SetFeedFetchBounds :one
+UPDATE feeds
+SET min_fetch_interval_seconds = $2,
+max_fetch_interval_seconds = $3,
+fetch_interval_seconds = LEAST(GREATEST(fetch_interval_seconds, $2), $3),
+updated_at = NOW()
+WHERE url = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
+`
+
+type SetFeedFetchBoundsParams struct {
+	Url                     string
+	MinFetchIntervalSeconds int32
+	MaxFetchIntervalSeconds int32
+}
+
+func (q *Queries) SetFeedFetchBounds(ctx context.Context, arg SetFeedFetchBoundsParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedFetchBounds, arg.Url, arg.MinFetchIntervalSeconds, arg.MaxFetchIntervalSeconds)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
+	)
+	return i, err
+}
+
+const setFeedRetention = `-- name: SetFeedRetention :one
+UPDATE feeds
+SET retention_max_posts = $2,
+retention_max_age_seconds = $3,
+updated_at = NOW()
+WHERE url = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
+`
+
+type SetFeedRetentionParams struct {
+	Url                    string
+	RetentionMaxPosts      sql.NullInt32
+	RetentionMaxAgeSeconds sql.NullInt32
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedRetention, arg.Url, arg.RetentionMaxPosts, arg.RetentionMaxAgeSeconds)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
+	)
+	return i, err
+}
+
+const setFeedSchedule = `-- name: SetFeedSchedule :one
+UPDATE feeds
+SET cron_schedule = $2,
+next_fetch_at = $3,
+updated_at = NOW()
+WHERE url = $1
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at, fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds, consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by, claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
+`
+
+type SetFeedScheduleParams struct {
+	Url          string
+	CronSchedule sql.NullString
+	NextFetchAt  sql.NullTime
+}
+
+func (q *Queries) SetFeedSchedule(ctx context.Context, arg SetFeedScheduleParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, setFeedSchedule, arg.Url, arg.CronSchedule, arg.NextFetchAt)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.NextFetchAt,
+		&i.FetchIntervalSeconds,
+		&i.MinFetchIntervalSeconds,
+		&i.MaxFetchIntervalSeconds,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.LastSucceededAt,
+		&i.DisabledAt,
+		&i.ClaimedBy,
+		&i.ClaimedUntil,
+		&i.CronSchedule,
+		&i.RetentionMaxPosts,
+		&i.RetentionMaxAgeSeconds,
+	)
+	return i, err
+}
+
+const transferFeeds = `-- name: TransferFeeds :execrows
+UPDATE feeds SET user_id = $1, updated_at = $2
+WHERE user_id = $3
+`
+
+type TransferFeedsParams struct {
+	ToUserID   uuid.UUID
+	UpdatedAt  time.Time
+	FromUserID uuid.UUID
+}
+
+func (q *Queries) TransferFeeds(ctx context.Context, arg TransferFeedsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, transferFeeds, arg.ToUserID, arg.UpdatedAt, arg.FromUserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -11,14 +11,64 @@ import (
 	"github.com/google/uuid"
 )
 
+type AggInstance struct {
+	ID          uuid.UUID
+	Hostname    string
+	Pid         int32
+	StartedAt   time.Time
+	HeartbeatAt time.Time
+	Daemon      bool
+}
+
+type ApiToken struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	Name      string
+	TokenHash []byte
+	ExpiresAt sql.NullTime
+}
+
+type AuditLog struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.NullUUID
+	UserName   sql.NullString
+	Command    string
+	Args       string
+	Succeeded  bool
+	Error      sql.NullString
+	DurationMs int64
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                      uuid.UUID
+	CreatedAt               time.Time
+	UpdatedAt               time.Time
+	Name                    string
+	Url                     string
+	UserID                  uuid.UUID
+	LastFetchedAt           sql.NullTime
+	NextFetchAt             sql.NullTime
+	FetchIntervalSeconds    int32
+	MinFetchIntervalSeconds int32
+	MaxFetchIntervalSeconds int32
+	ConsecutiveFailures     int32
+	LastError               sql.NullString
+	LastSucceededAt         sql.NullTime
+	DisabledAt              sql.NullTime
+	ClaimedBy               uuid.NullUUID
+	ClaimedUntil            sql.NullTime
+	CronSchedule            sql.NullString
+	RetentionMaxPosts       sql.NullInt32
+	RetentionMaxAgeSeconds  sql.NullInt32
+}
+
+type FeedCredential struct {
+	FeedID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Sealed    []byte
 }
 
 type FeedFollow struct {
@@ -29,15 +79,46 @@ type FeedFollow struct {
 	FeedID    uuid.UUID
 }
 
-type Post struct {
+type FetchQueue struct {
 	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
 	FeedID      uuid.UUID
+	Priority    int32
+	RequestedAt time.Time
+	RequestedBy uuid.NullUUID
+}
+
+type FetchRun struct {
+	ID            uuid.UUID
+	FeedID        uuid.UUID
+	StartedAt     time.Time
+	FinishedAt    time.Time
+	HttpStatus    sql.NullInt32
+	Bytes         int64
+	ItemsSeen     int32
+	ItemsInserted int32
+	ItemsSkipped  int32
+	Error         sql.NullString
+}
+
+type Post struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  sql.NullTime
+	FeedID       uuid.UUID
+	Content      sql.NullString
+	OriginalUrl  string
+	SearchVector interface{}
+}
+
+type StarredPost struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	Note      sql.NullString
 }
 
 type User struct {
@@ -45,4 +126,5 @@ type User struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Name      string
+	Role      string
 }
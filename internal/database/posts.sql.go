@@ -11,45 +11,103 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7,
-    $8
-)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const createPosts = `-- name: CreatePosts :many
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, original_url)
+SELECT  gen_random_uuid(),
+        $1::timestamp,
+        $1::timestamp,
+        item.title,
+        item.url,
+        NULLIF(item.description, ''),
+        NULLIF(item.published_at, '')::timestamptz AT TIME ZONE 'UTC',
+        $2::uuid,
+        NULLIF(item.content, ''),
+        item.original_url
+FROM unnest(
+    $3::text[],
+    $4::text[],
+    $5::text[],
+    $6::text[],
+    $7::text[],
+    $8::text[]
+) AS item(title, url, description, published_at, content, original_url)
+ON CONFLICT (feed_id, url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, content, original_url, search_vector
 `
 
-type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+type CreatePostsParams struct {
+	CreatedAt    time.Time
+	FeedID       uuid.UUID
+	Titles       []string
+	Urls         []string
+	Descriptions []string
+	PublishedAts []string
+	Contents     []string
+	OriginalUrls []string
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
+func (q *Queries) CreatePosts(ctx context.Context, arg CreatePostsParams) ([]Post, error) {
+	rows, err := q.db.QueryContext(ctx, createPosts,
 		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
 		arg.FeedID,
+		pq.Array(arg.Titles),
+		pq.Array(arg.Urls),
+		pq.Array(arg.Descriptions),
+		pq.Array(arg.PublishedAts),
+		pq.Array(arg.Contents),
+		pq.Array(arg.OriginalUrls),
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Post
+	for rows.Next() {
+		var i Post
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Content,
+			&i.OriginalUrl,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostByUrl = `-- name: GetPostByUrl :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.original_url, posts.search_vector FROM posts
+LEFT JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $1
+WHERE posts.url = $2
+ORDER BY feed_follows.id IS NULL, posts.created_at
+LIMIT 1
+`
+
+type GetPostByUrlParams struct {
+	UserID uuid.UUID
+	Url    string
+}
+
+func (q *Queries) GetPostByUrl(ctx context.Context, arg GetPostByUrlParams) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByUrl, arg.UserID, arg.Url)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -60,12 +118,15 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Content,
+		&i.OriginalUrl,
+		&i.SearchVector,
 	)
 	return i, err
 }
 
 const getPostForUser = `-- name: GetPostForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id from posts INNER JOIN feeds on posts.feed_id = feeds.id where feeds.user_id = $1 order by posts.published_at DESC limit $2
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.content, posts.original_url, posts.search_vector from posts INNER JOIN feeds on posts.feed_id = feeds.id where feeds.user_id = $1 order by posts.published_at DESC limit $2
 `
 
 type GetPostForUserParams struct {
@@ -91,6 +152,257 @@ func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams)
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Content,
+			&i.OriginalUrl,
+			&i.SearchVector,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPrunablePostCounts = `-- name: GetPrunablePostCounts :many
+WITH ranked AS (
+    SELECT  posts.id,
+            posts.feed_id,
+            posts.created_at,
+            ROW_NUMBER() OVER (
+                PARTITION BY posts.feed_id
+                ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
+            ) AS position
+    FROM posts
+    WHERE NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+), prunable AS (
+    SELECT ranked.id, ranked.feed_id FROM ranked
+    INNER JOIN feeds ON feeds.id = ranked.feed_id
+    WHERE ranked.created_at < COALESCE(
+        NOW() - (feeds.retention_max_age_seconds * INTERVAL '1 second'),
+        $1::timestamp
+    )
+    OR ranked.position > feeds.retention_max_posts
+)
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, COUNT(*) AS posts
+FROM prunable
+INNER JOIN feeds ON feeds.id = prunable.feed_id
+GROUP BY feeds.id
+ORDER BY feeds.name
+`
+
+type GetPrunablePostCountsRow struct {
+	FeedName string
+	FeedUrl  string
+	Posts    int64
+}
+
+func (q *Queries) GetPrunablePostCounts(ctx context.Context, createdBefore sql.NullTime) ([]GetPrunablePostCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePostCounts, createdBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrunablePostCountsRow
+	for rows.Next() {
+		var i GetPrunablePostCountsRow
+		if err := rows.Scan(&i.FeedName, &i.FeedUrl, &i.Posts); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRecentPostTimes = `-- name: GetRecentPostTimes :many
+SELECT COALESCE(published_at, created_at)::timestamp AS posted_at
+FROM posts
+WHERE feed_id = $1
+ORDER BY posted_at DESC
+LIMIT $2
+`
+
+type GetRecentPostTimesParams struct {
+	FeedID uuid.UUID
+	Limit  int32
+}
+
+func (q *Queries) GetRecentPostTimes(ctx context.Context, arg GetRecentPostTimesParams) ([]time.Time, error) {
+	rows, err := q.db.QueryContext(ctx, getRecentPostTimes, arg.FeedID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []time.Time
+	for rows.Next() {
+		var posted_at time.Time
+		if err := rows.Scan(&posted_at); err != nil {
+			return nil, err
+		}
+		items = append(items, posted_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const postExistsWithOriginalUrl = `-- name: PostExistsWithOriginalUrl :one
+SELECT EXISTS(SELECT 1 FROM posts WHERE feed_id = $1 AND original_url = $2)
+`
+
+type PostExistsWithOriginalUrlParams struct {
+	FeedID      uuid.UUID
+	OriginalUrl string
+}
+
+func (q *Queries) PostExistsWithOriginalUrl(ctx context.Context, arg PostExistsWithOriginalUrlParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, postExistsWithOriginalUrl, arg.FeedID, arg.OriginalUrl)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const prunePosts = `-- name: PrunePosts :many
+WITH ranked AS (
+    SELECT  posts.id,
+            posts.feed_id,
+            posts.created_at,
+            ROW_NUMBER() OVER (
+                PARTITION BY posts.feed_id
+                ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
+            ) AS position
+    FROM posts
+    WHERE NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+), prunable AS (
+    SELECT ranked.id, ranked.feed_id FROM ranked
+    INNER JOIN feeds ON feeds.id = ranked.feed_id
+    WHERE ranked.created_at < COALESCE(
+        NOW() - (feeds.retention_max_age_seconds * INTERVAL '1 second'),
+        $1::timestamp
+    )
+    OR ranked.position > feeds.retention_max_posts
+), deleted AS (
+    DELETE FROM posts
+    USING prunable
+    WHERE posts.id = prunable.id
+    RETURNING posts.feed_id
+)
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, COUNT(*) AS posts
+FROM deleted
+INNER JOIN feeds ON feeds.id = deleted.feed_id
+GROUP BY feeds.id
+ORDER BY feeds.name
+`
+
+type PrunePostsRow struct {
+	FeedName string
+	FeedUrl  string
+	Posts    int64
+}
+
+func (q *Queries) PrunePosts(ctx context.Context, createdBefore sql.NullTime) ([]PrunePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, prunePosts, createdBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PrunePostsRow
+	for rows.Next() {
+		var i PrunePostsRow
+		if err := rows.Scan(&i.FeedName, &i.FeedUrl, &i.Posts); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT  posts.id,
+        posts.title,
+        posts.url,
+        posts.published_at,
+        feeds.name AS feed_name,
+        ts_rank(posts.search_vector, websearch_to_tsquery('english', $1)) AS rank,
+        ts_headline(
+            'english',
+            concat_ws(' ', posts.title, posts.description, posts.content),
+            websearch_to_tsquery('english', $1),
+            'StartSel=**, StopSel=**, MaxFragments=2, MinWords=5, MaxWords=20'
+        ) AS snippet
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = $2
+AND posts.search_vector @@ websearch_to_tsquery('english', $1)
+AND ($3::text IS NULL OR feeds.url = $3)
+AND COALESCE(posts.published_at, posts.created_at) >= $4
+ORDER BY rank DESC, COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT $5
+`
+
+type SearchPostsParams struct {
+	Query      string
+	UserID     uuid.UUID
+	FeedUrl    sql.NullString
+	Since      time.Time
+	MaxResults int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.UserID,
+		arg.FeedUrl,
+		arg.Since,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
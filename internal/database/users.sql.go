@@ -12,6 +12,17 @@ import (
 	"github.com/google/uuid"
 )
 
+const countUsers = `-- name: CountUsers :one
+SELECT count(*) FROM users
+`
+
+func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsers)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, name)
 VALUES (
@@ -20,7 +31,7 @@ VALUES (
     $3,
     $4
 )
-RETURNING id, created_at, updated_at, name
+RETURNING id, created_at, updated_at, name, role
 `
 
 type CreateUserParams struct {
@@ -43,10 +54,23 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.Role,
 	)
 	return i, err
 }
 
+const deleteUser = `-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1
+`
+
+func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const deleteUsers = `-- name: DeleteUsers :exec
 DELETE from users
 `
@@ -57,7 +81,7 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT users.id, users.created_at, users.updated_at, users.name from users where users.name=$1
+SELECT users.id, users.created_at, users.updated_at, users.name, users.role from users where users.name=$1
 `
 
 func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
@@ -68,6 +92,35 @@ func (q *Queries) GetUser(ctx context.Context, name string) (User, error) {
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.Name,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserActivity = `-- name: GetUserActivity :one
+SELECT  (SELECT count(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follows,
+        (SELECT count(*) FROM starred_posts WHERE starred_posts.user_id = $1) AS starred,
+        (SELECT count(*) FROM api_tokens WHERE api_tokens.user_id = $1) AS tokens,
+        (SELECT count(*) FROM posts
+         INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+         WHERE feed_follows.user_id = $1) AS followed_posts
+`
+
+type GetUserActivityRow struct {
+	Follows       int64
+	Starred       int64
+	Tokens        int64
+	FollowedPosts int64
+}
+
+func (q *Queries) GetUserActivity(ctx context.Context, userID uuid.UUID) (GetUserActivityRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserActivity, userID)
+	var i GetUserActivityRow
+	err := row.Scan(
+		&i.Follows,
+		&i.Starred,
+		&i.Tokens,
+		&i.FollowedPosts,
 	)
 	return i, err
 }
@@ -84,7 +137,7 @@ func (q *Queries) GetUsernameById(ctx context.Context, id uuid.UUID) (string, er
 }
 
 const getUsers = `-- name: GetUsers :many
-SELECT id, created_at, updated_at, name FROM users
+SELECT id, created_at, updated_at, name, role FROM users
 `
 
 func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
@@ -101,6 +154,7 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.Name,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
@@ -114,3 +168,53 @@ func (q *Queries) GetUsers(ctx context.Context) ([]User, error) {
 	}
 	return items, nil
 }
+
+const renameUser = `-- name: RenameUser :one
+UPDATE users SET name = $1, updated_at = $2
+WHERE name = $3
+RETURNING id, created_at, updated_at, name, role
+`
+
+type RenameUserParams struct {
+	NewName   string
+	UpdatedAt time.Time
+	Name      string
+}
+
+func (q *Queries) RenameUser(ctx context.Context, arg RenameUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, renameUser, arg.NewName, arg.UpdatedAt, arg.Name)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Role,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users SET role = $2, updated_at = $3
+WHERE name = $1
+RETURNING id, created_at, updated_at, name, role
+`
+
+type SetUserRoleParams struct {
+	Name      string
+	Role      string
+	UpdatedAt time.Time
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.Name, arg.Role, arg.UpdatedAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Role,
+	)
+	return i, err
+}
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Upper bounds in seconds of fetch latency histogram buckets
+var fetchDurationBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Gauge value computed when metrics are scraped, e.g. from database
+type GaugeFunc func(ctx context.Context) (float64, error)
+
+type gauge struct {
+	name  string
+	help  string
+	value GaugeFunc
+}
+
+// Registry collects aggregator metrics and renders them in Prometheus text format.
+// Methods are safe to call on nil registry, which records nothing.
+type Registry struct {
+	mu                sync.Mutex
+	fetches           map[string]uint64
+	durationCounts    []uint64
+	durationSum       float64
+	durationCount     uint64
+	postsInserted     uint64
+	duplicatesSkipped uint64
+	lastTick          time.Time
+	gauges            []gauge
+}
+
+func New() *Registry {
+	return &Registry{
+		fetches:        map[string]uint64{},
+		durationCounts: make([]uint64, len(fetchDurationBuckets)),
+		lastTick:       time.Now(),
+	}
+}
+
+// Records finished fetch of single feed
+func (r *Registry) ObserveFetch(outcome string, duration time.Duration, inserted, skipped int) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fetches[outcome]++
+	seconds := duration.Seconds()
+	for i, bound := range fetchDurationBuckets {
+		if seconds <= bound {
+			r.durationCounts[i]++
+		}
+	}
+	r.durationSum += seconds
+	r.durationCount++
+	r.postsInserted += uint64(inserted)
+	r.duplicatesSkipped += uint64(skipped)
+	r.lastTick = time.Now()
+}
+
+// Marks that scheduler is making progress
+func (r *Registry) Tick() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastTick = time.Now()
+}
+
+// Time of last scheduler progress
+func (r *Registry) LastTick() time.Time {
+	if r == nil {
+		return time.Time{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastTick
+}
+
+// Adds gauge evaluated on every scrape
+func (r *Registry) RegisterGauge(name, help string, value GaugeFunc) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gauges = append(r.gauges, gauge{name: name, help: help, value: value})
+}
+
+// Writes all metrics in Prometheus text exposition format
+func (r *Registry) WriteText(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	gauges := append([]gauge(nil), r.gauges...)
+	r.mu.Unlock()
+
+	// Gauges may hit database so they are evaluated without holding the lock
+	gaugeValues := make([]float64, len(gauges))
+	for i, g := range gauges {
+		value, err := g.value(ctx)
+		if err != nil {
+			return fmt.Errorf("error computing metric %s: %w", g.name, err)
+		}
+		gaugeValues[i] = value
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outcomes := make([]string, 0, len(r.fetches))
+	for outcome := range r.fetches {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+
+	fmt.Fprintf(w, "# HELP gator_fetches_total Feed fetches by outcome.\n")
+	fmt.Fprintf(w, "# TYPE gator_fetches_total counter\n")
+	for _, outcome := range outcomes {
+		fmt.Fprintf(w, "gator_fetches_total{outcome=%q} %d\n", outcome, r.fetches[outcome])
+	}
+
+	fmt.Fprintf(w, "# HELP gator_fetch_duration_seconds Time spent fetching and storing single feed.\n")
+	fmt.Fprintf(w, "# TYPE gator_fetch_duration_seconds histogram\n")
+	for i, bound := range fetchDurationBuckets {
+		fmt.Fprintf(w, "gator_fetch_duration_seconds_bucket{le=%q} %d\n", formatFloat(bound), r.durationCounts[i])
+	}
+	fmt.Fprintf(w, "gator_fetch_duration_seconds_bucket{le=\"+Inf\"} %d\n", r.durationCount)
+	fmt.Fprintf(w, "gator_fetch_duration_seconds_sum %s\n", formatFloat(r.durationSum))
+	fmt.Fprintf(w, "gator_fetch_duration_seconds_count %d\n", r.durationCount)
+
+	fmt.Fprintf(w, "# HELP gator_posts_inserted_total Posts stored from fetched feeds.\n")
+	fmt.Fprintf(w, "# TYPE gator_posts_inserted_total counter\n")
+	fmt.Fprintf(w, "gator_posts_inserted_total %d\n", r.postsInserted)
+
+	fmt.Fprintf(w, "# HELP gator_posts_duplicates_total Feed items skipped because post already existed.\n")
+	fmt.Fprintf(w, "# TYPE gator_posts_duplicates_total counter\n")
+	fmt.Fprintf(w, "gator_posts_duplicates_total %d\n", r.duplicatesSkipped)
+
+	for i, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n", g.name, g.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", g.name)
+		fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(gaugeValues[i]))
+	}
+
+	return nil
+}
+
+// HTTP handler serving metrics for Prometheus scrapes
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body bytes.Buffer
+		if err := r.WriteText(req.Context(), &body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(body.Bytes())
+	})
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,360 @@
+// Package migrate applies goose annotated SQL migrations and records them in
+// goose_db_version, so databases migrated with goose CLI keep working.
+package migrate
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const versionTable = "goose_db_version"
+
+// Single migration file eg. 001_users.sql
+type Migration struct {
+	Version int64
+	Name    string
+	up      []string
+	down    []string
+	noTx    bool
+}
+
+// State of migration in database
+type Status struct {
+	Migration Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Reads migrations from *.sql files in dir ordered by version
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %v", err)
+	}
+
+	migrations := []Migration{}
+	seen := map[int64]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		versionPart, _, _ := strings.Cut(entry.Name(), "_")
+		version, parseErr := strconv.ParseInt(versionPart, 10, 64)
+		if parseErr != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s must start with positive version number", entry.Name())
+		}
+		if other, duplicate := seen[version]; duplicate {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		content, readErr := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if readErr != nil {
+			return nil, fmt.Errorf("error reading migration %s: %v", entry.Name(), readErr)
+		}
+
+		migration, splitErr := parseMigration(string(content))
+		if splitErr != nil {
+			return nil, fmt.Errorf("error parsing migration %s: %v", entry.Name(), splitErr)
+		}
+		migration.Version = version
+		migration.Name = entry.Name()
+		migrations = append(migrations, migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Splits goose annotated file to up and down statements. Statements end with
+// semicolon at the end of line unless wrapped in StatementBegin/StatementEnd
+func parseMigration(content string) (Migration, error) {
+	migration := Migration{}
+	var section *[]string
+	var statement strings.Builder
+	inBlock := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "-- +goose") {
+			switch strings.TrimSpace(strings.TrimPrefix(trimmed, "-- +goose")) {
+			case "Up":
+				section = &migration.up
+			case "Down":
+				section = &migration.down
+			case "StatementBegin":
+				inBlock = true
+			case "StatementEnd":
+				inBlock = false
+				if section != nil && strings.TrimSpace(statement.String()) != "" {
+					*section = append(*section, statement.String())
+				}
+				statement.Reset()
+			case "NO TRANSACTION":
+				migration.noTx = true
+			default:
+				return Migration{}, fmt.Errorf("unknown annotation %q", trimmed)
+			}
+			continue
+		}
+
+		if section == nil || (!inBlock && (trimmed == "" || strings.HasPrefix(trimmed, "--"))) {
+			continue
+		}
+
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if !inBlock && strings.HasSuffix(trimmed, ";") {
+			*section = append(*section, statement.String())
+			statement.Reset()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Migration{}, err
+	}
+
+	if inBlock {
+		return Migration{}, fmt.Errorf("missing StatementEnd annotation")
+	}
+	if strings.TrimSpace(statement.String()) != "" {
+		return Migration{}, fmt.Errorf("statement is missing terminating semicolon")
+	}
+	if migration.up == nil {
+		return Migration{}, fmt.Errorf("missing Up annotation")
+	}
+	return migration, nil
+}
+
+// SQL flavour of database, decides how version table is created
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+func (d Dialect) versionTableDDL() string {
+	if d == SQLite {
+		return `CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version_id INTEGER NOT NULL,
+    is_applied INTEGER NOT NULL,
+    tstamp TIMESTAMP DEFAULT (datetime('now'))
+)`
+	}
+	return `CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+    id SERIAL PRIMARY KEY,
+    version_id BIGINT NOT NULL,
+    is_applied BOOLEAN NOT NULL,
+    tstamp TIMESTAMP DEFAULT NOW()
+)`
+}
+
+func (d Dialect) versionTableExistsQuery() string {
+	if d == SQLite {
+		return `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = '` + versionTable + `')`
+	}
+	return `SELECT to_regclass('` + versionTable + `') IS NOT NULL`
+}
+
+// Applies migrations of one backend to its database
+type Runner struct {
+	db         *sql.DB
+	dialect    Dialect
+	migrations []Migration
+}
+
+func NewRunner(db *sql.DB, dialect Dialect, migrations []Migration) *Runner {
+	return &Runner{db: db, dialect: dialect, migrations: migrations}
+}
+
+// Creates version table like goose does, with version 0 marking empty database
+func (r *Runner) ensureVersionTable(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, r.dialect.versionTableDDL()); err != nil {
+		return fmt.Errorf("error creating %s table: %v", versionTable, err)
+	}
+
+	_, err := r.db.ExecContext(ctx, `INSERT INTO `+versionTable+` (version_id, is_applied)
+SELECT 0, TRUE WHERE NOT EXISTS (SELECT 1 FROM `+versionTable+`)`)
+	if err != nil {
+		return fmt.Errorf("error initializing %s table: %v", versionTable, err)
+	}
+	return nil
+}
+
+// Applied versions with time of application. Latest row of each version wins,
+// older goose releases recorded rollbacks as rows with is_applied false.
+// Database without version table has nothing applied, it is not created here
+func (r *Runner) appliedVersions(ctx context.Context) (map[int64]time.Time, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, r.dialect.versionTableExistsQuery()).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error checking %s table: %v", versionTable, err)
+	}
+	if !exists {
+		return map[int64]time.Time{}, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT version_id, is_applied, tstamp FROM `+versionTable+` ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied migrations: %v", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		var appliedAt sql.NullTime
+		if err := rows.Scan(&version, &isApplied, &appliedAt); err != nil {
+			return nil, err
+		}
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if isApplied && version > 0 {
+			applied[version] = appliedAt.Time
+		}
+	}
+	return applied, rows.Err()
+}
+
+// State of every known migration, only reads the database
+func (r *Runner) Statuses(ctx context.Context) ([]Status, error) {
+	applied, err := r.appliedVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(r.migrations))
+	for _, migration := range r.migrations {
+		appliedAt, isApplied := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: isApplied, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+// Migrations not applied yet, in order they would be applied
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	statuses, err := r.Statuses(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Applies all pending migrations, returns the applied ones
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	if err := r.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		if err := r.run(ctx, migration, migration.up, true); err != nil {
+			return pending[:i], err
+		}
+	}
+	return pending, nil
+}
+
+// Rolls back latest applied migration, ok is false when nothing is applied
+func (r *Runner) Down(ctx context.Context) (Migration, bool, error) {
+	if err := r.ensureVersionTable(ctx); err != nil {
+		return Migration{}, false, err
+	}
+	statuses, err := r.Statuses(ctx)
+	if err != nil {
+		return Migration{}, false, err
+	}
+
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if statuses[i].Applied {
+			migration := statuses[i].Migration
+			return migration, true, r.run(ctx, migration, migration.down, false)
+		}
+	}
+	return Migration{}, false, nil
+}
+
+// Rolls back latest applied migration and applies it again, later pending ones are left alone
+func (r *Runner) Redo(ctx context.Context) (Migration, error) {
+	migration, rolledBack, err := r.Down(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	if !rolledBack {
+		return Migration{}, fmt.Errorf("no applied migration to redo")
+	}
+	return migration, r.run(ctx, migration, migration.up, true)
+}
+
+// Runs statements of migration and records new version in the same transaction
+func (r *Runner) run(ctx context.Context, migration Migration, statements []string, up bool) error {
+	record := `DELETE FROM ` + versionTable + ` WHERE version_id = $1`
+	if up {
+		record = `INSERT INTO ` + versionTable + ` (version_id, is_applied) VALUES ($1, TRUE)`
+	}
+
+	if migration.noTx {
+		// Single connection keeps session settings such as PRAGMA between statements
+		conn, err := r.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("error getting connection for migration %s: %v", migration.Name, err)
+		}
+		defer conn.Close()
+
+		for _, statement := range statements {
+			if _, err := conn.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("error running migration %s: %v", migration.Name, err)
+			}
+		}
+		if _, err := conn.ExecContext(ctx, record, migration.Version); err != nil {
+			return fmt.Errorf("error recording migration %s: %v", migration.Name, err)
+		}
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction for migration %s: %v", migration.Name, err)
+	}
+	defer tx.Rollback()
+
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("error running migration %s: %v", migration.Name, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, record, migration.Version); err != nil {
+		return fmt.Errorf("error recording migration %s: %v", migration.Name, err)
+	}
+
+	return tx.Commit()
+}
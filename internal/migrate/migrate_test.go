@@ -0,0 +1,224 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "modernc.org/sqlite"
+)
+
+var testMigrations = fstest.MapFS{
+	"schema/001_users.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+
+-- +goose Down
+DROP TABLE users;
+`)},
+	"schema/002_feeds.sql": {Data: []byte(`-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE feeds (
+    id INTEGER PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id)
+);
+-- +goose StatementEnd
+CREATE INDEX feeds_user_id ON feeds (user_id);
+
+-- +goose Down
+DROP TABLE feeds;
+`)},
+	"schema/003_pragma.sql": {Data: []byte(`-- +goose NO TRANSACTION
+-- +goose Up
+PRAGMA foreign_keys = OFF;
+ALTER TABLE users ADD COLUMN email TEXT;
+PRAGMA foreign_keys = ON;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN email;
+`)},
+	"schema/README.md": {Data: []byte("not a migration")},
+}
+
+func newTestRunner(t *testing.T, fsys fstest.MapFS) (*Runner, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite", "file:"+filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, err := Load(fsys, "schema")
+	if err != nil {
+		t.Fatalf("error loading migrations: %v", err)
+	}
+	return NewRunner(db, SQLite, migrations), db
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count); err != nil {
+		t.Fatalf("error checking table %s: %v", name, err)
+	}
+	return count > 0
+}
+
+func migrationNames(migrations []Migration) []string {
+	names := []string{}
+	for _, migration := range migrations {
+		names = append(names, migration.Name)
+	}
+	return names
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		files   fstest.MapFS
+		wantErr string
+	}{
+		{"no version", fstest.MapFS{"schema/users.sql": {Data: []byte("-- +goose Up\n")}}, "version number"},
+		{"same version", fstest.MapFS{
+			"schema/001_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+			"schema/01_b.sql":  {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+		}, "same version"},
+		{"no up", fstest.MapFS{"schema/001_a.sql": {Data: []byte("CREATE TABLE a (id INTEGER);\n")}}, "missing Up"},
+		{"open block", fstest.MapFS{"schema/001_a.sql": {Data: []byte("-- +goose Up\n-- +goose StatementBegin\nSELECT 1;\n")}}, "StatementEnd"},
+		{"no semicolon", fstest.MapFS{"schema/001_a.sql": {Data: []byte("-- +goose Up\nSELECT 1\n")}}, "semicolon"},
+		{"unknown annotation", fstest.MapFS{"schema/001_a.sql": {Data: []byte("-- +goose Sideways\n")}}, "unknown annotation"},
+	}
+
+	for _, tc := range cases {
+		if _, err := Load(tc.files, "schema"); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: loading returned %v, want error with %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestPendingIsReadOnly(t *testing.T) {
+	ctx := context.Background()
+	runner, db := newTestRunner(t, testMigrations)
+
+	pending, err := runner.Pending(ctx)
+	if err != nil {
+		t.Fatalf("error getting pending migrations: %v", err)
+	}
+	if got := strings.Join(migrationNames(pending), ","); got != "001_users.sql,002_feeds.sql,003_pragma.sql" {
+		t.Errorf("pending on empty database is %s, want all migrations", got)
+	}
+	statuses, err := runner.Statuses(ctx)
+	if err != nil || len(statuses) != 3 || statuses[0].Applied {
+		t.Errorf("statuses on empty database are %+v (%v), want all unapplied", statuses, err)
+	}
+	if tableExists(t, db, versionTable) {
+		t.Errorf("checking pending migrations created %s table", versionTable)
+	}
+}
+
+func TestUpDownRedo(t *testing.T) {
+	ctx := context.Background()
+	runner, db := newTestRunner(t, testMigrations)
+
+	applied, err := runner.Up(ctx)
+	if err != nil || len(applied) != 3 {
+		t.Fatalf("up applied %v (%v), want all migrations", migrationNames(applied), err)
+	}
+	if _, err := db.Exec("INSERT INTO users (id, name, email) VALUES (1, 'alice', 'alice@example.com')"); err != nil {
+		t.Fatalf("schema is not migrated: %v", err)
+	}
+	if applied, err := runner.Up(ctx); err != nil || len(applied) != 0 {
+		t.Errorf("second up applied %v (%v), want nothing", migrationNames(applied), err)
+	}
+
+	migration, ok, err := runner.Down(ctx)
+	if err != nil || !ok || migration.Name != "003_pragma.sql" {
+		t.Fatalf("down rolled back %s (%t, %v), want latest migration", migration.Name, ok, err)
+	}
+	statuses, err := runner.Statuses(ctx)
+	if err != nil {
+		t.Fatalf("error getting statuses: %v", err)
+	}
+	for i, want := range []bool{true, true, false} {
+		if statuses[i].Applied != want {
+			t.Errorf("%s applied is %t, want %t", statuses[i].Migration.Name, statuses[i].Applied, want)
+		}
+	}
+	if !statuses[0].Applied || statuses[0].AppliedAt.IsZero() {
+		t.Errorf("applied migration %s has no time of application", statuses[0].Migration.Name)
+	}
+
+	// redo leaves later pending migration alone
+	migration, err = runner.Redo(ctx)
+	if err != nil || migration.Name != "002_feeds.sql" {
+		t.Fatalf("redo ran %s (%v), want 002_feeds.sql", migration.Name, err)
+	}
+	pending, err := runner.Pending(ctx)
+	if got := strings.Join(migrationNames(pending), ","); err != nil || got != "003_pragma.sql" {
+		t.Errorf("pending after redo is %s (%v), want 003_pragma.sql", got, err)
+	}
+
+	for _, want := range []string{"002_feeds.sql", "001_users.sql"} {
+		if migration, ok, err := runner.Down(ctx); err != nil || !ok || migration.Name != want {
+			t.Fatalf("down rolled back %s (%t, %v), want %s", migration.Name, ok, err, want)
+		}
+	}
+	if _, ok, err := runner.Down(ctx); err != nil || ok {
+		t.Errorf("down on empty schema returned %t (%v), want nothing rolled back", ok, err)
+	}
+	if _, err := runner.Redo(ctx); err == nil {
+		t.Error("redo on empty schema succeeded")
+	}
+	if tableExists(t, db, "users") {
+		t.Error("users table is left after rolling back all migrations")
+	}
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	ctx := context.Background()
+	files := fstest.MapFS{
+		"schema/001_users.sql": testMigrations["schema/001_users.sql"],
+		"schema/002_broken.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE posts (id INTEGER PRIMARY KEY);
+INSERT INTO missing_table VALUES (1);
+
+-- +goose Down
+DROP TABLE posts;
+`)},
+	}
+	runner, db := newTestRunner(t, files)
+
+	applied, err := runner.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "002_broken.sql") {
+		t.Fatalf("up returned %v, want error naming broken migration", err)
+	}
+	if got := strings.Join(migrationNames(applied), ","); got != "001_users.sql" {
+		t.Errorf("up reported %s applied, want 001_users.sql", got)
+	}
+	if tableExists(t, db, "posts") {
+		t.Error("statements of failed migration were kept")
+	}
+	pending, err := runner.Pending(ctx)
+	if err != nil || len(pending) != 1 || pending[0].Name != "002_broken.sql" {
+		t.Errorf("pending after failure is %v (%v), want broken migration", migrationNames(pending), err)
+	}
+}
+
+// Version table written by goose CLI, rollbacks recorded as rows with is_applied false
+func TestGooseVersionTable(t *testing.T) {
+	ctx := context.Background()
+	runner, db := newTestRunner(t, testMigrations)
+	if _, err := db.Exec(SQLite.versionTableDDL()); err != nil {
+		t.Fatalf("error creating version table: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO ` + versionTable + ` (version_id, is_applied) VALUES (0, 1), (1, 1), (2, 1), (2, 0)`); err != nil {
+		t.Fatalf("error recording versions: %v", err)
+	}
+
+	pending, err := runner.Pending(ctx)
+	if got := strings.Join(migrationNames(pending), ","); err != nil || got != "002_feeds.sql,003_pragma.sql" {
+		t.Errorf("pending is %s (%v), want versions after rolled back 2", got, err)
+	}
+}
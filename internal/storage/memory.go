@@ -0,0 +1,1861 @@
+package storage
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/google/uuid"
+)
+
+const Memory Backend = "memory"
+
+// Returned by memory store where Postgres would fail on a constraint
+var (
+	ErrUniqueViolation     = errors.New("duplicate key value violates unique constraint")
+	ErrForeignKeyViolation = errors.New("insert or update violates foreign key constraint")
+	ErrCheckViolation      = errors.New("new row violates check constraint")
+)
+
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("%w %q", ErrUniqueViolation, constraint)
+}
+
+func foreignKeyViolation(constraint string) error {
+	return fmt.Errorf("%w %q", ErrForeignKeyViolation, constraint)
+}
+
+func checkViolation(constraint string) error {
+	return fmt.Errorf("%w %q", ErrCheckViolation, constraint)
+}
+
+type memoryData struct {
+	users        map[uuid.UUID]database.User
+	feeds        map[uuid.UUID]database.Feed
+	follows      map[uuid.UUID]database.FeedFollow
+	posts        map[uuid.UUID]database.Post
+	fetchRuns    map[uuid.UUID]database.FetchRun
+	aggInstances map[uuid.UUID]database.AggInstance
+	fetchQueue   map[uuid.UUID]database.FetchQueue
+	credentials  map[uuid.UUID]database.FeedCredential
+	starred      map[starKey]database.StarredPost
+	auditLog     map[uuid.UUID]database.AuditLog
+	apiTokens    map[uuid.UUID]database.ApiToken
+}
+
+type starKey struct {
+	userID uuid.UUID
+	postID uuid.UUID
+}
+
+func newMemoryData() *memoryData {
+	return &memoryData{
+		users:        map[uuid.UUID]database.User{},
+		feeds:        map[uuid.UUID]database.Feed{},
+		follows:      map[uuid.UUID]database.FeedFollow{},
+		posts:        map[uuid.UUID]database.Post{},
+		fetchRuns:    map[uuid.UUID]database.FetchRun{},
+		aggInstances: map[uuid.UUID]database.AggInstance{},
+		fetchQueue:   map[uuid.UUID]database.FetchQueue{},
+		credentials:  map[uuid.UUID]database.FeedCredential{},
+		starred:      map[starKey]database.StarredPost{},
+		auditLog:     map[uuid.UUID]database.AuditLog{},
+		apiTokens:    map[uuid.UUID]database.ApiToken{},
+	}
+}
+
+func (d *memoryData) clone() *memoryData {
+	credentials := map[uuid.UUID]database.FeedCredential{}
+	for id, credential := range d.credentials {
+		credential.Sealed = slices.Clone(credential.Sealed)
+		credentials[id] = credential
+	}
+	return &memoryData{
+		users:        maps.Clone(d.users),
+		feeds:        maps.Clone(d.feeds),
+		follows:      maps.Clone(d.follows),
+		posts:        maps.Clone(d.posts),
+		fetchRuns:    maps.Clone(d.fetchRuns),
+		aggInstances: maps.Clone(d.aggInstances),
+		fetchQueue:   maps.Clone(d.fetchQueue),
+		credentials:  credentials,
+		starred:      maps.Clone(d.starred),
+		auditLog:     maps.Clone(d.auditLog),
+		apiTokens:    maps.Clone(d.apiTokens),
+	}
+}
+
+// Store keeping everything in process memory, meant for tests of handlers.
+// Mirrors constraints of the Postgres schema: unique keys, foreign keys with
+// their ON DELETE actions and ordering of NULLs
+type memoryStore struct {
+	mu   *sync.Mutex
+	data *memoryData
+	// set for store bound to transaction by InTx, mutex is then already held
+	inTx bool
+}
+
+func NewMemory() Store {
+	return &memoryStore{mu: &sync.Mutex{}, data: newMemoryData()}
+}
+
+func (s *memoryStore) lock() func() {
+	if s.inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// Transaction works on a copy of data which replaces the original on commit.
+// Other callers wait until it finishes, like writers of SQLite
+func (s *memoryStore) InTx(ctx context.Context, fn func(q database.Querier) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	txStore := &memoryStore{mu: s.mu, data: s.data.clone(), inTx: true}
+	if err := fn(txStore); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.data = txStore.data
+	return nil
+}
+
+func (s *memoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *memoryStore) Backend() Backend {
+	return Memory
+}
+
+// Memory store has no connection and no migrations
+func (s *memoryStore) DB() *sql.DB {
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
+
+// Rows in order of creation, tables without ORDER BY have no defined order
+// but stable one keeps tests deterministic
+func sortedRows[Row any](rows map[uuid.UUID]Row, createdAt func(Row) time.Time, id func(Row) uuid.UUID) []Row {
+	sorted := slices.Collect(maps.Values(rows))
+	slices.SortFunc(sorted, func(a, b Row) int {
+		idA, idB := id(a), id(b)
+		return cmp.Or(createdAt(a).Compare(createdAt(b)), slices.Compare(idA[:], idB[:]))
+	})
+	return sorted
+}
+
+func (d *memoryData) sortedFeeds() []database.Feed {
+	return sortedRows(d.feeds, func(f database.Feed) time.Time { return f.CreatedAt }, func(f database.Feed) uuid.UUID { return f.ID })
+}
+
+// Compares nullable times with NULL sorting as larger than any value like Postgres does,
+// so NULLs come last in ascending and first in descending order
+func compareNullTime(a, b sql.NullTime) int {
+	switch {
+	case !a.Valid && !b.Valid:
+		return 0
+	case !a.Valid:
+		return 1
+	case !b.Valid:
+		return -1
+	}
+	return a.Time.Compare(b.Time)
+}
+
+func (d *memoryData) deleteUser(id uuid.UUID) {
+	delete(d.users, id)
+	for _, feed := range d.feeds {
+		if feed.UserID == id {
+			d.deleteFeed(feed.ID)
+		}
+	}
+	for followID, follow := range d.follows {
+		if follow.UserID == id {
+			delete(d.follows, followID)
+		}
+	}
+	for key := range d.starred {
+		if key.userID == id {
+			delete(d.starred, key)
+		}
+	}
+	for queueID, queued := range d.fetchQueue {
+		if queued.RequestedBy.Valid && queued.RequestedBy.UUID == id {
+			queued.RequestedBy = uuid.NullUUID{}
+			d.fetchQueue[queueID] = queued
+		}
+	}
+	for tokenID, token := range d.apiTokens {
+		if token.UserID == id {
+			delete(d.apiTokens, tokenID)
+		}
+	}
+	for entryID, entry := range d.auditLog {
+		if entry.UserID.Valid && entry.UserID.UUID == id {
+			entry.UserID = uuid.NullUUID{}
+			d.auditLog[entryID] = entry
+		}
+	}
+}
+
+// Name of unique constraint new token would break, empty when there is none
+func (d *memoryData) apiTokenConflict(token database.ApiToken) string {
+	if _, ok := d.apiTokens[token.ID]; ok {
+		return "api_tokens_pkey"
+	}
+	for _, stored := range d.apiTokens {
+		if bytes.Equal(stored.TokenHash, token.TokenHash) {
+			return "api_tokens_token_hash_key"
+		}
+		if stored.UserID == token.UserID && stored.Name == token.Name {
+			return "api_tokens_user_id_name_key"
+		}
+	}
+	return ""
+}
+
+func (d *memoryData) userByName(name string) (database.User, bool) {
+	for _, user := range d.users {
+		if user.Name == name {
+			return user, true
+		}
+	}
+	return database.User{}, false
+}
+
+func (d *memoryData) deleteFeed(id uuid.UUID) {
+	delete(d.feeds, id)
+	delete(d.credentials, id)
+	for followID, follow := range d.follows {
+		if follow.FeedID == id {
+			delete(d.follows, followID)
+		}
+	}
+	for postID, post := range d.posts {
+		if post.FeedID == id {
+			d.deletePost(postID)
+		}
+	}
+	for runID, run := range d.fetchRuns {
+		if run.FeedID == id {
+			delete(d.fetchRuns, runID)
+		}
+	}
+	for queueID, queued := range d.fetchQueue {
+		if queued.FeedID == id {
+			delete(d.fetchQueue, queueID)
+		}
+	}
+}
+
+func (d *memoryData) deletePost(id uuid.UUID) {
+	delete(d.posts, id)
+	for key := range d.starred {
+		if key.postID == id {
+			delete(d.starred, key)
+		}
+	}
+}
+
+// Posts removed by retention policies: older than max age of their feed or the
+// global one, or beyond max count of newest posts of feed. Starred posts are exempt
+// and do not count towards max count
+func (d *memoryData) prunablePosts(createdBefore sql.NullTime) []database.Post {
+	starred := map[uuid.UUID]bool{}
+	for key := range d.starred {
+		starred[key.postID] = true
+	}
+
+	byFeed := map[uuid.UUID][]database.Post{}
+	for _, post := range d.posts {
+		if !starred[post.ID] {
+			byFeed[post.FeedID] = append(byFeed[post.FeedID], post)
+		}
+	}
+
+	now := time.Now()
+	var prunable []database.Post
+	for feedID, posts := range byFeed {
+		feed := d.feeds[feedID]
+		slices.SortFunc(posts, func(a, b database.Post) int {
+			return postedAt(b).Compare(postedAt(a))
+		})
+
+		cutoff := createdBefore
+		if feed.RetentionMaxAgeSeconds.Valid {
+			cutoff = sql.NullTime{Time: now.Add(-time.Duration(feed.RetentionMaxAgeSeconds.Int32) * time.Second), Valid: true}
+		}
+		for i, post := range posts {
+			tooOld := cutoff.Valid && post.CreatedAt.Before(cutoff.Time)
+			tooMany := feed.RetentionMaxPosts.Valid && i+1 > int(feed.RetentionMaxPosts.Int32)
+			if tooOld || tooMany {
+				prunable = append(prunable, post)
+			}
+		}
+	}
+	return prunable
+}
+
+// Counts of posts per feed ordered by feed name
+func (d *memoryData) countByFeed(posts []database.Post) []database.GetPrunablePostCountsRow {
+	counts := map[uuid.UUID]int64{}
+	for _, post := range posts {
+		counts[post.FeedID]++
+	}
+	var rows []database.GetPrunablePostCountsRow
+	for feedID, count := range counts {
+		feed := d.feeds[feedID]
+		rows = append(rows, database.GetPrunablePostCountsRow{FeedName: feed.Name, FeedUrl: feed.Url, Posts: count})
+	}
+	slices.SortFunc(rows, func(a, b database.GetPrunablePostCountsRow) int {
+		return cmp.Compare(a.FeedName, b.FeedName)
+	})
+	return rows
+}
+
+// COALESCE(published_at, created_at)
+func postedAt(post database.Post) time.Time {
+	if post.PublishedAt.Valid {
+		return post.PublishedAt.Time
+	}
+	return post.CreatedAt
+}
+
+func (d *memoryData) deleteAggInstance(id uuid.UUID) {
+	delete(d.aggInstances, id)
+	for feedID, feed := range d.feeds {
+		if feed.ClaimedBy.Valid && feed.ClaimedBy.UUID == id {
+			feed.ClaimedBy = uuid.NullUUID{}
+			d.feeds[feedID] = feed
+		}
+	}
+}
+
+func (d *memoryData) feedByUrl(url string) (database.Feed, bool) {
+	for _, feed := range d.feeds {
+		if feed.Url == url {
+			return feed, true
+		}
+	}
+	return database.Feed{}, false
+}
+
+func isClaimable(feed database.Feed, now time.Time) bool {
+	return !feed.ClaimedBy.Valid || (feed.ClaimedUntil.Valid && feed.ClaimedUntil.Time.Before(now))
+}
+
+func isDue(feed database.Feed, now time.Time) bool {
+	return !feed.DisabledAt.Valid &&
+		(!feed.NextFetchAt.Valid || !feed.NextFetchAt.Time.After(now)) &&
+		isClaimable(feed, now)
+}
+
+// Marks feeds as claimed by instance for lease, instance has to exist like the foreign key requires
+func (d *memoryData) claim(feeds []database.Feed, instanceID uuid.NullUUID, leaseSeconds int32) ([]database.Feed, error) {
+	if instanceID.Valid {
+		if _, ok := d.aggInstances[instanceID.UUID]; !ok {
+			return nil, foreignKeyViolation("feeds_claimed_by_fkey")
+		}
+	}
+
+	now := time.Now()
+	var claimed []database.Feed
+	for _, feed := range feeds {
+		feed.ClaimedBy = instanceID
+		feed.ClaimedUntil = sql.NullTime{Time: now.Add(time.Duration(leaseSeconds) * time.Second), Valid: true}
+		feed.LastFetchedAt = sql.NullTime{Time: now, Valid: true}
+		feed.UpdatedAt = now
+		d.feeds[feed.ID] = feed
+		claimed = append(claimed, feed)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) ClaimActiveFeeds(ctx context.Context, arg database.ClaimActiveFeedsParams) ([]database.Feed, error) {
+	defer s.lock()()
+
+	now := time.Now()
+	var active []database.Feed
+	for _, feed := range s.data.sortedFeeds() {
+		if !feed.DisabledAt.Valid && isClaimable(feed, now) {
+			active = append(active, feed)
+		}
+	}
+	return s.data.claim(active, arg.InstanceID, arg.LeaseSeconds)
+}
+
+func (s *memoryStore) ClaimDueFeeds(ctx context.Context, arg database.ClaimDueFeedsParams) ([]database.Feed, error) {
+	defer s.lock()()
+
+	now := time.Now()
+	var due []database.Feed
+	for _, feed := range s.data.sortedFeeds() {
+		if isDue(feed, now) && (!arg.ScheduledOnly || feed.CronSchedule.Valid) {
+			due = append(due, feed)
+		}
+	}
+	// ORDER BY next_fetch_at ASC NULLS FIRST
+	slices.SortStableFunc(due, func(a, b database.Feed) int {
+		if a.NextFetchAt.Valid != b.NextFetchAt.Valid {
+			return compareNullTime(b.NextFetchAt, a.NextFetchAt)
+		}
+		return compareNullTime(a.NextFetchAt, b.NextFetchAt)
+	})
+	if len(due) > int(arg.MaxFeeds) {
+		due = due[:max(arg.MaxFeeds, 0)]
+	}
+	return s.data.claim(due, arg.InstanceID, arg.LeaseSeconds)
+}
+
+func (s *memoryStore) ClaimFeed(ctx context.Context, arg database.ClaimFeedParams) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feeds[arg.ID]
+	if !ok || !isClaimable(feed, time.Now()) {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	claimed, err := s.data.claim([]database.Feed{feed}, arg.InstanceID, arg.LeaseSeconds)
+	if err != nil {
+		return database.Feed{}, err
+	}
+	return claimed[0], nil
+}
+
+func (s *memoryStore) ClaimQueuedFeeds(ctx context.Context, arg database.ClaimQueuedFeedsParams) ([]database.Feed, error) {
+	defer s.lock()()
+
+	queue := slices.Collect(maps.Values(s.data.fetchQueue))
+	slices.SortFunc(queue, func(a, b database.FetchQueue) int {
+		return cmp.Or(cmp.Compare(b.Priority, a.Priority), a.RequestedAt.Compare(b.RequestedAt))
+	})
+
+	now := time.Now()
+	var queued []database.Feed
+	for _, entry := range queue {
+		if len(queued) >= int(arg.MaxFeeds) {
+			break
+		}
+		if feed := s.data.feeds[entry.FeedID]; isClaimable(feed, now) {
+			queued = append(queued, feed)
+		}
+	}
+
+	claimed, err := s.data.claim(queued, arg.InstanceID, arg.LeaseSeconds)
+	if err != nil {
+		return nil, err
+	}
+	for _, feed := range claimed {
+		for queueID, entry := range s.data.fetchQueue {
+			if entry.FeedID == feed.ID {
+				delete(s.data.fetchQueue, queueID)
+			}
+		}
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) CountLiveAggDaemons(ctx context.Context, staleSeconds int32) (int64, error) {
+	defer s.lock()()
+
+	cutoff := time.Now().Add(-time.Duration(staleSeconds) * time.Second)
+	var count int64
+	for _, instance := range s.data.aggInstances {
+		if instance.Daemon && !instance.HeartbeatAt.Before(cutoff) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStore) CountOverdueFeeds(ctx context.Context) (int64, error) {
+	defer s.lock()()
+
+	now := time.Now()
+	var count int64
+	for _, feed := range s.data.feeds {
+		if isDue(feed, now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *memoryStore) CountQueuedFeeds(ctx context.Context) (int64, error) {
+	defer s.lock()()
+
+	return int64(len(s.data.fetchQueue)), nil
+}
+
+func (s *memoryStore) CountUsers(ctx context.Context) (int64, error) {
+	defer s.lock()()
+
+	return int64(len(s.data.users)), nil
+}
+
+func (s *memoryStore) CreateApiToken(ctx context.Context, arg database.CreateApiTokenParams) (database.ApiToken, error) {
+	defer s.lock()()
+
+	token := database.ApiToken(arg)
+	token.TokenHash = slices.Clone(arg.TokenHash)
+	if constraint := s.data.apiTokenConflict(token); constraint != "" {
+		return database.ApiToken{}, uniqueViolation(constraint)
+	}
+	if _, ok := s.data.users[arg.UserID]; !ok {
+		return database.ApiToken{}, foreignKeyViolation("api_tokens_user_id_fkey")
+	}
+
+	s.data.apiTokens[token.ID] = token
+	return token, nil
+}
+
+func (s *memoryStore) CreateAuditEntry(ctx context.Context, arg database.CreateAuditEntryParams) error {
+	defer s.lock()()
+
+	if _, ok := s.data.auditLog[arg.ID]; ok {
+		return uniqueViolation("audit_log_pkey")
+	}
+
+	entry := database.AuditLog{
+		ID:         arg.ID,
+		CreatedAt:  arg.CreatedAt,
+		UserName:   arg.UserName,
+		Command:    arg.Command,
+		Args:       arg.Args,
+		Succeeded:  arg.Succeeded,
+		Error:      arg.Error,
+		DurationMs: arg.DurationMs,
+	}
+	// user deleted by the audited command is not linked
+	if _, ok := s.data.users[arg.UserID.UUID]; arg.UserID.Valid && ok {
+		entry.UserID = arg.UserID
+	}
+	s.data.auditLog[entry.ID] = entry
+	return nil
+}
+
+func (s *memoryStore) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.feeds[arg.ID]; ok {
+		return database.Feed{}, uniqueViolation("feeds_pkey")
+	}
+	for _, feed := range s.data.feeds {
+		if feed.UserID == arg.UserID && feed.Name == arg.Name {
+			return database.Feed{}, uniqueViolation("feeds_user_id_name_key")
+		}
+		if feed.Url == arg.Url {
+			return database.Feed{}, uniqueViolation("feeds_url_key")
+		}
+	}
+	if _, ok := s.data.users[arg.UserID]; !ok {
+		return database.Feed{}, foreignKeyViolation("feeds_user_id_fkey")
+	}
+
+	feed := database.Feed{
+		ID:                      arg.ID,
+		CreatedAt:               arg.CreatedAt,
+		UpdatedAt:               arg.UpdatedAt,
+		Name:                    arg.Name,
+		Url:                     arg.Url,
+		UserID:                  arg.UserID,
+		FetchIntervalSeconds:    1800,
+		MinFetchIntervalSeconds: 300,
+		MaxFetchIntervalSeconds: 86400,
+	}
+	s.data.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (s *memoryStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.follows[arg.ID]; ok {
+		return database.CreateFeedFollowRow{}, uniqueViolation("feed_follows_pkey")
+	}
+	for _, follow := range s.data.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == arg.FeedID {
+			return database.CreateFeedFollowRow{}, uniqueViolation("feed_follows_user_id_feed_id_key")
+		}
+	}
+	user, userOk := s.data.users[arg.UserID]
+	if !userOk {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows_user_id_fkey")
+	}
+	feed, feedOk := s.data.feeds[arg.FeedID]
+	if !feedOk {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows_feed_id_fkey")
+	}
+
+	s.data.follows[arg.ID] = database.FeedFollow(arg)
+	return database.CreateFeedFollowRow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+		FeedName:  feed.Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (s *memoryStore) CreateFetchRun(ctx context.Context, arg database.CreateFetchRunParams) (database.FetchRun, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.fetchRuns[arg.ID]; ok {
+		return database.FetchRun{}, uniqueViolation("fetch_runs_pkey")
+	}
+	if _, ok := s.data.feeds[arg.FeedID]; !ok {
+		return database.FetchRun{}, foreignKeyViolation("fetch_runs_feed_id_fkey")
+	}
+
+	run := database.FetchRun(arg)
+	s.data.fetchRuns[run.ID] = run
+	return run, nil
+}
+
+// Like INSERT ... ON CONFLICT DO NOTHING, posts clashing with stored ones or
+// earlier items of the batch on title or url are skipped
+func (s *memoryStore) CreatePosts(ctx context.Context, arg database.CreatePostsParams) ([]database.Post, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.feeds[arg.FeedID]; !ok && len(arg.Titles) > 0 {
+		return nil, foreignKeyViolation("posts_feed_id_fkey")
+	}
+
+	var created []database.Post
+	for i := range arg.Titles {
+		publishedAt := sql.NullTime{}
+		if arg.PublishedAts[i] != "" {
+			parsed, parseErr := time.Parse(time.RFC3339Nano, arg.PublishedAts[i])
+			if parseErr != nil {
+				return nil, fmt.Errorf("invalid input syntax for type timestamp: %q", arg.PublishedAts[i])
+			}
+			// ::timestamptz AT TIME ZONE 'UTC'
+			publishedAt = sql.NullTime{Time: parsed.UTC(), Valid: true}
+		}
+
+		conflict := false
+		for _, post := range s.data.posts {
+			if post.FeedID == arg.FeedID && post.Url == arg.Urls[i] {
+				conflict = true
+				break
+			}
+		}
+		if conflict {
+			continue
+		}
+
+		post := database.Post{
+			ID:          uuid.New(),
+			CreatedAt:   arg.CreatedAt,
+			UpdatedAt:   arg.CreatedAt,
+			Title:       arg.Titles[i],
+			Url:         arg.Urls[i],
+			Description: sql.NullString{String: arg.Descriptions[i], Valid: arg.Descriptions[i] != ""},
+			PublishedAt: publishedAt,
+			FeedID:      arg.FeedID,
+			Content:     sql.NullString{String: arg.Contents[i], Valid: arg.Contents[i] != ""},
+			OriginalUrl: arg.OriginalUrls[i],
+		}
+		s.data.posts[post.ID] = post
+		created = append(created, post)
+	}
+	return created, nil
+}
+
+func (s *memoryStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.users[arg.ID]; ok {
+		return database.User{}, uniqueViolation("users_pkey")
+	}
+	for _, user := range s.data.users {
+		if user.Name == arg.Name {
+			return database.User{}, uniqueViolation("users_name_key")
+		}
+	}
+
+	user := database.User{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Role:      "member",
+	}
+	s.data.users[user.ID] = user
+	return user, nil
+}
+
+func (s *memoryStore) DeleteAggInstance(ctx context.Context, id uuid.UUID) error {
+	defer s.lock()()
+
+	s.data.deleteAggInstance(id)
+	return nil
+}
+
+func (s *memoryStore) DeleteApiToken(ctx context.Context, arg database.DeleteApiTokenParams) (int64, error) {
+	defer s.lock()()
+
+	var deleted int64
+	for id, token := range s.data.apiTokens {
+		if token.UserID == arg.UserID && token.Name == arg.Name {
+			delete(s.data.apiTokens, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.feeds[id]; !ok {
+		return 0, nil
+	}
+	s.data.deleteFeed(id)
+	return 1, nil
+}
+
+func (s *memoryStore) DeleteFeedCredentials(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.credentials[feedID]; !ok {
+		return 0, nil
+	}
+	delete(s.data.credentials, feedID)
+	return 1, nil
+}
+
+func (s *memoryStore) DeleteFeeds(ctx context.Context) error {
+	defer s.lock()()
+
+	for id := range s.data.feeds {
+		s.data.deleteFeed(id)
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteFeedsFollow(ctx context.Context, arg database.DeleteFeedsFollowParams) error {
+	defer s.lock()()
+
+	feed, ok := s.data.feedByUrl(arg.Url)
+	if !ok {
+		return nil
+	}
+	for id, follow := range s.data.follows {
+		if follow.UserID == arg.UserID && follow.FeedID == feed.ID {
+			delete(s.data.follows, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) DeleteFetchRunsBefore(ctx context.Context, startedAt time.Time) (int64, error) {
+	defer s.lock()()
+
+	var deleted int64
+	for id, run := range s.data.fetchRuns {
+		if run.StartedAt.Before(startedAt) {
+			delete(s.data.fetchRuns, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) DeleteStaleAggInstances(ctx context.Context, staleSeconds int32) (int64, error) {
+	defer s.lock()()
+
+	cutoff := time.Now().Add(-time.Duration(staleSeconds) * time.Second)
+	var deleted int64
+	for id, instance := range s.data.aggInstances {
+		if instance.HeartbeatAt.Before(cutoff) {
+			s.data.deleteAggInstance(id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.users[id]; !ok {
+		return 0, nil
+	}
+	s.data.deleteUser(id)
+	return 1, nil
+}
+
+func (s *memoryStore) DeleteUsers(ctx context.Context) error {
+	defer s.lock()()
+
+	for id := range s.data.users {
+		s.data.deleteUser(id)
+	}
+	return nil
+}
+
+func (s *memoryStore) EnableFeed(ctx context.Context, url string) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feedByUrl(url)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feed.DisabledAt = sql.NullTime{}
+	feed.ConsecutiveFailures = 0
+	feed.NextFetchAt = sql.NullTime{}
+	feed.UpdatedAt = time.Now()
+	s.data.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (s *memoryStore) EnqueueFeedFetch(ctx context.Context, arg database.EnqueueFeedFetchParams) error {
+	defer s.lock()()
+
+	if _, ok := s.data.feeds[arg.FeedID]; !ok {
+		return foreignKeyViolation("fetch_queue_feed_id_fkey")
+	}
+	if arg.RequestedBy.Valid {
+		if _, ok := s.data.users[arg.RequestedBy.UUID]; !ok {
+			return foreignKeyViolation("fetch_queue_requested_by_fkey")
+		}
+	}
+
+	// ON CONFLICT (feed_id) DO UPDATE keeps the higher priority
+	for id, queued := range s.data.fetchQueue {
+		if queued.FeedID == arg.FeedID {
+			queued.Priority = max(queued.Priority, arg.Priority)
+			s.data.fetchQueue[id] = queued
+			return nil
+		}
+	}
+	if _, ok := s.data.fetchQueue[arg.ID]; ok {
+		return uniqueViolation("fetch_queue_pkey")
+	}
+
+	s.data.fetchQueue[arg.ID] = database.FetchQueue(arg)
+	return nil
+}
+
+func (s *memoryStore) ExtendFeedClaims(ctx context.Context, arg database.ExtendFeedClaimsParams) (int64, error) {
+	defer s.lock()()
+
+	if !arg.InstanceID.Valid {
+		return 0, nil
+	}
+	var extended int64
+	for id, feed := range s.data.feeds {
+		if feed.ClaimedBy != arg.InstanceID {
+			continue
+		}
+		feed.ClaimedUntil = sql.NullTime{Time: time.Now().Add(time.Duration(arg.LeaseSeconds) * time.Second), Valid: true}
+		s.data.feeds[id] = feed
+		extended++
+	}
+	return extended, nil
+}
+
+func (s *memoryStore) GetAllApiTokens(ctx context.Context) ([]database.ApiToken, error) {
+	defer s.lock()()
+
+	tokens := slices.Collect(maps.Values(s.data.apiTokens))
+	slices.SortFunc(tokens, func(a, b database.ApiToken) int {
+		return cmp.Or(
+			slices.Compare(a.UserID[:], b.UserID[:]),
+			a.CreatedAt.Compare(b.CreatedAt),
+			strings.Compare(a.Name, b.Name),
+		)
+	})
+	return tokens, nil
+}
+
+func (s *memoryStore) GetAllFeedCredentials(ctx context.Context) ([]database.FeedCredential, error) {
+	defer s.lock()()
+
+	credentials := slices.Collect(maps.Values(s.data.credentials))
+	slices.SortFunc(credentials, func(a, b database.FeedCredential) int {
+		return slices.Compare(a.FeedID[:], b.FeedID[:])
+	})
+	for i := range credentials {
+		credentials[i].Sealed = slices.Clone(credentials[i].Sealed)
+	}
+	return credentials, nil
+}
+
+func (s *memoryStore) GetAllFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	defer s.lock()()
+
+	return sortedRows(s.data.follows, func(f database.FeedFollow) time.Time { return f.CreatedAt }, func(f database.FeedFollow) uuid.UUID { return f.ID }), nil
+}
+
+func (s *memoryStore) GetAllFeeds(ctx context.Context) ([]database.Feed, error) {
+	defer s.lock()()
+
+	feeds := slices.Collect(maps.Values(s.data.feeds))
+	slices.SortFunc(feeds, func(a, b database.Feed) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.Url, b.Url))
+	})
+	return feeds, nil
+}
+
+func (s *memoryStore) GetAllPosts(ctx context.Context) ([]database.GetAllPostsRow, error) {
+	defer s.lock()()
+
+	posts := slices.Collect(maps.Values(s.data.posts))
+	slices.SortFunc(posts, func(a, b database.Post) int {
+		return cmp.Or(slices.Compare(a.FeedID[:], b.FeedID[:]), a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.Url, b.Url))
+	})
+	rows := make([]database.GetAllPostsRow, 0, len(posts))
+	for _, post := range posts {
+		rows = append(rows, database.GetAllPostsRow{
+			ID:          post.ID,
+			CreatedAt:   post.CreatedAt,
+			UpdatedAt:   post.UpdatedAt,
+			Title:       post.Title,
+			Url:         post.Url,
+			Description: post.Description,
+			PublishedAt: post.PublishedAt,
+			FeedID:      post.FeedID,
+			Content:     post.Content,
+			OriginalUrl: post.OriginalUrl,
+		})
+	}
+	return rows, nil
+}
+
+func (s *memoryStore) GetAllStarredPosts(ctx context.Context) ([]database.StarredPost, error) {
+	defer s.lock()()
+
+	starred := slices.Collect(maps.Values(s.data.starred))
+	slices.SortFunc(starred, func(a, b database.StarredPost) int {
+		return cmp.Or(
+			a.CreatedAt.Compare(b.CreatedAt),
+			slices.Compare(a.UserID[:], b.UserID[:]),
+			slices.Compare(a.PostID[:], b.PostID[:]),
+		)
+	})
+	return starred, nil
+}
+
+func (s *memoryStore) GetApiTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	defer s.lock()()
+
+	var tokens []database.ApiToken
+	for _, token := range s.data.apiTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b database.ApiToken) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.Name, b.Name))
+	})
+	return tokens, nil
+}
+
+// Entries of user match by stored name or, for renamed users, by id
+func (s *memoryStore) GetAuditEntries(ctx context.Context, arg database.GetAuditEntriesParams) ([]database.AuditLog, error) {
+	defer s.lock()()
+
+	user, userFound := s.data.userByName(arg.UserName.String)
+	var entries []database.AuditLog
+	for _, entry := range s.data.auditLog {
+		if entry.CreatedAt.Before(arg.Since) {
+			continue
+		}
+		if arg.UserName.Valid && entry.UserName != arg.UserName &&
+			!(userFound && entry.UserID.Valid && entry.UserID.UUID == user.ID) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b database.AuditLog) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	if len(entries) > int(arg.MaxEntries) {
+		entries = entries[:max(arg.MaxEntries, 0)]
+	}
+	return entries, nil
+}
+
+func (s *memoryStore) GetBrokenFeeds(ctx context.Context) ([]database.Feed, error) {
+	defer s.lock()()
+
+	var broken []database.Feed
+	for _, feed := range s.data.sortedFeeds() {
+		if feed.DisabledAt.Valid || feed.ConsecutiveFailures > 0 {
+			broken = append(broken, feed)
+		}
+	}
+	slices.SortStableFunc(broken, func(a, b database.Feed) int {
+		return cmp.Or(cmp.Compare(b.ConsecutiveFailures, a.ConsecutiveFailures), cmp.Compare(a.Name, b.Name))
+	})
+	return broken, nil
+}
+
+func (s *memoryStore) GetFeedById(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feeds[id]
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (s *memoryStore) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feedByUrl(url)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (s *memoryStore) GetFeedCredentials(ctx context.Context, feedID uuid.UUID) ([]byte, error) {
+	defer s.lock()()
+
+	credential, ok := s.data.credentials[feedID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return slices.Clone(credential.Sealed), nil
+}
+
+func (s *memoryStore) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	defer s.lock()()
+
+	follows := sortedRows(s.data.follows, func(f database.FeedFollow) time.Time { return f.CreatedAt }, func(f database.FeedFollow) uuid.UUID { return f.ID })
+	var rows []database.GetFeedFollowsForUserRow
+	for _, follow := range follows {
+		if follow.UserID != userID {
+			continue
+		}
+		rows = append(rows, database.GetFeedFollowsForUserRow{
+			ID:        follow.ID,
+			CreatedAt: follow.CreatedAt,
+			UpdatedAt: follow.UpdatedAt,
+			UserID:    follow.UserID,
+			FeedID:    follow.FeedID,
+			FeedName:  s.data.feeds[follow.FeedID].Name,
+			UserName:  s.data.users[follow.UserID].Name,
+		})
+	}
+	return rows, nil
+}
+
+func (s *memoryStore) GetFeedPostId(ctx context.Context, arg database.GetFeedPostIdParams) (uuid.UUID, error) {
+	defer s.lock()()
+
+	for _, post := range s.data.posts {
+		if post.FeedID == arg.FeedID && post.Url == arg.Url {
+			return post.ID, nil
+		}
+	}
+	return uuid.UUID{}, sql.ErrNoRows
+}
+
+func (s *memoryStore) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	defer s.lock()()
+
+	var rows []database.GetFeedsRow
+	for _, feed := range s.data.sortedFeeds() {
+		rows = append(rows, database.GetFeedsRow{Name: feed.Name, Url: feed.Url, UserID: feed.UserID})
+	}
+	return rows, nil
+}
+
+func (s *memoryStore) GetFetchRuns(ctx context.Context, arg database.GetFetchRunsParams) ([]database.GetFetchRunsRow, error) {
+	defer s.lock()()
+
+	var rows []database.GetFetchRunsRow
+	for _, run := range s.data.fetchRuns {
+		feed := s.data.feeds[run.FeedID]
+		if (arg.FeedUrl.Valid && feed.Url != arg.FeedUrl.String) || run.StartedAt.Before(arg.Since) {
+			continue
+		}
+		rows = append(rows, database.GetFetchRunsRow{
+			ID:            run.ID,
+			FeedID:        run.FeedID,
+			StartedAt:     run.StartedAt,
+			FinishedAt:    run.FinishedAt,
+			HttpStatus:    run.HttpStatus,
+			Bytes:         run.Bytes,
+			ItemsSeen:     run.ItemsSeen,
+			ItemsInserted: run.ItemsInserted,
+			ItemsSkipped:  run.ItemsSkipped,
+			Error:         run.Error,
+			FeedName:      feed.Name,
+			FeedUrl:       feed.Url,
+		})
+	}
+	slices.SortFunc(rows, func(a, b database.GetFetchRunsRow) int {
+		return b.StartedAt.Compare(a.StartedAt)
+	})
+	if len(rows) > int(arg.MaxRuns) {
+		rows = rows[:max(arg.MaxRuns, 0)]
+	}
+	return rows, nil
+}
+
+func (s *memoryStore) GetOwnedFeeds(ctx context.Context, userID uuid.UUID) ([]database.GetOwnedFeedsRow, error) {
+	defer s.lock()()
+
+	var owned []database.Feed
+	for _, feed := range s.data.feeds {
+		if feed.UserID == userID {
+			owned = append(owned, feed)
+		}
+	}
+	slices.SortFunc(owned, func(a, b database.Feed) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.Name, b.Name))
+	})
+
+	var rows []database.GetOwnedFeedsRow
+	for _, feed := range owned {
+		row := database.GetOwnedFeedsRow{ID: feed.ID, Name: feed.Name, Url: feed.Url}
+		for _, follow := range s.data.follows {
+			if follow.FeedID == feed.ID && follow.UserID != feed.UserID {
+				row.OtherFollowers++
+			}
+		}
+		for _, post := range s.data.posts {
+			if post.FeedID == feed.ID {
+				row.Posts++
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (s *memoryStore) GetPostByUrl(ctx context.Context, arg database.GetPostByUrlParams) (database.Post, error) {
+	defer s.lock()()
+
+	// Earliest copy of the post wins, copies from feeds followed by the user go first
+	var found *database.Post
+	foundFollowed := false
+	posts := sortedRows(s.data.posts, func(p database.Post) time.Time { return p.CreatedAt }, func(p database.Post) uuid.UUID { return p.ID })
+	for _, post := range posts {
+		if post.Url != arg.Url {
+			continue
+		}
+		followed := s.data.isFollowing(arg.UserID, post.FeedID)
+		if found == nil || (followed && !foundFollowed) {
+			found = &post
+			foundFollowed = followed
+		}
+	}
+	if found == nil {
+		return database.Post{}, sql.ErrNoRows
+	}
+	return *found, nil
+}
+
+func (s *memoryStore) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) ([]database.Post, error) {
+	defer s.lock()()
+
+	posts := sortedRows(s.data.posts, func(p database.Post) time.Time { return p.CreatedAt }, func(p database.Post) uuid.UUID { return p.ID })
+	var userPosts []database.Post
+	for _, post := range posts {
+		if s.data.feeds[post.FeedID].UserID == arg.UserID {
+			userPosts = append(userPosts, post)
+		}
+	}
+	// ORDER BY published_at DESC puts posts without date first
+	slices.SortStableFunc(userPosts, func(a, b database.Post) int {
+		return compareNullTime(b.PublishedAt, a.PublishedAt)
+	})
+	if len(userPosts) > int(arg.Limit) {
+		userPosts = userPosts[:max(arg.Limit, 0)]
+	}
+	return userPosts, nil
+}
+
+func (s *memoryStore) GetPrunablePostCounts(ctx context.Context, createdBefore sql.NullTime) ([]database.GetPrunablePostCountsRow, error) {
+	defer s.lock()()
+
+	return s.data.countByFeed(s.data.prunablePosts(createdBefore)), nil
+}
+
+func (s *memoryStore) GetRecentPostTimes(ctx context.Context, arg database.GetRecentPostTimesParams) ([]time.Time, error) {
+	defer s.lock()()
+
+	var times []time.Time
+	for _, post := range s.data.posts {
+		if post.FeedID != arg.FeedID {
+			continue
+		}
+		postedAt := post.CreatedAt
+		if post.PublishedAt.Valid {
+			postedAt = post.PublishedAt.Time
+		}
+		times = append(times, postedAt)
+	}
+	slices.SortFunc(times, func(a, b time.Time) int {
+		return b.Compare(a)
+	})
+	if len(times) > int(arg.Limit) {
+		times = times[:max(arg.Limit, 0)]
+	}
+	return times, nil
+}
+
+func (s *memoryStore) GetStarredPosts(ctx context.Context, userID uuid.UUID) ([]database.GetStarredPostsRow, error) {
+	defer s.lock()()
+
+	var rows []database.GetStarredPostsRow
+	for key, star := range s.data.starred {
+		if key.userID != userID {
+			continue
+		}
+		post := s.data.posts[key.postID]
+		rows = append(rows, database.GetStarredPostsRow{
+			Title:       post.Title,
+			Url:         post.Url,
+			PublishedAt: post.PublishedAt,
+			FeedName:    s.data.feeds[post.FeedID].Name,
+			StarredAt:   star.CreatedAt,
+			Note:        star.Note,
+		})
+	}
+	slices.SortFunc(rows, func(a, b database.GetStarredPostsRow) int {
+		return b.StarredAt.Compare(a.StarredAt)
+	})
+	return rows, nil
+}
+
+func (s *memoryStore) GetUser(ctx context.Context, name string) (database.User, error) {
+	defer s.lock()()
+
+	if user, ok := s.data.userByName(name); ok {
+		return user, nil
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *memoryStore) GetUserActivity(ctx context.Context, userID uuid.UUID) (database.GetUserActivityRow, error) {
+	defer s.lock()()
+
+	var activity database.GetUserActivityRow
+	for _, follow := range s.data.follows {
+		if follow.UserID != userID {
+			continue
+		}
+		activity.Follows++
+		for _, post := range s.data.posts {
+			if post.FeedID == follow.FeedID {
+				activity.FollowedPosts++
+			}
+		}
+	}
+	for key := range s.data.starred {
+		if key.userID == userID {
+			activity.Starred++
+		}
+	}
+	for _, token := range s.data.apiTokens {
+		if token.UserID == userID {
+			activity.Tokens++
+		}
+	}
+	return activity, nil
+}
+
+func (s *memoryStore) GetUserByTokenHash(ctx context.Context, arg database.GetUserByTokenHashParams) (database.User, error) {
+	defer s.lock()()
+
+	for _, token := range s.data.apiTokens {
+		if !bytes.Equal(token.TokenHash, arg.TokenHash) {
+			continue
+		}
+		if token.ExpiresAt.Valid && !token.ExpiresAt.Time.After(arg.ValidAt) {
+			break
+		}
+		return s.data.users[token.UserID], nil
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *memoryStore) GetUsernameById(ctx context.Context, id uuid.UUID) (string, error) {
+	defer s.lock()()
+
+	user, ok := s.data.users[id]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return user.Name, nil
+}
+
+func (s *memoryStore) GetUsers(ctx context.Context) ([]database.User, error) {
+	defer s.lock()()
+
+	var users []database.User
+	for _, user := range sortedRows(s.data.users, func(u database.User) time.Time { return u.CreatedAt }, func(u database.User) uuid.UUID { return u.ID }) {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (s *memoryStore) HeartbeatAggInstance(ctx context.Context, arg database.HeartbeatAggInstanceParams) error {
+	defer s.lock()()
+
+	now := time.Now()
+	if instance, ok := s.data.aggInstances[arg.ID]; ok {
+		instance.HeartbeatAt = now
+		s.data.aggInstances[arg.ID] = instance
+		return nil
+	}
+	s.data.aggInstances[arg.ID] = database.AggInstance{
+		ID:          arg.ID,
+		Hostname:    arg.Hostname,
+		Pid:         arg.Pid,
+		StartedAt:   arg.StartedAt,
+		HeartbeatAt: now,
+		Daemon:      arg.Daemon,
+	}
+	return nil
+}
+
+// Like INSERT ... ON CONFLICT DO NOTHING without target, any unique clash skips the token
+func (s *memoryStore) ImportApiToken(ctx context.Context, arg database.ImportApiTokenParams) (int64, error) {
+	defer s.lock()()
+
+	token := database.ApiToken(arg)
+	token.TokenHash = slices.Clone(arg.TokenHash)
+	if s.data.apiTokenConflict(token) != "" {
+		return 0, nil
+	}
+	if _, ok := s.data.users[arg.UserID]; !ok {
+		return 0, foreignKeyViolation("api_tokens_user_id_fkey")
+	}
+
+	s.data.apiTokens[token.ID] = token
+	return 1, nil
+}
+
+func (s *memoryStore) ImportFeed(ctx context.Context, arg database.ImportFeedParams) (int64, error) {
+	defer s.lock()()
+
+	if _, ok := s.data.feedByUrl(arg.Url); ok {
+		return 0, nil
+	}
+	if _, ok := s.data.feeds[arg.ID]; ok {
+		return 0, uniqueViolation("feeds_pkey")
+	}
+	for _, feed := range s.data.feeds {
+		if feed.UserID == arg.UserID && feed.Name == arg.Name {
+			return 0, uniqueViolation("feeds_user_id_name_key")
+		}
+	}
+	if _, ok := s.data.users[arg.UserID]; !ok {
+		return 0, foreignKeyViolation("feeds_user_id_fkey")
+	}
+
+	s.data.feeds[arg.ID] = database.Feed{
+		ID:                      arg.ID,
+		CreatedAt:               arg.CreatedAt,
+		UpdatedAt:               arg.UpdatedAt,
+		Name:                    arg.Name,
+		Url:                     arg.Url,
+		UserID:                  arg.UserID,
+		LastFetchedAt:           arg.LastFetchedAt,
+		NextFetchAt:             arg.NextFetchAt,
+		FetchIntervalSeconds:    arg.FetchIntervalSeconds,
+		MinFetchIntervalSeconds: arg.MinFetchIntervalSeconds,
+		MaxFetchIntervalSeconds: arg.MaxFetchIntervalSeconds,
+		ConsecutiveFailures:     arg.ConsecutiveFailures,
+		LastError:               arg.LastError,
+		LastSucceededAt:         arg.LastSucceededAt,
+		DisabledAt:              arg.DisabledAt,
+		CronSchedule:            arg.CronSchedule,
+		RetentionMaxPosts:       arg.RetentionMaxPosts,
+		RetentionMaxAgeSeconds:  arg.RetentionMaxAgeSeconds,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) ImportFeedFollow(ctx context.Context, arg database.ImportFeedFollowParams) (int64, error) {
+	defer s.lock()()
+
+	if s.data.isFollowing(arg.UserID, arg.FeedID) {
+		return 0, nil
+	}
+	if _, ok := s.data.follows[arg.ID]; ok {
+		return 0, uniqueViolation("feed_follows_pkey")
+	}
+	if _, ok := s.data.users[arg.UserID]; !ok {
+		return 0, foreignKeyViolation("feed_follows_user_id_fkey")
+	}
+	if _, ok := s.data.feeds[arg.FeedID]; !ok {
+		return 0, foreignKeyViolation("feed_follows_feed_id_fkey")
+	}
+
+	s.data.follows[arg.ID] = database.FeedFollow(arg)
+	return 1, nil
+}
+
+func (s *memoryStore) ImportPost(ctx context.Context, arg database.ImportPostParams) (int64, error) {
+	defer s.lock()()
+
+	for _, post := range s.data.posts {
+		if post.FeedID == arg.FeedID && post.Url == arg.Url {
+			return 0, nil
+		}
+	}
+	if _, ok := s.data.posts[arg.ID]; ok {
+		return 0, uniqueViolation("posts_pkey")
+	}
+	if _, ok := s.data.feeds[arg.FeedID]; !ok {
+		return 0, foreignKeyViolation("posts_feed_id_fkey")
+	}
+
+	s.data.posts[arg.ID] = database.Post{
+		ID:          arg.ID,
+		CreatedAt:   arg.CreatedAt,
+		UpdatedAt:   arg.UpdatedAt,
+		Title:       arg.Title,
+		Url:         arg.Url,
+		Description: arg.Description,
+		PublishedAt: arg.PublishedAt,
+		FeedID:      arg.FeedID,
+		Content:     arg.Content,
+		OriginalUrl: arg.OriginalUrl,
+	}
+	return 1, nil
+}
+
+func (s *memoryStore) ImportStarredPost(ctx context.Context, arg database.ImportStarredPostParams) (int64, error) {
+	defer s.lock()()
+
+	key := starKey{userID: arg.UserID, postID: arg.PostID}
+	if _, ok := s.data.starred[key]; ok {
+		return 0, nil
+	}
+	if _, ok := s.data.users[arg.UserID]; !ok {
+		return 0, foreignKeyViolation("starred_posts_user_id_fkey")
+	}
+	if _, ok := s.data.posts[arg.PostID]; !ok {
+		return 0, foreignKeyViolation("starred_posts_post_id_fkey")
+	}
+
+	s.data.starred[key] = database.StarredPost(arg)
+	return 1, nil
+}
+
+func (s *memoryStore) PostExistsWithOriginalUrl(ctx context.Context, arg database.PostExistsWithOriginalUrlParams) (bool, error) {
+	defer s.lock()()
+
+	for _, post := range s.data.posts {
+		if post.FeedID == arg.FeedID && post.OriginalUrl == arg.OriginalUrl {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *memoryStore) PrunePosts(ctx context.Context, createdBefore sql.NullTime) ([]database.PrunePostsRow, error) {
+	defer s.lock()()
+
+	prunable := s.data.prunablePosts(createdBefore)
+	counts := s.data.countByFeed(prunable)
+	for _, post := range prunable {
+		s.data.deletePost(post.ID)
+	}
+	return convertAll(counts, func(row database.GetPrunablePostCountsRow) database.PrunePostsRow {
+		return database.PrunePostsRow(row)
+	}), nil
+}
+
+func (s *memoryStore) RecordFeedFailure(ctx context.Context, arg database.RecordFeedFailureParams) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feeds[arg.ID]
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	now := time.Now()
+	feed.ConsecutiveFailures++
+	feed.LastError = arg.LastError
+	feed.NextFetchAt = arg.NextFetchAt
+	if feed.ConsecutiveFailures >= arg.MaxFailures {
+		feed.DisabledAt = sql.NullTime{Time: now, Valid: true}
+	}
+	feed.UpdatedAt = now
+	s.data.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (s *memoryStore) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	defer s.lock()()
+
+	feed, ok := s.data.feeds[id]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	feed.ConsecutiveFailures = 0
+	feed.LastError = sql.NullString{}
+	feed.LastSucceededAt = sql.NullTime{Time: now, Valid: true}
+	feed.UpdatedAt = now
+	s.data.feeds[id] = feed
+	return nil
+}
+
+func (s *memoryStore) ReleaseFeedClaim(ctx context.Context, id uuid.UUID) error {
+	defer s.lock()()
+
+	feed, ok := s.data.feeds[id]
+	if !ok {
+		return nil
+	}
+	feed.ClaimedBy = uuid.NullUUID{}
+	feed.ClaimedUntil = sql.NullTime{}
+	s.data.feeds[id] = feed
+	return nil
+}
+
+func (s *memoryStore) RenameUser(ctx context.Context, arg database.RenameUserParams) (database.User, error) {
+	defer s.lock()()
+
+	user, ok := s.data.userByName(arg.Name)
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	if other, taken := s.data.userByName(arg.NewName); taken && other.ID != user.ID {
+		return database.User{}, uniqueViolation("users_name_key")
+	}
+	user.Name = arg.NewName
+	user.UpdatedAt = arg.UpdatedAt
+	s.data.users[user.ID] = user
+	return user, nil
+}
+
+func (s *memoryStore) ScheduleFeedFetch(ctx context.Context, arg database.ScheduleFeedFetchParams) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feeds[arg.ID]
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feed.FetchIntervalSeconds = arg.FetchIntervalSeconds
+	feed.NextFetchAt = arg.NextFetchAt
+	feed.UpdatedAt = time.Now()
+	s.data.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+// Approximates Postgres full text search: words match by prefix instead of
+// stemming and rank weights title, description and content like setweight A, B and C
+func (s *memoryStore) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	defer s.lock()()
+
+	alternatives := parseWebsearch(arg.Query)
+	var rows []database.SearchPostsRow
+	var postedAt []time.Time
+	for _, post := range s.data.posts {
+		feed := s.data.feeds[post.FeedID]
+		if arg.FeedUrl.Valid && feed.Url != arg.FeedUrl.String {
+			continue
+		}
+		posted := post.CreatedAt
+		if post.PublishedAt.Valid {
+			posted = post.PublishedAt.Time
+		}
+		if posted.Before(arg.Since) || !s.data.isFollowing(arg.UserID, post.FeedID) {
+			continue
+		}
+
+		fields := [][]string{searchWords(post.Title), searchWords(post.Description.String), searchWords(post.Content.String)}
+		rank, ok := matchPost(alternatives, fields)
+		if !ok {
+			continue
+		}
+		rows = append(rows, database.SearchPostsRow{
+			ID:          post.ID,
+			Title:       post.Title,
+			Url:         post.Url,
+			PublishedAt: post.PublishedAt,
+			FeedName:    feed.Name,
+			Rank:        rank,
+			Snippet:     searchSnippet(alternatives, post),
+		})
+		postedAt = append(postedAt, posted)
+	}
+
+	order := make([]int, len(rows))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return cmp.Or(cmp.Compare(rows[b].Rank, rows[a].Rank), postedAt[b].Compare(postedAt[a]))
+	})
+	sorted := make([]database.SearchPostsRow, 0, len(rows))
+	for _, i := range order {
+		sorted = append(sorted, rows[i])
+	}
+	if len(sorted) > int(arg.MaxResults) {
+		sorted = sorted[:max(arg.MaxResults, 0)]
+	}
+	return sorted, nil
+}
+
+func (d *memoryData) isFollowing(userID, feedID uuid.UUID) bool {
+	for _, follow := range d.follows {
+		if follow.UserID == userID && follow.FeedID == feedID {
+			return true
+		}
+	}
+	return false
+}
+
+// Weights of title, description and content
+var searchWeights = []float32{1.0, 0.4, 0.2}
+
+// Rank of post words split to fields, false when no alternative of query matches
+func matchPost(alternatives [][]searchTerm, fields [][]string) (float32, bool) {
+	var rank float32
+	matched := false
+	for _, terms := range alternatives {
+		var termsRank float32
+		allMatch := true
+		for _, term := range terms {
+			var termRank float32
+			for i, words := range fields {
+				termRank += float32(countPhrase(words, term.words)) * searchWeights[i]
+			}
+			if (termRank > 0) == term.negated {
+				allMatch = false
+				break
+			}
+			termsRank += termRank
+		}
+		if allMatch {
+			matched = true
+			rank = max(rank, termsRank)
+		}
+	}
+	return rank, matched
+}
+
+// Occurrences of phrase in words, each word of phrase matching by prefix
+func countPhrase(words, phrase []string) int {
+	count := 0
+	for start := 0; start+len(phrase) <= len(words); start++ {
+		if phraseAt(words, phrase, start) {
+			count++
+		}
+	}
+	return count
+}
+
+func phraseAt(words, phrase []string, start int) bool {
+	for i, word := range phrase {
+		if !strings.HasPrefix(words[start+i], word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Up to 20 words of post around first match with matched words marked like ts_headline does
+func searchSnippet(alternatives [][]searchTerm, post database.Post) string {
+	text := strings.Fields(strings.Join([]string{post.Title, post.Description.String, post.Content.String}, " "))
+
+	marked := make([]bool, len(text))
+	first := -1
+	for i, word := range text {
+		for _, terms := range alternatives {
+			for _, term := range terms {
+				if term.negated {
+					continue
+				}
+				for _, termWord := range term.words {
+					for _, textWord := range searchWords(word) {
+						if strings.HasPrefix(textWord, termWord) {
+							marked[i] = true
+						}
+					}
+				}
+			}
+		}
+		if marked[i] && first < 0 {
+			first = i
+		}
+	}
+
+	start := max(first-5, 0)
+	end := min(start+20, len(text))
+	snippet := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		if marked[i] {
+			snippet = append(snippet, "**"+text[i]+"**")
+		} else {
+			snippet = append(snippet, text[i])
+		}
+	}
+	return strings.Join(snippet, " ")
+}
+
+func (s *memoryStore) SetFeedCredentials(ctx context.Context, arg database.SetFeedCredentialsParams) error {
+	defer s.lock()()
+
+	if _, ok := s.data.feeds[arg.FeedID]; !ok {
+		return foreignKeyViolation("feed_credentials_feed_id_fkey")
+	}
+
+	credential, ok := s.data.credentials[arg.FeedID]
+	if !ok {
+		credential = database.FeedCredential{FeedID: arg.FeedID, CreatedAt: arg.CreatedAt}
+	}
+	credential.UpdatedAt = arg.UpdatedAt
+	credential.Sealed = slices.Clone(arg.Sealed)
+	s.data.credentials[arg.FeedID] = credential
+	return nil
+}
+
+func (s *memoryStore) SetFeedFetchBounds(ctx context.Context, arg database.SetFeedFetchBoundsParams) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feedByUrl(arg.Url)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feed.MinFetchIntervalSeconds = arg.MinFetchIntervalSeconds
+	feed.MaxFetchIntervalSeconds = arg.MaxFetchIntervalSeconds
+	feed.FetchIntervalSeconds = min(max(feed.FetchIntervalSeconds, arg.MinFetchIntervalSeconds), arg.MaxFetchIntervalSeconds)
+	feed.UpdatedAt = time.Now()
+	s.data.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (s *memoryStore) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feedByUrl(arg.Url)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feed.RetentionMaxPosts = arg.RetentionMaxPosts
+	feed.RetentionMaxAgeSeconds = arg.RetentionMaxAgeSeconds
+	feed.UpdatedAt = time.Now()
+	s.data.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (s *memoryStore) SetFeedSchedule(ctx context.Context, arg database.SetFeedScheduleParams) (database.Feed, error) {
+	defer s.lock()()
+
+	feed, ok := s.data.feedByUrl(arg.Url)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	feed.CronSchedule = arg.CronSchedule
+	feed.NextFetchAt = arg.NextFetchAt
+	feed.UpdatedAt = time.Now()
+	s.data.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (s *memoryStore) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (database.User, error) {
+	defer s.lock()()
+
+	if arg.Role != "admin" && arg.Role != "member" {
+		return database.User{}, checkViolation("users_role_check")
+	}
+	user, ok := s.data.userByName(arg.Name)
+	if !ok {
+		return database.User{}, sql.ErrNoRows
+	}
+	user.Role = arg.Role
+	user.UpdatedAt = arg.UpdatedAt
+	s.data.users[user.ID] = user
+	return user, nil
+}
+
+func (s *memoryStore) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	defer s.lock()()
+
+	if _, ok := s.data.users[arg.UserID]; !ok {
+		return foreignKeyViolation("starred_posts_user_id_fkey")
+	}
+	if _, ok := s.data.posts[arg.PostID]; !ok {
+		return foreignKeyViolation("starred_posts_post_id_fkey")
+	}
+
+	key := starKey{userID: arg.UserID, postID: arg.PostID}
+	star, ok := s.data.starred[key]
+	if !ok {
+		star = database.StarredPost(arg)
+	}
+	star.Note = arg.Note
+	s.data.starred[key] = star
+	return nil
+}
+
+func (s *memoryStore) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	defer s.lock()()
+
+	var moved []database.Feed
+	for _, feed := range s.data.feeds {
+		if feed.UserID == arg.FromUserID {
+			moved = append(moved, feed)
+		}
+	}
+	if len(moved) == 0 || arg.FromUserID == arg.ToUserID {
+		return int64(len(moved)), nil
+	}
+	if _, ok := s.data.users[arg.ToUserID]; !ok {
+		return 0, foreignKeyViolation("feeds_user_id_fkey")
+	}
+	for _, feed := range moved {
+		for _, kept := range s.data.feeds {
+			if kept.UserID == arg.ToUserID && kept.Name == feed.Name {
+				return 0, uniqueViolation("feeds_user_id_name_key")
+			}
+		}
+	}
+
+	for _, feed := range moved {
+		feed.UserID = arg.ToUserID
+		feed.UpdatedAt = arg.UpdatedAt
+		s.data.feeds[feed.ID] = feed
+	}
+	return int64(len(moved)), nil
+}
+
+func (s *memoryStore) UnstarPost(ctx context.Context, arg database.UnstarPostParams) (int64, error) {
+	defer s.lock()()
+
+	key := starKey{userID: arg.UserID, postID: arg.PostID}
+	if _, ok := s.data.starred[key]; !ok {
+		return 0, nil
+	}
+	delete(s.data.starred, key)
+	return 1, nil
+}
+
+var _ Store = (*memoryStore)(nil)
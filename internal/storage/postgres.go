@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net/url"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+
+	"github.com/lib/pq"
+)
+
+type postgresStore struct {
+	*database.Queries
+	db *sql.DB
+}
+
+// Columns are TIMESTAMP without time zone, so session runs in UTC and times written
+// from Go are converted to UTC. Otherwise values written by the host and NOW() of the
+// server differ by offset between their time zones, like _timezone=UTC does for SQLite
+func openPostgres(dbURL string) (*postgresStore, error) {
+	parsed, err := url.Parse(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	query := parsed.Query()
+	query.Set("timezone", "UTC")
+	parsed.RawQuery = query.Encode()
+
+	connector, err := pq.NewConnector(parsed.String())
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(utcConnector{connector})
+	return &postgresStore{Queries: database.New(db), db: db}, nil
+}
+
+type utcConnector struct {
+	*pq.Connector
+}
+
+func (c utcConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return utcConn{conn}, nil
+}
+
+// Connection of lib/pq converting time arguments to UTC. Forwards optional
+// interfaces of the driver so queries do not fall back to prepared statements
+type utcConn struct {
+	driver.Conn
+}
+
+func (c utcConn) CheckNamedValue(nv *driver.NamedValue) error {
+	// Checked before Valuer conversion, so nullable times are converted here too
+	switch value := nv.Value.(type) {
+	case time.Time:
+		nv.Value = value.UTC()
+		return nil
+	case sql.NullTime:
+		nv.Value = nil
+		if value.Valid {
+			nv.Value = value.Time.UTC()
+		}
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c utcConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c utcConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c utcConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c utcConn) Ping(ctx context.Context) error {
+	return c.Conn.(driver.Pinger).Ping(ctx)
+}
+
+func (c utcConn) ResetSession(ctx context.Context) error {
+	return c.Conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c utcConn) IsValid() bool {
+	return c.Conn.(driver.Validator).IsValid()
+}
+
+func (s *postgresStore) InTx(ctx context.Context, fn func(q database.Querier) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *postgresStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *postgresStore) Backend() Backend {
+	return Postgres
+}
+
+func (s *postgresStore) DB() *sql.DB {
+	return s.db
+}
+
+func (s *postgresStore) Close() error {
+	return s.db.Close()
+}
+
+var _ Store = (*postgresStore)(nil)
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+func TestUTCConnConvertsTimes(t *testing.T) {
+	local := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	cases := []struct {
+		name  string
+		value any
+		want  any
+	}{
+		{"time", local, local.UTC()},
+		{"valid null time", sql.NullTime{Time: local, Valid: true}, local.UTC()},
+		{"null time", sql.NullTime{}, nil},
+	}
+
+	for _, tc := range cases {
+		nv := &driver.NamedValue{Value: tc.value}
+		if err := (utcConn{}).CheckNamedValue(nv); err != nil {
+			t.Fatalf("%s: unexpected error %v", tc.name, err)
+		}
+		if nv.Value != tc.want {
+			t.Errorf("%s: converted to %v, want %v", tc.name, nv.Value, tc.want)
+		}
+	}
+
+	// Other values are left to default conversion
+	if err := (utcConn{}).CheckNamedValue(&driver.NamedValue{Value: "text"}); err != driver.ErrSkip {
+		t.Errorf("string returned %v, want driver.ErrSkip", err)
+	}
+}
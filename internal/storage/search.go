@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Single word or quoted phrase of search query
+type searchTerm struct {
+	words   []string
+	negated bool
+}
+
+// Parses query in syntax of Postgres websearch_to_tsquery: words are joined
+// with AND, "quoted text" is a phrase, -word excludes it and "or" separates
+// alternatives. Result are alternatives each made of terms which all have to match
+func parseWebsearch(query string) [][]searchTerm {
+	var alternatives [][]searchTerm
+	var current []searchTerm
+
+	for len(query) > 0 {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if query == "" {
+			break
+		}
+
+		negated := false
+		if query[0] == '-' {
+			negated = true
+			query = query[1:]
+		}
+
+		var text string
+		if strings.HasPrefix(query, `"`) {
+			end := strings.IndexByte(query[1:], '"')
+			if end < 0 {
+				text, query = query[1:], ""
+			} else {
+				text, query = query[1:end+1], query[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(query, unicode.IsSpace)
+			if end < 0 {
+				end = len(query)
+			}
+			text, query = query[:end], query[end:]
+			if !negated && strings.EqualFold(text, "or") {
+				if len(current) > 0 {
+					alternatives = append(alternatives, current)
+					current = nil
+				}
+				continue
+			}
+		}
+
+		if words := searchWords(text); len(words) > 0 {
+			current = append(current, searchTerm{words: words, negated: negated})
+		}
+	}
+	if len(current) > 0 {
+		alternatives = append(alternatives, current)
+	}
+
+	// Alternative with only excluded terms would match nearly everything
+	var matchable [][]searchTerm
+	for _, terms := range alternatives {
+		for _, term := range terms {
+			if !term.negated {
+				matchable = append(matchable, terms)
+				break
+			}
+		}
+	}
+	return matchable
+}
+
+// Lower cased words of text without punctuation
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// Translates websearch query to FTS5 query syntax of SQLite, every word is
+// quoted so it can not be taken for FTS5 operator
+func ftsQuery(query string) string {
+	var alternatives []string
+	for _, terms := range parseWebsearch(query) {
+		var included, excluded []string
+		for _, term := range terms {
+			phrase := `"` + strings.Join(term.words, " ") + `"`
+			if term.negated {
+				excluded = append(excluded, phrase)
+			} else {
+				included = append(included, phrase)
+			}
+		}
+
+		alternative := "(" + strings.Join(included, " AND ") + ")"
+		for _, phrase := range excluded {
+			alternative = "(" + alternative + " NOT " + phrase + ")"
+		}
+		alternatives = append(alternatives, alternative)
+	}
+	return strings.Join(alternatives, " OR ")
+}
@@ -0,0 +1,646 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/MichalGul/blog_aggregator/internal/sqlitedb"
+	"github.com/google/uuid"
+	"modernc.org/sqlite"
+)
+
+// Format the driver writes times in, _timezone=UTC keeps them comparable as text
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+const sqliteParams = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite&_timezone=UTC&_txlock=immediate"
+
+// now() and now_offset(seconds) stand in for NOW() and interval arithmetic of Postgres queries,
+// NULL offset gives NULL like interval arithmetic does
+func init() {
+	sqlite.MustRegisterScalarFunction("now", 0, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		return time.Now().UTC().Format(sqliteTimeFormat), nil
+	})
+	sqlite.MustRegisterScalarFunction("now_offset", 1, func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		seconds, ok := args[0].(int64)
+		if !ok {
+			return nil, fmt.Errorf("now_offset expects integer seconds, got %T", args[0])
+		}
+		return time.Now().UTC().Add(time.Duration(seconds) * time.Second).Format(sqliteTimeFormat), nil
+	})
+}
+
+// Adapts queries generated for SQLite to the Postgres query interface. Rows of
+// both packages have identical fields so they convert directly
+type sqliteStore struct {
+	q  *sqlitedb.Queries
+	db *sql.DB
+	// set for store bound to transaction by InTx
+	tx *sql.Tx
+}
+
+func openSQLite(dbURL string) (*sqliteStore, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(dbURL, "sqlite:"), "//")
+	if path == "" {
+		return nil, fmt.Errorf("sqlite db_url is missing database file path")
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+sqliteParams)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteStore{q: sqlitedb.New(db), db: db}, nil
+}
+
+func (s *sqliteStore) InTx(ctx context.Context, fn func(q database.Querier) error) error {
+	// SQLite has no nested transactions, store already in one joins it
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqliteStore{q: s.q.WithTx(tx), db: s.db, tx: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqliteStore) Backend() Backend {
+	return SQLite
+}
+
+func (s *sqliteStore) DB() *sql.DB {
+	return s.db
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+func toFeed(feed sqlitedb.Feed) database.Feed {
+	return database.Feed(feed)
+}
+
+func toFeeds(feeds []sqlitedb.Feed, err error) ([]database.Feed, error) {
+	return convertAll(feeds, toFeed), err
+}
+
+// SQLite keeps search index in separate table so posts have no search vector
+func toPost(post sqlitedb.Post) database.Post {
+	return database.Post{
+		ID:          post.ID,
+		CreatedAt:   post.CreatedAt,
+		UpdatedAt:   post.UpdatedAt,
+		Title:       post.Title,
+		Url:         post.Url,
+		Description: post.Description,
+		PublishedAt: post.PublishedAt,
+		FeedID:      post.FeedID,
+		Content:     post.Content,
+		OriginalUrl: post.OriginalUrl,
+	}
+}
+
+func (s *sqliteStore) ClaimActiveFeeds(ctx context.Context, arg database.ClaimActiveFeedsParams) ([]database.Feed, error) {
+	return toFeeds(s.q.ClaimActiveFeeds(ctx, sqlitedb.ClaimActiveFeedsParams(arg)))
+}
+
+func (s *sqliteStore) ClaimDueFeeds(ctx context.Context, arg database.ClaimDueFeedsParams) ([]database.Feed, error) {
+	return toFeeds(s.q.ClaimDueFeeds(ctx, sqlitedb.ClaimDueFeedsParams{
+		InstanceID:    arg.InstanceID,
+		LeaseSeconds:  arg.LeaseSeconds,
+		ScheduledOnly: arg.ScheduledOnly,
+		MaxFeeds:      int64(arg.MaxFeeds),
+	}))
+}
+
+func (s *sqliteStore) ClaimFeed(ctx context.Context, arg database.ClaimFeedParams) (database.Feed, error) {
+	feed, err := s.q.ClaimFeed(ctx, sqlitedb.ClaimFeedParams(arg))
+	return toFeed(feed), err
+}
+
+// SQLite can not delete from queue inside UPDATE, claimed entries are removed in the same transaction
+func (s *sqliteStore) ClaimQueuedFeeds(ctx context.Context, arg database.ClaimQueuedFeedsParams) ([]database.Feed, error) {
+	var claimed []database.Feed
+	err := s.InTx(ctx, func(q database.Querier) error {
+		txStore := q.(*sqliteStore)
+		feeds, claimErr := toFeeds(txStore.q.ClaimQueuedFeeds(ctx, sqlitedb.ClaimQueuedFeedsParams{
+			InstanceID:   arg.InstanceID,
+			LeaseSeconds: arg.LeaseSeconds,
+			MaxFeeds:     int64(arg.MaxFeeds),
+		}))
+		if claimErr != nil {
+			return claimErr
+		}
+		for _, feed := range feeds {
+			if deleteErr := txStore.q.DeleteQueuedFetch(ctx, feed.ID); deleteErr != nil {
+				return deleteErr
+			}
+		}
+		claimed = feeds
+		return nil
+	})
+	return claimed, err
+}
+
+func (s *sqliteStore) CountLiveAggDaemons(ctx context.Context, staleSeconds int32) (int64, error) {
+	return s.q.CountLiveAggDaemons(ctx, staleSeconds)
+}
+
+func (s *sqliteStore) CountOverdueFeeds(ctx context.Context) (int64, error) {
+	return s.q.CountOverdueFeeds(ctx)
+}
+
+func (s *sqliteStore) CountQueuedFeeds(ctx context.Context) (int64, error) {
+	return s.q.CountQueuedFeeds(ctx)
+}
+
+func (s *sqliteStore) CountUsers(ctx context.Context) (int64, error) {
+	return s.q.CountUsers(ctx)
+}
+
+func (s *sqliteStore) CreateApiToken(ctx context.Context, arg database.CreateApiTokenParams) (database.ApiToken, error) {
+	token, err := s.q.CreateApiToken(ctx, sqlitedb.CreateApiTokenParams(arg))
+	return database.ApiToken(token), err
+}
+
+func (s *sqliteStore) CreateAuditEntry(ctx context.Context, arg database.CreateAuditEntryParams) error {
+	return s.q.CreateAuditEntry(ctx, sqlitedb.CreateAuditEntryParams(arg))
+}
+
+func (s *sqliteStore) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	feed, err := s.q.CreateFeed(ctx, sqlitedb.CreateFeedParams(arg))
+	return toFeed(feed), err
+}
+
+// SQLite does not allow INSERT inside WITH, names are read by follow up query
+func (s *sqliteStore) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	var created database.CreateFeedFollowRow
+	err := s.InTx(ctx, func(q database.Querier) error {
+		txStore := q.(*sqliteStore)
+		follow, createErr := txStore.q.CreateFeedFollow(ctx, sqlitedb.CreateFeedFollowParams(arg))
+		if createErr != nil {
+			return createErr
+		}
+		row, getErr := txStore.q.GetFeedFollow(ctx, follow.ID)
+		created = database.CreateFeedFollowRow(row)
+		return getErr
+	})
+	return created, err
+}
+
+func (s *sqliteStore) CreateFetchRun(ctx context.Context, arg database.CreateFetchRunParams) (database.FetchRun, error) {
+	run, err := s.q.CreateFetchRun(ctx, sqlitedb.CreateFetchRunParams(arg))
+	return database.FetchRun(run), err
+}
+
+// Inserts posts one by one, items already stored are skipped like ON CONFLICT DO NOTHING in Postgres
+func (s *sqliteStore) CreatePosts(ctx context.Context, arg database.CreatePostsParams) ([]database.Post, error) {
+	var posts []database.Post
+	for i := range arg.Titles {
+		publishedAt := sql.NullTime{}
+		if arg.PublishedAts[i] != "" {
+			parsed, parseErr := time.Parse(time.RFC3339Nano, arg.PublishedAts[i])
+			if parseErr != nil {
+				return nil, fmt.Errorf("invalid published at %q: %v", arg.PublishedAts[i], parseErr)
+			}
+			publishedAt = sql.NullTime{Time: parsed, Valid: true}
+		}
+
+		post, createErr := s.q.CreatePost(ctx, sqlitedb.CreatePostParams{
+			ID:          uuid.New(),
+			CreatedAt:   arg.CreatedAt,
+			UpdatedAt:   arg.CreatedAt,
+			Title:       arg.Titles[i],
+			Url:         arg.Urls[i],
+			Description: sql.NullString{String: arg.Descriptions[i], Valid: arg.Descriptions[i] != ""},
+			PublishedAt: publishedAt,
+			FeedID:      arg.FeedID,
+			Content:     sql.NullString{String: arg.Contents[i], Valid: arg.Contents[i] != ""},
+			OriginalUrl: arg.OriginalUrls[i],
+		})
+		if errors.Is(createErr, sql.ErrNoRows) {
+			continue
+		}
+		if createErr != nil {
+			return nil, createErr
+		}
+		posts = append(posts, toPost(post))
+	}
+	return posts, nil
+}
+
+func (s *sqliteStore) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	user, err := s.q.CreateUser(ctx, sqlitedb.CreateUserParams(arg))
+	return database.User(user), err
+}
+
+func (s *sqliteStore) DeleteAggInstance(ctx context.Context, id uuid.UUID) error {
+	return s.q.DeleteAggInstance(ctx, id)
+}
+
+func (s *sqliteStore) DeleteApiToken(ctx context.Context, arg database.DeleteApiTokenParams) (int64, error) {
+	return s.q.DeleteApiToken(ctx, sqlitedb.DeleteApiTokenParams(arg))
+}
+
+func (s *sqliteStore) DeleteFeed(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.q.DeleteFeed(ctx, id)
+}
+
+func (s *sqliteStore) DeleteFeedCredentials(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	return s.q.DeleteFeedCredentials(ctx, feedID)
+}
+
+func (s *sqliteStore) DeleteFeeds(ctx context.Context) error {
+	return s.q.DeleteFeeds(ctx)
+}
+
+func (s *sqliteStore) DeleteFeedsFollow(ctx context.Context, arg database.DeleteFeedsFollowParams) error {
+	return s.q.DeleteFeedsFollow(ctx, sqlitedb.DeleteFeedsFollowParams(arg))
+}
+
+func (s *sqliteStore) DeleteFetchRunsBefore(ctx context.Context, startedAt time.Time) (int64, error) {
+	return s.q.DeleteFetchRunsBefore(ctx, startedAt)
+}
+
+func (s *sqliteStore) DeleteStaleAggInstances(ctx context.Context, staleSeconds int32) (int64, error) {
+	return s.q.DeleteStaleAggInstances(ctx, staleSeconds)
+}
+
+func (s *sqliteStore) DeleteUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	return s.q.DeleteUser(ctx, id)
+}
+
+func (s *sqliteStore) DeleteUsers(ctx context.Context) error {
+	return s.q.DeleteUsers(ctx)
+}
+
+func (s *sqliteStore) EnableFeed(ctx context.Context, url string) (database.Feed, error) {
+	feed, err := s.q.EnableFeed(ctx, url)
+	return toFeed(feed), err
+}
+
+func (s *sqliteStore) EnqueueFeedFetch(ctx context.Context, arg database.EnqueueFeedFetchParams) error {
+	return s.q.EnqueueFeedFetch(ctx, sqlitedb.EnqueueFeedFetchParams(arg))
+}
+
+func (s *sqliteStore) ExtendFeedClaims(ctx context.Context, arg database.ExtendFeedClaimsParams) (int64, error) {
+	return s.q.ExtendFeedClaims(ctx, sqlitedb.ExtendFeedClaimsParams(arg))
+}
+
+func (s *sqliteStore) GetAllApiTokens(ctx context.Context) ([]database.ApiToken, error) {
+	tokens, err := s.q.GetAllApiTokens(ctx)
+	return convertAll(tokens, func(row sqlitedb.ApiToken) database.ApiToken {
+		return database.ApiToken(row)
+	}), err
+}
+
+func (s *sqliteStore) GetAllFeedCredentials(ctx context.Context) ([]database.FeedCredential, error) {
+	credentials, err := s.q.GetAllFeedCredentials(ctx)
+	return convertAll(credentials, func(row sqlitedb.FeedCredential) database.FeedCredential {
+		return database.FeedCredential(row)
+	}), err
+}
+
+func (s *sqliteStore) GetAllFeedFollows(ctx context.Context) ([]database.FeedFollow, error) {
+	follows, err := s.q.GetAllFeedFollows(ctx)
+	return convertAll(follows, func(row sqlitedb.FeedFollow) database.FeedFollow {
+		return database.FeedFollow(row)
+	}), err
+}
+
+func (s *sqliteStore) GetAllFeeds(ctx context.Context) ([]database.Feed, error) {
+	return toFeeds(s.q.GetAllFeeds(ctx))
+}
+
+func (s *sqliteStore) GetAllPosts(ctx context.Context) ([]database.GetAllPostsRow, error) {
+	posts, err := s.q.GetAllPosts(ctx)
+	return convertAll(posts, func(row sqlitedb.Post) database.GetAllPostsRow {
+		return database.GetAllPostsRow(row)
+	}), err
+}
+
+func (s *sqliteStore) GetAllStarredPosts(ctx context.Context) ([]database.StarredPost, error) {
+	starred, err := s.q.GetAllStarredPosts(ctx)
+	return convertAll(starred, func(row sqlitedb.StarredPost) database.StarredPost {
+		return database.StarredPost(row)
+	}), err
+}
+
+func (s *sqliteStore) GetApiTokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	tokens, err := s.q.GetApiTokensForUser(ctx, userID)
+	return convertAll(tokens, func(row sqlitedb.ApiToken) database.ApiToken {
+		return database.ApiToken(row)
+	}), err
+}
+
+func (s *sqliteStore) GetAuditEntries(ctx context.Context, arg database.GetAuditEntriesParams) ([]database.AuditLog, error) {
+	entries, err := s.q.GetAuditEntries(ctx, sqlitedb.GetAuditEntriesParams{
+		UserName:   arg.UserName,
+		Since:      arg.Since,
+		MaxEntries: int64(arg.MaxEntries),
+	})
+	return convertAll(entries, func(row sqlitedb.AuditLog) database.AuditLog {
+		return database.AuditLog(row)
+	}), err
+}
+
+func (s *sqliteStore) GetBrokenFeeds(ctx context.Context) ([]database.Feed, error) {
+	return toFeeds(s.q.GetBrokenFeeds(ctx))
+}
+
+func (s *sqliteStore) GetFeedById(ctx context.Context, id uuid.UUID) (database.Feed, error) {
+	feed, err := s.q.GetFeedById(ctx, id)
+	return toFeed(feed), err
+}
+
+func (s *sqliteStore) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	feed, err := s.q.GetFeedByUrl(ctx, url)
+	return toFeed(feed), err
+}
+
+func (s *sqliteStore) GetFeedCredentials(ctx context.Context, feedID uuid.UUID) ([]byte, error) {
+	return s.q.GetFeedCredentials(ctx, feedID)
+}
+
+func (s *sqliteStore) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	follows, err := s.q.GetFeedFollowsForUser(ctx, userID)
+	return convertAll(follows, func(row sqlitedb.GetFeedFollowsForUserRow) database.GetFeedFollowsForUserRow {
+		return database.GetFeedFollowsForUserRow(row)
+	}), err
+}
+
+func (s *sqliteStore) GetFeedPostId(ctx context.Context, arg database.GetFeedPostIdParams) (uuid.UUID, error) {
+	return s.q.GetFeedPostId(ctx, sqlitedb.GetFeedPostIdParams(arg))
+}
+
+func (s *sqliteStore) GetFeeds(ctx context.Context) ([]database.GetFeedsRow, error) {
+	feeds, err := s.q.GetFeeds(ctx)
+	return convertAll(feeds, func(row sqlitedb.GetFeedsRow) database.GetFeedsRow {
+		return database.GetFeedsRow(row)
+	}), err
+}
+
+func (s *sqliteStore) GetFetchRuns(ctx context.Context, arg database.GetFetchRunsParams) ([]database.GetFetchRunsRow, error) {
+	runs, err := s.q.GetFetchRuns(ctx, sqlitedb.GetFetchRunsParams{
+		FeedUrl: arg.FeedUrl,
+		Since:   arg.Since,
+		MaxRuns: int64(arg.MaxRuns),
+	})
+	return convertAll(runs, func(row sqlitedb.GetFetchRunsRow) database.GetFetchRunsRow {
+		return database.GetFetchRunsRow(row)
+	}), err
+}
+
+func (s *sqliteStore) GetOwnedFeeds(ctx context.Context, userID uuid.UUID) ([]database.GetOwnedFeedsRow, error) {
+	feeds, err := s.q.GetOwnedFeeds(ctx, userID)
+	return convertAll(feeds, func(row sqlitedb.GetOwnedFeedsRow) database.GetOwnedFeedsRow {
+		return database.GetOwnedFeedsRow(row)
+	}), err
+}
+
+func (s *sqliteStore) GetPostByUrl(ctx context.Context, arg database.GetPostByUrlParams) (database.Post, error) {
+	post, err := s.q.GetPostByUrl(ctx, sqlitedb.GetPostByUrlParams(arg))
+	return toPost(post), err
+}
+
+func (s *sqliteStore) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) ([]database.Post, error) {
+	posts, err := s.q.GetPostForUser(ctx, sqlitedb.GetPostForUserParams{
+		UserID: arg.UserID,
+		Limit:  int64(arg.Limit),
+	})
+	return convertAll(posts, toPost), err
+}
+
+func (s *sqliteStore) GetPrunablePostCounts(ctx context.Context, createdBefore sql.NullTime) ([]database.GetPrunablePostCountsRow, error) {
+	counts, err := s.q.GetPrunablePostCounts(ctx, createdBefore)
+	return convertAll(counts, func(row sqlitedb.GetPrunablePostCountsRow) database.GetPrunablePostCountsRow {
+		return database.GetPrunablePostCountsRow(row)
+	}), err
+}
+
+func (s *sqliteStore) GetRecentPostTimes(ctx context.Context, arg database.GetRecentPostTimesParams) ([]time.Time, error) {
+	rows, err := s.q.GetRecentPostTimes(ctx, sqlitedb.GetRecentPostTimesParams{
+		FeedID: arg.FeedID,
+		Limit:  int64(arg.Limit),
+	})
+	return convertAll(rows, func(row sqlitedb.GetRecentPostTimesRow) time.Time {
+		if row.PublishedAt.Valid {
+			return row.PublishedAt.Time
+		}
+		return row.CreatedAt
+	}), err
+}
+
+func (s *sqliteStore) GetStarredPosts(ctx context.Context, userID uuid.UUID) ([]database.GetStarredPostsRow, error) {
+	starred, err := s.q.GetStarredPosts(ctx, userID)
+	return convertAll(starred, func(row sqlitedb.GetStarredPostsRow) database.GetStarredPostsRow {
+		return database.GetStarredPostsRow(row)
+	}), err
+}
+
+func (s *sqliteStore) GetUser(ctx context.Context, name string) (database.User, error) {
+	user, err := s.q.GetUser(ctx, name)
+	return database.User(user), err
+}
+
+func (s *sqliteStore) GetUserActivity(ctx context.Context, userID uuid.UUID) (database.GetUserActivityRow, error) {
+	activity, err := s.q.GetUserActivity(ctx, userID)
+	return database.GetUserActivityRow(activity), err
+}
+
+func (s *sqliteStore) GetUserByTokenHash(ctx context.Context, arg database.GetUserByTokenHashParams) (database.User, error) {
+	user, err := s.q.GetUserByTokenHash(ctx, sqlitedb.GetUserByTokenHashParams(arg))
+	return database.User(user), err
+}
+
+func (s *sqliteStore) GetUsernameById(ctx context.Context, id uuid.UUID) (string, error) {
+	return s.q.GetUsernameById(ctx, id)
+}
+
+func (s *sqliteStore) GetUsers(ctx context.Context) ([]database.User, error) {
+	users, err := s.q.GetUsers(ctx)
+	return convertAll(users, func(user sqlitedb.User) database.User {
+		return database.User(user)
+	}), err
+}
+
+func (s *sqliteStore) HeartbeatAggInstance(ctx context.Context, arg database.HeartbeatAggInstanceParams) error {
+	return s.q.HeartbeatAggInstance(ctx, sqlitedb.HeartbeatAggInstanceParams(arg))
+}
+
+func (s *sqliteStore) ImportApiToken(ctx context.Context, arg database.ImportApiTokenParams) (int64, error) {
+	return s.q.ImportApiToken(ctx, sqlitedb.ImportApiTokenParams(arg))
+}
+
+func (s *sqliteStore) ImportFeed(ctx context.Context, arg database.ImportFeedParams) (int64, error) {
+	return s.q.ImportFeed(ctx, sqlitedb.ImportFeedParams(arg))
+}
+
+func (s *sqliteStore) ImportFeedFollow(ctx context.Context, arg database.ImportFeedFollowParams) (int64, error) {
+	return s.q.ImportFeedFollow(ctx, sqlitedb.ImportFeedFollowParams(arg))
+}
+
+func (s *sqliteStore) ImportPost(ctx context.Context, arg database.ImportPostParams) (int64, error) {
+	return s.q.ImportPost(ctx, sqlitedb.ImportPostParams(arg))
+}
+
+func (s *sqliteStore) ImportStarredPost(ctx context.Context, arg database.ImportStarredPostParams) (int64, error) {
+	return s.q.ImportStarredPost(ctx, sqlitedb.ImportStarredPostParams(arg))
+}
+
+func (s *sqliteStore) PostExistsWithOriginalUrl(ctx context.Context, arg database.PostExistsWithOriginalUrlParams) (bool, error) {
+	exists, err := s.q.PostExistsWithOriginalUrl(ctx, sqlitedb.PostExistsWithOriginalUrlParams(arg))
+	return exists != 0, err
+}
+
+// SQLite has no DELETE in WITH clause, posts are counted and then deleted in the same transaction
+func (s *sqliteStore) PrunePosts(ctx context.Context, createdBefore sql.NullTime) ([]database.PrunePostsRow, error) {
+	var pruned []database.PrunePostsRow
+	err := s.InTx(ctx, func(q database.Querier) error {
+		txStore := q.(*sqliteStore)
+		counts, countErr := txStore.q.GetPrunablePostCounts(ctx, createdBefore)
+		if countErr != nil {
+			return countErr
+		}
+		if _, deleteErr := txStore.q.DeletePrunablePosts(ctx, createdBefore); deleteErr != nil {
+			return deleteErr
+		}
+		pruned = convertAll(counts, func(row sqlitedb.GetPrunablePostCountsRow) database.PrunePostsRow {
+			return database.PrunePostsRow(row)
+		})
+		return nil
+	})
+	return pruned, err
+}
+
+func (s *sqliteStore) RecordFeedFailure(ctx context.Context, arg database.RecordFeedFailureParams) (database.Feed, error) {
+	feed, err := s.q.RecordFeedFailure(ctx, sqlitedb.RecordFeedFailureParams{
+		LastError:   arg.LastError,
+		NextFetchAt: arg.NextFetchAt,
+		MaxFailures: arg.MaxFailures,
+		ID:          arg.ID,
+	})
+	return toFeed(feed), err
+}
+
+func (s *sqliteStore) RecordFeedSuccess(ctx context.Context, id uuid.UUID) error {
+	return s.q.RecordFeedSuccess(ctx, id)
+}
+
+func (s *sqliteStore) ReleaseFeedClaim(ctx context.Context, id uuid.UUID) error {
+	return s.q.ReleaseFeedClaim(ctx, id)
+}
+
+func (s *sqliteStore) RenameUser(ctx context.Context, arg database.RenameUserParams) (database.User, error) {
+	user, err := s.q.RenameUser(ctx, sqlitedb.RenameUserParams(arg))
+	return database.User(user), err
+}
+
+func (s *sqliteStore) ScheduleFeedFetch(ctx context.Context, arg database.ScheduleFeedFetchParams) (database.Feed, error) {
+	feed, err := s.q.ScheduleFeedFetch(ctx, sqlitedb.ScheduleFeedFetchParams{
+		FetchIntervalSeconds: arg.FetchIntervalSeconds,
+		NextFetchAt:          arg.NextFetchAt,
+		ID:                   arg.ID,
+	})
+	return toFeed(feed), err
+}
+
+// Query is translated from websearch syntax to FTS5 one, bm25 score is
+// negated so better matches rank higher like with ts_rank
+func (s *sqliteStore) SearchPosts(ctx context.Context, arg database.SearchPostsParams) ([]database.SearchPostsRow, error) {
+	query := ftsQuery(arg.Query)
+	if query == "" {
+		return nil, nil
+	}
+
+	rows, err := s.q.SearchPosts(ctx, sqlitedb.SearchPostsParams{
+		Query:      query,
+		UserID:     arg.UserID,
+		FeedUrl:    arg.FeedUrl,
+		Since:      arg.Since,
+		MaxResults: int64(arg.MaxResults),
+	})
+	return convertAll(rows, func(row sqlitedb.SearchPostsRow) database.SearchPostsRow {
+		return database.SearchPostsRow{
+			ID:          row.ID,
+			Title:       row.Title,
+			Url:         row.Url,
+			PublishedAt: row.PublishedAt,
+			FeedName:    row.FeedName,
+			Rank:        float32(row.Rank),
+			Snippet:     row.Snippet,
+		}
+	}), err
+}
+
+func (s *sqliteStore) SetFeedCredentials(ctx context.Context, arg database.SetFeedCredentialsParams) error {
+	return s.q.SetFeedCredentials(ctx, sqlitedb.SetFeedCredentialsParams(arg))
+}
+
+func (s *sqliteStore) SetFeedFetchBounds(ctx context.Context, arg database.SetFeedFetchBoundsParams) (database.Feed, error) {
+	feed, err := s.q.SetFeedFetchBounds(ctx, sqlitedb.SetFeedFetchBoundsParams{
+		MinFetchIntervalSeconds: arg.MinFetchIntervalSeconds,
+		MaxFetchIntervalSeconds: arg.MaxFetchIntervalSeconds,
+		Url:                     arg.Url,
+	})
+	return toFeed(feed), err
+}
+
+func (s *sqliteStore) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) (database.Feed, error) {
+	feed, err := s.q.SetFeedRetention(ctx, sqlitedb.SetFeedRetentionParams{
+		RetentionMaxPosts:      arg.RetentionMaxPosts,
+		RetentionMaxAgeSeconds: arg.RetentionMaxAgeSeconds,
+		Url:                    arg.Url,
+	})
+	return toFeed(feed), err
+}
+
+func (s *sqliteStore) SetFeedSchedule(ctx context.Context, arg database.SetFeedScheduleParams) (database.Feed, error) {
+	feed, err := s.q.SetFeedSchedule(ctx, sqlitedb.SetFeedScheduleParams{
+		CronSchedule: arg.CronSchedule,
+		NextFetchAt:  arg.NextFetchAt,
+		Url:          arg.Url,
+	})
+	return toFeed(feed), err
+}
+
+func (s *sqliteStore) SetUserRole(ctx context.Context, arg database.SetUserRoleParams) (database.User, error) {
+	user, err := s.q.SetUserRole(ctx, sqlitedb.SetUserRoleParams{
+		Role:      arg.Role,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+	})
+	return database.User(user), err
+}
+
+func (s *sqliteStore) StarPost(ctx context.Context, arg database.StarPostParams) error {
+	return s.q.StarPost(ctx, sqlitedb.StarPostParams(arg))
+}
+
+func (s *sqliteStore) TransferFeeds(ctx context.Context, arg database.TransferFeedsParams) (int64, error) {
+	return s.q.TransferFeeds(ctx, sqlitedb.TransferFeedsParams(arg))
+}
+
+func (s *sqliteStore) UnstarPost(ctx context.Context, arg database.UnstarPostParams) (int64, error) {
+	return s.q.UnstarPost(ctx, sqlitedb.UnstarPostParams(arg))
+}
+
+var _ Store = (*sqliteStore)(nil)
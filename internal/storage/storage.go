@@ -0,0 +1,66 @@
+// Package storage selects the database backend from db_url and exposes it
+// through the query interface generated for Postgres.
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+type Backend string
+
+const (
+	Postgres Backend = "postgres"
+	SQLite   Backend = "sqlite"
+)
+
+// Data access used by handlers, implemented by every backend
+type Store interface {
+	database.Querier
+
+	// Runs fn with queries bound to one transaction, rolled back when fn returns error or
+	// panics. Handlers making more than one write go through it so they never half apply
+	InTx(ctx context.Context, fn func(q database.Querier) error) error
+	Ping(ctx context.Context) error
+	Backend() Backend
+	// Connection used by schema migrations
+	DB() *sql.DB
+	Close() error
+}
+
+// Opens store for db_url, postgres:// and postgresql:// urls use Postgres,
+// sqlite:// urls a database file eg. sqlite:///home/me/gator.db
+func Open(dbURL string) (Store, error) {
+	switch {
+	case strings.HasPrefix(dbURL, "postgres://"), strings.HasPrefix(dbURL, "postgresql://"):
+		return openPostgres(dbURL)
+	case strings.HasPrefix(dbURL, "sqlite:"):
+		return openSQLite(dbURL)
+	default:
+		return nil, fmt.Errorf("unsupported db_url %q, expected postgres:// or sqlite:// scheme", redactURL(dbURL))
+	}
+}
+
+// Scheme of url without credentials for error messages
+func redactURL(dbURL string) string {
+	scheme, _, found := strings.Cut(dbURL, "://")
+	if !found {
+		return "..."
+	}
+	return scheme + "://..."
+}
+
+func convertAll[From, To any](items []From, convert func(From) To) []To {
+	if items == nil {
+		return nil
+	}
+	converted := make([]To, 0, len(items))
+	for _, item := range items {
+		converted = append(converted, convert(item))
+	}
+	return converted
+}
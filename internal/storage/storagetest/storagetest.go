@@ -0,0 +1,969 @@
+// Package storagetest checks that storage backends behave the same way.
+package storagetest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/archive"
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/MichalGul/blog_aggregator/internal/storage"
+	"github.com/google/uuid"
+)
+
+// TestStore runs the contract every backend has to follow against empty store:
+// unique and foreign keys, cascading deletes, NULL ordering, search, retention,
+// api tokens and rollback of transactions. All data is deleted when it returns. Returned error joins every
+// broken expectation.
+func TestStore(ctx context.Context, store storage.Store) error {
+	checks := []struct {
+		name string
+		fn   func(context.Context, storage.Store) error
+	}{
+		{"users", checkUsers},
+		{"feeds", checkFeeds},
+		{"posts", checkPosts},
+		{"publish times", checkPublishTimes},
+		{"search", checkSearch},
+		{"retention", checkRetention},
+		{"tokens", checkTokens},
+		{"imports", checkImports},
+		{"audit", checkAudit},
+		{"claims", checkClaims},
+		{"transactions", checkTransactions},
+		{"cascades", checkCascades},
+		{"ownership", checkOwnership},
+	}
+
+	var errs []error
+	for _, check := range checks {
+		if err := check.fn(ctx, store); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", check.name, err))
+		}
+		if err := store.DeleteUsers(ctx); err != nil {
+			return errors.Join(append(errs, fmt.Errorf("error cleaning up after %s: %v", check.name, err))...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Timestamps with second precision survive round trip through every backend
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func createUser(ctx context.Context, q database.Querier, name string) (database.User, error) {
+	return q.CreateUser(ctx, database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UpdatedAt: now(),
+		Name:      name,
+	})
+}
+
+func createFeed(ctx context.Context, q database.Querier, user database.User, name string) (database.Feed, error) {
+	return q.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UpdatedAt: now(),
+		Name:      name,
+		Url:       "https://example.com/" + name + ".xml",
+		UserID:    user.ID,
+	})
+}
+
+func checkUsers(ctx context.Context, store storage.Store) error {
+	user, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	if _, err := createUser(ctx, store, "alice"); err == nil {
+		return errors.New("duplicate user name accepted")
+	}
+	if _, err := store.GetUser(ctx, "bob"); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("missing user returned %v, want sql.ErrNoRows", err)
+	}
+	name, err := store.GetUsernameById(ctx, user.ID)
+	if err != nil || name != "alice" {
+		return fmt.Errorf("user name by id is %q (%v), want alice", name, err)
+	}
+
+	if user.Role != "member" {
+		return fmt.Errorf("new user has role %q, want member", user.Role)
+	}
+	promoted, err := store.SetUserRole(ctx, database.SetUserRoleParams{Name: "alice", Role: "admin", UpdatedAt: now()})
+	if err != nil || promoted.Role != "admin" {
+		return fmt.Errorf("promoted user has role %q (%v), want admin", promoted.Role, err)
+	}
+	if stored, err := store.GetUser(ctx, "alice"); err != nil || stored.Role != "admin" {
+		return fmt.Errorf("stored user has role %q (%v), want admin", stored.Role, err)
+	}
+	if _, err := store.SetUserRole(ctx, database.SetUserRoleParams{Name: "alice", Role: "owner", UpdatedAt: now()}); err == nil {
+		return errors.New("unknown role accepted")
+	}
+	if _, err := store.SetUserRole(ctx, database.SetUserRoleParams{Name: "bob", Role: "admin", UpdatedAt: now()}); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("promoting missing user returned %v, want sql.ErrNoRows", err)
+	}
+	if count, err := store.CountUsers(ctx); err != nil || count != 1 {
+		return fmt.Errorf("user count is %d (%v), want 1", count, err)
+	}
+
+	renamed, err := store.RenameUser(ctx, database.RenameUserParams{NewName: "carol", UpdatedAt: now(), Name: "alice"})
+	if err != nil || renamed.ID != user.ID || renamed.Name != "carol" || renamed.Role != "admin" {
+		return fmt.Errorf("renamed user is %+v (%v), want carol with the same id and role", renamed, err)
+	}
+	if _, err := createUser(ctx, store, "dave"); err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	if _, err := store.RenameUser(ctx, database.RenameUserParams{NewName: "dave", UpdatedAt: now(), Name: "carol"}); err == nil {
+		return errors.New("rename to taken user name accepted")
+	}
+	if _, err := store.RenameUser(ctx, database.RenameUserParams{NewName: "erin", UpdatedAt: now(), Name: "alice"}); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("renaming missing user returned %v, want sql.ErrNoRows", err)
+	}
+	return nil
+}
+
+func checkFeeds(ctx context.Context, store storage.Store) error {
+	user, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	feed, err := createFeed(ctx, store, user, "blog")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	if feed.FetchIntervalSeconds != 1800 || feed.MinFetchIntervalSeconds != 300 || feed.MaxFetchIntervalSeconds != 86400 {
+		return fmt.Errorf("new feed has fetch intervals %d/%d/%d, want column defaults",
+			feed.FetchIntervalSeconds, feed.MinFetchIntervalSeconds, feed.MaxFetchIntervalSeconds)
+	}
+	if _, err := createFeed(ctx, store, user, "blog"); err == nil {
+		return errors.New("duplicate feed url accepted")
+	}
+
+	// feed names are unique per user only
+	renamed := database.CreateFeedParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), Name: "blog", Url: "https://example.com/other.xml", UserID: user.ID}
+	if _, err := store.CreateFeed(ctx, renamed); err == nil {
+		return errors.New("duplicate feed name of the same user accepted")
+	}
+	other, err := createUser(ctx, store, "bob")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	renamed.UserID = other.ID
+	if _, err := store.CreateFeed(ctx, renamed); err != nil {
+		return fmt.Errorf("error creating feed named as feed of other user: %v", err)
+	}
+	if _, err := createFeed(ctx, store, database.User{ID: uuid.New()}, "orphan"); err == nil {
+		return errors.New("feed of missing user accepted")
+	}
+
+	follow := database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UpdatedAt: now(),
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	}
+	row, err := store.CreateFeedFollow(ctx, follow)
+	if err != nil {
+		return fmt.Errorf("error following feed: %v", err)
+	}
+	if row.FeedName != "blog" || row.UserName != "alice" {
+		return fmt.Errorf("follow returned names %q and %q", row.FeedName, row.UserName)
+	}
+	follow.ID = uuid.New()
+	if _, err := store.CreateFeedFollow(ctx, follow); err == nil {
+		return errors.New("duplicate follow accepted")
+	}
+
+	if err := store.DeleteFeedsFollow(ctx, database.DeleteFeedsFollowParams{Url: feed.Url, UserID: user.ID}); err != nil {
+		return fmt.Errorf("error unfollowing feed: %v", err)
+	}
+	follows, err := store.GetFeedFollowsForUser(ctx, user.ID)
+	if err != nil || len(follows) != 0 {
+		return fmt.Errorf("user follows %d feeds after unfollow (%v)", len(follows), err)
+	}
+
+	for i, want := range []int64{1, 0} {
+		deleted, err := store.DeleteFeed(ctx, feed.ID)
+		if err != nil || deleted != want {
+			return fmt.Errorf("delete %d of feed removed %d rows (%v), want %d", i+1, deleted, err, want)
+		}
+	}
+	if _, err := store.GetFeedByUrl(ctx, renamed.Url); err != nil {
+		return fmt.Errorf("feed of other user lost after deleting feed: %v", err)
+	}
+	return nil
+}
+
+func checkPosts(ctx context.Context, store storage.Store) error {
+	user, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	feed, err := createFeed(ctx, store, user, "blog")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+
+	created, err := store.CreatePosts(ctx, database.CreatePostsParams{
+		CreatedAt:    now(),
+		FeedID:       feed.ID,
+		Titles:       []string{"old", "undated", "new", "old again"},
+		Urls:         []string{"https://example.com/1", "https://example.com/2", "https://example.com/3", "https://example.com/1"},
+		OriginalUrls: []string{"https://example.com/1", "https://example.com/2", "https://example.com/3", "https://example.com/1"},
+		Descriptions: []string{"", "text", "", ""},
+		PublishedAts: []string{"2024-01-01T00:00:00Z", "", "2024-06-01T00:00:00Z", "2024-02-01T00:00:00Z"},
+		Contents:     []string{"", "", "", ""},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating posts: %v", err)
+	}
+	if len(created) != 3 {
+		return fmt.Errorf("created %d posts, want 3 with duplicate url skipped", len(created))
+	}
+
+	// posts are identified by url within their feed, titles may repeat
+	reader, err := createUser(ctx, store, "bob")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	mirror, err := createFeed(ctx, store, reader, "mirror")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	copies, err := store.CreatePosts(ctx, database.CreatePostsParams{
+		CreatedAt:    now().Add(time.Second),
+		FeedID:       mirror.ID,
+		Titles:       []string{"old", "new"},
+		Urls:         []string{"https://example.com/1", "https://example.com/5"},
+		OriginalUrls: []string{"https://example.com/1", "https://example.com/5"},
+		Descriptions: []string{"", ""},
+		PublishedAts: []string{"", ""},
+		Contents:     []string{"", ""},
+	})
+	if err != nil || len(copies) != 2 {
+		return fmt.Errorf("created %d posts in other feed (%v), want 2 with same url and title", len(copies), err)
+	}
+	if _, err := store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UpdatedAt: now(),
+		UserID:    reader.ID,
+		FeedID:    mirror.ID,
+	}); err != nil {
+		return fmt.Errorf("error following feed: %v", err)
+	}
+	// lookup by url prefers followed feeds, then the earliest copy
+	post, err := store.GetPostByUrl(ctx, database.GetPostByUrlParams{UserID: reader.ID, Url: "https://example.com/1"})
+	if err != nil || post.FeedID != mirror.ID {
+		return fmt.Errorf("post by url for follower is from feed %v (%v), want followed feed", post.FeedID, err)
+	}
+	post, err = store.GetPostByUrl(ctx, database.GetPostByUrlParams{UserID: user.ID, Url: "https://example.com/1"})
+	if err != nil || post.FeedID != feed.ID {
+		return fmt.Errorf("post by url is from feed %v (%v), want earliest copy", post.FeedID, err)
+	}
+	exists, err := store.PostExistsWithOriginalUrl(ctx, database.PostExistsWithOriginalUrlParams{FeedID: mirror.ID, OriginalUrl: "https://example.com/2"})
+	if err != nil || exists {
+		return fmt.Errorf("original url of other feed reported as stored (%v)", err)
+	}
+
+	posts, err := store.GetPostForUser(ctx, database.GetPostForUserParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		return fmt.Errorf("error getting posts: %v", err)
+	}
+	var titles []string
+	for _, post := range posts {
+		titles = append(titles, post.Title)
+	}
+	// published_at DESC puts NULLs first
+	if fmt.Sprint(titles) != "[undated new old]" {
+		return fmt.Errorf("posts in order %v, want [undated new old]", titles)
+	}
+	if posts[1].Description.Valid {
+		return errors.New("empty description stored as text, want NULL")
+	}
+	return nil
+}
+
+// Publish dates keep their instant whatever offset the feed used
+func checkPublishTimes(ctx context.Context, store storage.Store) error {
+	user, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	feed, err := createFeed(ctx, store, user, "blog")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+
+	created, err := store.CreatePosts(ctx, database.CreatePostsParams{
+		CreatedAt:    now(),
+		FeedID:       feed.ID,
+		Titles:       []string{"east", "west"},
+		Urls:         []string{"https://example.com/1", "https://example.com/2"},
+		OriginalUrls: []string{"https://example.com/1", "https://example.com/2"},
+		Descriptions: []string{"", ""},
+		PublishedAts: []string{"2024-03-01T12:00:00+02:00", "2024-03-01T09:30:00-01:00"},
+		Contents:     []string{"", ""},
+	})
+	if err != nil || len(created) != 2 {
+		return fmt.Errorf("created %d posts (%v), want 2", len(created), err)
+	}
+
+	posts, err := store.GetPostForUser(ctx, database.GetPostForUserParams{UserID: user.ID, Limit: 10})
+	if err != nil {
+		return fmt.Errorf("error getting posts: %v", err)
+	}
+	want := map[string]time.Time{
+		"east": time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC),
+		"west": time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+	}
+	for _, post := range posts {
+		if !post.PublishedAt.Valid || !post.PublishedAt.Time.Equal(want[post.Title]) {
+			return fmt.Errorf("post %s published at %v, want %v", post.Title, post.PublishedAt.Time, want[post.Title])
+		}
+	}
+	// ordered by instant, not by wall time of the feed
+	if len(posts) != 2 || posts[0].Title != "west" {
+		return fmt.Errorf("posts in order %v, want west first", posts)
+	}
+	return nil
+}
+
+func checkSearch(ctx context.Context, store storage.Store) error {
+	user, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	followed, err := createFeed(ctx, store, user, "followed")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	other, err := createFeed(ctx, store, user, "other")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	if _, err := store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UpdatedAt: now(),
+		UserID:    user.ID,
+		FeedID:    followed.ID,
+	}); err != nil {
+		return fmt.Errorf("error following feed: %v", err)
+	}
+
+	for _, feed := range []database.Feed{followed, other} {
+		if _, err := store.CreatePosts(ctx, database.CreatePostsParams{
+			CreatedAt:    now(),
+			FeedID:       feed.ID,
+			Titles:       []string{feed.Name + " gardening tips", feed.Name + " weekly notes", feed.Name + " cooking"},
+			Urls:         []string{"https://example.com/" + feed.Name + "/1", "https://example.com/" + feed.Name + "/2", "https://example.com/" + feed.Name + "/3"},
+			OriginalUrls: []string{"https://example.com/" + feed.Name + "/1", "https://example.com/" + feed.Name + "/2", "https://example.com/" + feed.Name + "/3"},
+			Descriptions: []string{"growing tomatoes", "", "tomatoes soup"},
+			PublishedAts: []string{"", "", ""},
+			Contents:     []string{"", "about gardening and tomatoes", ""},
+		}); err != nil {
+			return fmt.Errorf("error creating posts: %v", err)
+		}
+	}
+
+	search := func(query string) ([]string, error) {
+		rows, err := store.SearchPosts(ctx, database.SearchPostsParams{Query: query, UserID: user.ID, MaxResults: 10})
+		var titles []string
+		for _, row := range rows {
+			titles = append(titles, row.Title)
+		}
+		return titles, err
+	}
+
+	// title weighs more than content, posts of not followed feed are never returned
+	titles, err := search("gardening")
+	if err != nil || fmt.Sprint(titles) != "[followed gardening tips followed weekly notes]" {
+		return fmt.Errorf("search for gardening found %v (%v)", titles, err)
+	}
+	titles, err = search("tomatoes -soup")
+	if err != nil || len(titles) != 2 {
+		return fmt.Errorf("search excluding word found %v (%v)", titles, err)
+	}
+	titles, err = search(`"tomatoes soup" or weekly`)
+	if err != nil || len(titles) != 2 {
+		return fmt.Errorf("search for phrase or word found %v (%v)", titles, err)
+	}
+	return nil
+}
+
+func checkRetention(ctx context.Context, store storage.Store) error {
+	user, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	feed, err := createFeed(ctx, store, user, "blog")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	if _, err := store.CreatePosts(ctx, database.CreatePostsParams{
+		CreatedAt:    now(),
+		FeedID:       feed.ID,
+		Titles:       []string{"first", "second", "third", "fourth"},
+		Urls:         []string{"https://example.com/1", "https://example.com/2", "https://example.com/3", "https://example.com/4"},
+		OriginalUrls: []string{"https://example.com/1", "https://example.com/2", "https://example.com/3", "https://example.com/4"},
+		Descriptions: []string{"", "", "", ""},
+		PublishedAts: []string{"2024-01-01T00:00:00Z", "2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z", "2024-04-01T00:00:00Z"},
+		Contents:     []string{"", "", "", ""},
+	}); err != nil {
+		return fmt.Errorf("error creating posts: %v", err)
+	}
+
+	// starred oldest post is kept and does not count towards max posts
+	first, err := store.GetPostByUrl(ctx, database.GetPostByUrlParams{UserID: user.ID, Url: "https://example.com/1"})
+	if err != nil {
+		return fmt.Errorf("error getting post: %v", err)
+	}
+	if err := store.StarPost(ctx, database.StarPostParams{UserID: user.ID, PostID: first.ID, CreatedAt: now()}); err != nil {
+		return fmt.Errorf("error starring post: %v", err)
+	}
+	if _, err := store.SetFeedRetention(ctx, database.SetFeedRetentionParams{
+		Url:               feed.Url,
+		RetentionMaxPosts: sql.NullInt32{Int32: 2, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("error setting retention: %v", err)
+	}
+
+	counts, err := store.GetPrunablePostCounts(ctx, sql.NullTime{})
+	if err != nil || len(counts) != 1 || counts[0].Posts != 1 {
+		return fmt.Errorf("prunable posts are %v (%v), want 1 post of blog", counts, err)
+	}
+	pruned, err := store.PrunePosts(ctx, sql.NullTime{})
+	if err != nil || len(pruned) != 1 || pruned[0].Posts != 1 {
+		return fmt.Errorf("pruned posts are %v (%v), want 1 post of blog", pruned, err)
+	}
+	if _, err := store.GetPostByUrl(ctx, database.GetPostByUrlParams{UserID: user.ID, Url: "https://example.com/2"}); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("post beyond max count left (%v)", err)
+	}
+
+	// global max age prunes everything not starred
+	pruned, err = store.PrunePosts(ctx, sql.NullTime{Time: now().Add(time.Hour), Valid: true})
+	if err != nil || len(pruned) != 1 || pruned[0].Posts != 2 {
+		return fmt.Errorf("pruned posts by age are %v (%v), want 2 posts of blog", pruned, err)
+	}
+	starred, err := store.GetStarredPosts(ctx, user.ID)
+	if err != nil || len(starred) != 1 {
+		return fmt.Errorf("%d starred posts left after prune (%v), want 1", len(starred), err)
+	}
+	return nil
+}
+
+func checkTokens(ctx context.Context, store storage.Store) error {
+	alice, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	bob, err := createUser(ctx, store, "bob")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	createToken := func(user database.User, name string, hash string, expiresAt sql.NullTime) error {
+		_, err := store.CreateApiToken(ctx, database.CreateApiTokenParams{
+			ID:        uuid.New(),
+			CreatedAt: now(),
+			UserID:    user.ID,
+			Name:      name,
+			TokenHash: []byte(hash),
+			ExpiresAt: expiresAt,
+		})
+		return err
+	}
+	if err := createToken(alice, "cli", "alice-cli", sql.NullTime{}); err != nil {
+		return fmt.Errorf("error creating token: %v", err)
+	}
+	if err := createToken(alice, "old", "alice-old", sql.NullTime{Time: now().Add(-time.Hour), Valid: true}); err != nil {
+		return fmt.Errorf("error creating token: %v", err)
+	}
+	if err := createToken(alice, "cli", "alice-other", sql.NullTime{}); err == nil {
+		return errors.New("duplicate token name of user accepted")
+	}
+	if err := createToken(bob, "cli", "alice-cli", sql.NullTime{}); err == nil {
+		return errors.New("duplicate token hash accepted")
+	}
+	if err := createToken(bob, "cli", "bob-cli", sql.NullTime{}); err != nil {
+		return fmt.Errorf("token name of other user rejected: %v", err)
+	}
+
+	user, err := store.GetUserByTokenHash(ctx, database.GetUserByTokenHashParams{TokenHash: []byte("alice-cli"), ValidAt: now()})
+	if err != nil || user.ID != alice.ID {
+		return fmt.Errorf("token resolved to %q (%v), want alice", user.Name, err)
+	}
+	if _, err := store.GetUserByTokenHash(ctx, database.GetUserByTokenHashParams{TokenHash: []byte("alice-old"), ValidAt: now()}); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("expired token returned %v, want sql.ErrNoRows", err)
+	}
+	tokens, err := store.GetApiTokensForUser(ctx, alice.ID)
+	if err != nil || len(tokens) != 2 || tokens[0].Name != "cli" || tokens[1].Name != "old" {
+		return fmt.Errorf("tokens of alice are %+v (%v), want cli and old", tokens, err)
+	}
+
+	revoked, err := store.DeleteApiToken(ctx, database.DeleteApiTokenParams{UserID: alice.ID, Name: "cli"})
+	if err != nil || revoked != 1 {
+		return fmt.Errorf("revoking token deleted %d rows (%v), want 1", revoked, err)
+	}
+	if _, err := store.GetUserByTokenHash(ctx, database.GetUserByTokenHashParams{TokenHash: []byte("alice-cli"), ValidAt: now()}); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("revoked token returned %v, want sql.ErrNoRows", err)
+	}
+	if _, err := store.GetUserByTokenHash(ctx, database.GetUserByTokenHashParams{TokenHash: []byte("bob-cli"), ValidAt: now()}); err != nil {
+		return fmt.Errorf("token of bob lost after revoking token of alice: %v", err)
+	}
+	return nil
+}
+
+func checkImports(ctx context.Context, store storage.Store) error {
+	user, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	if _, err := store.SetUserRole(ctx, database.SetUserRoleParams{Name: "alice", Role: "admin", UpdatedAt: now()}); err != nil {
+		return fmt.Errorf("error promoting user: %v", err)
+	}
+
+	// rows already stored under the same natural key are skipped
+	feed := database.ImportFeedParams{
+		ID:                      uuid.New(),
+		CreatedAt:               now(),
+		UpdatedAt:               now(),
+		Name:                    "blog",
+		Url:                     "https://example.com/blog.xml",
+		UserID:                  user.ID,
+		FetchIntervalSeconds:    600,
+		MinFetchIntervalSeconds: 60,
+		MaxFetchIntervalSeconds: 3600,
+		CronSchedule:            sql.NullString{String: "0 * * * *", Valid: true},
+		RetentionMaxPosts:       sql.NullInt32{Int32: 10, Valid: true},
+	}
+	for i, want := range []int64{1, 0} {
+		feed.ID = uuid.New()
+		added, err := store.ImportFeed(ctx, feed)
+		if err != nil || added != want {
+			return fmt.Errorf("import %d of feed added %d rows (%v), want %d", i+1, added, err, want)
+		}
+	}
+	stored, err := store.GetFeedByUrl(ctx, feed.Url)
+	if err != nil || stored.FetchIntervalSeconds != 600 || stored.CronSchedule != feed.CronSchedule || stored.RetentionMaxPosts != feed.RetentionMaxPosts {
+		return fmt.Errorf("imported feed lost its settings %+v (%v)", stored, err)
+	}
+
+	post := database.ImportPostParams{
+		CreatedAt:   now(),
+		UpdatedAt:   now(),
+		Title:       "first",
+		Url:         "https://example.com/1",
+		OriginalUrl: "https://example.com/1?utm_source=rss",
+		FeedID:      stored.ID,
+	}
+	for i, want := range []int64{1, 0} {
+		post.ID = uuid.New()
+		added, err := store.ImportPost(ctx, post)
+		if err != nil || added != want {
+			return fmt.Errorf("import %d of post added %d rows (%v), want %d", i+1, added, err, want)
+		}
+	}
+	postID, err := store.GetFeedPostId(ctx, database.GetFeedPostIdParams{FeedID: stored.ID, Url: post.Url})
+	if err != nil {
+		return fmt.Errorf("error getting imported post: %v", err)
+	}
+
+	for i, want := range []int64{1, 0} {
+		added, err := store.ImportFeedFollow(ctx, database.ImportFeedFollowParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), UserID: user.ID, FeedID: stored.ID})
+		if err != nil || added != want {
+			return fmt.Errorf("import %d of follow added %d rows (%v), want %d", i+1, added, err, want)
+		}
+		added, err = store.ImportStarredPost(ctx, database.ImportStarredPostParams{UserID: user.ID, PostID: postID, CreatedAt: now()})
+		if err != nil || added != want {
+			return fmt.Errorf("import %d of star added %d rows (%v), want %d", i+1, added, err, want)
+		}
+		added, err = store.ImportApiToken(ctx, database.ImportApiTokenParams{
+			ID:        uuid.New(),
+			CreatedAt: now(),
+			UserID:    user.ID,
+			Name:      "cli",
+			TokenHash: []byte("hash"),
+			ExpiresAt: sql.NullTime{Time: now().Add(time.Hour), Valid: true},
+		})
+		if err != nil || added != want {
+			return fmt.Errorf("import %d of token added %d rows (%v), want %d", i+1, added, err, want)
+		}
+	}
+
+	// archive survives replacing the database with itself
+	exported, err := archive.Export(ctx, store)
+	if err != nil {
+		return fmt.Errorf("error exporting: %v", err)
+	}
+	imported, err := archive.Import(ctx, store, exported, archive.Replace)
+	if err != nil {
+		return fmt.Errorf("error importing: %v", err)
+	}
+	if imported != exported.Count() || imported != (archive.Stats{Users: 1, Feeds: 1, Posts: 1, Follows: 1, Starred: 1, Tokens: 1}) {
+		return fmt.Errorf("replace import added %+v, archive has %+v", imported, exported.Count())
+	}
+	reexported, err := archive.Export(ctx, store)
+	if err != nil {
+		return fmt.Errorf("error exporting: %v", err)
+	}
+	reexported.ExportedAt = exported.ExportedAt
+	before, _ := json.Marshal(exported)
+	after, _ := json.Marshal(reexported)
+	if !bytes.Equal(before, after) {
+		return fmt.Errorf("archive changed after import:\n%s\nwant\n%s", after, before)
+	}
+	return nil
+}
+
+// Audit log outlives users, so entries are written in transaction that is rolled back
+func checkAudit(ctx context.Context, store storage.Store) error {
+	errRollback := errors.New("rollback")
+	err := store.InTx(ctx, func(q database.Querier) error {
+		user, err := createUser(ctx, q, "alice")
+		if err != nil {
+			return fmt.Errorf("error creating user: %v", err)
+		}
+		// bob was deleted by the audited command, so only his name is kept
+		ids := []uuid.UUID{user.ID, uuid.New(), uuid.Nil}
+		for i, name := range []string{"alice", "bob", ""} {
+			if err := q.CreateAuditEntry(ctx, database.CreateAuditEntryParams{
+				ID:        uuid.New(),
+				CreatedAt: now().Add(time.Duration(i) * time.Second),
+				UserID:    uuid.NullUUID{UUID: ids[i], Valid: name != ""},
+				UserName:  sql.NullString{String: name, Valid: name != ""},
+				Command:   "addfeed",
+				Args:      `["blog"]`,
+				Succeeded: true,
+			}); err != nil {
+				return fmt.Errorf("error creating audit entry: %v", err)
+			}
+		}
+
+		entries, err := q.GetAuditEntries(ctx, database.GetAuditEntriesParams{Since: now().Add(-time.Hour), MaxEntries: 2})
+		if err != nil || len(entries) != 2 || entries[0].UserName.Valid || entries[1].UserName.String != "bob" || entries[1].UserID.Valid {
+			return fmt.Errorf("latest audit entries are %+v (%v), want anonymous and unlinked bob", entries, err)
+		}
+		entries, err = q.GetAuditEntries(ctx, database.GetAuditEntriesParams{
+			UserName:   sql.NullString{String: "alice", Valid: true},
+			MaxEntries: 10,
+		})
+		if err != nil || len(entries) != 1 || entries[0].UserID != (uuid.NullUUID{UUID: user.ID, Valid: true}) {
+			return fmt.Errorf("audit entries of alice are %+v (%v), want one linked to her", entries, err)
+		}
+		if _, err := q.RenameUser(ctx, database.RenameUserParams{NewName: "alicia", UpdatedAt: now(), Name: "alice"}); err != nil {
+			return fmt.Errorf("error renaming user: %v", err)
+		}
+		entries, err = q.GetAuditEntries(ctx, database.GetAuditEntriesParams{
+			UserName:   sql.NullString{String: "alicia", Valid: true},
+			MaxEntries: 10,
+		})
+		if err != nil || len(entries) != 1 || entries[0].UserName.String != "alice" {
+			return fmt.Errorf("audit entries of renamed alice are %+v (%v), want one under her old name", entries, err)
+		}
+
+		if err := q.DeleteUsers(ctx); err != nil {
+			return fmt.Errorf("error deleting users: %v", err)
+		}
+		entries, err = q.GetAuditEntries(ctx, database.GetAuditEntriesParams{
+			UserName:   sql.NullString{String: "alice", Valid: true},
+			MaxEntries: 10,
+		})
+		if err != nil || len(entries) != 1 || entries[0].UserID.Valid || entries[0].UserName.String != "alice" {
+			return fmt.Errorf("audit entries of deleted alice are %+v (%v), want one keeping her name", entries, err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return err
+	}
+	return nil
+}
+
+func checkClaims(ctx context.Context, store storage.Store) error {
+	user, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	instance := database.HeartbeatAggInstanceParams{ID: uuid.New(), Hostname: "test", Pid: 1, StartedAt: now()}
+	if err := store.HeartbeatAggInstance(ctx, instance); err != nil {
+		return fmt.Errorf("error registering instance: %v", err)
+	}
+
+	// only daemons count as serving the refresh queue
+	if live, err := store.CountLiveAggDaemons(ctx, 60); err != nil || live != 0 {
+		return fmt.Errorf("%d live daemons (%v), want 0 with one-off instance only", live, err)
+	}
+	daemon := database.HeartbeatAggInstanceParams{ID: uuid.New(), Hostname: "test", Pid: 2, StartedAt: now(), Daemon: true}
+	if err := store.HeartbeatAggInstance(ctx, daemon); err != nil {
+		return fmt.Errorf("error registering daemon: %v", err)
+	}
+	if live, err := store.CountLiveAggDaemons(ctx, 60); err != nil || live != 1 {
+		return fmt.Errorf("%d live daemons (%v), want 1", live, err)
+	}
+	if err := store.DeleteAggInstance(ctx, daemon.ID); err != nil {
+		return fmt.Errorf("error deleting daemon: %v", err)
+	}
+
+	var feeds []database.Feed
+	for i, nextFetch := range []time.Duration{-time.Hour, 0, -2 * time.Hour} {
+		feed, err := createFeed(ctx, store, user, fmt.Sprintf("feed%d", i))
+		if err != nil {
+			return fmt.Errorf("error creating feed: %v", err)
+		}
+		if nextFetch != 0 {
+			if _, err := store.ScheduleFeedFetch(ctx, database.ScheduleFeedFetchParams{
+				ID:                   feed.ID,
+				FetchIntervalSeconds: feed.FetchIntervalSeconds,
+				NextFetchAt:          sql.NullTime{Time: now().Add(nextFetch), Valid: true},
+			}); err != nil {
+				return fmt.Errorf("error scheduling feed: %v", err)
+			}
+		}
+		feeds = append(feeds, feed)
+	}
+
+	// next_fetch_at ASC NULLS FIRST, never fetched feed goes first
+	claimed, err := store.ClaimDueFeeds(ctx, database.ClaimDueFeedsParams{
+		MaxFeeds:     2,
+		InstanceID:   uuid.NullUUID{UUID: instance.ID, Valid: true},
+		LeaseSeconds: 60,
+	})
+	if err != nil {
+		return fmt.Errorf("error claiming feeds: %v", err)
+	}
+	claimedIDs := map[uuid.UUID]bool{}
+	for _, feed := range claimed {
+		claimedIDs[feed.ID] = true
+	}
+	if len(claimed) != 2 || !claimedIDs[feeds[1].ID] || !claimedIDs[feeds[2].ID] {
+		return fmt.Errorf("claimed %d feeds, want unscheduled and most overdue one", len(claimed))
+	}
+	if _, err := store.ClaimFeed(ctx, database.ClaimFeedParams{ID: feeds[1].ID, LeaseSeconds: 60}); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("claiming claimed feed returned %v, want sql.ErrNoRows", err)
+	}
+
+	// heartbeat renews leases of held feeds only
+	extended, err := store.ExtendFeedClaims(ctx, database.ExtendFeedClaimsParams{
+		LeaseSeconds: 3600,
+		InstanceID:   uuid.NullUUID{UUID: instance.ID, Valid: true},
+	})
+	if err != nil || extended != 2 {
+		return fmt.Errorf("extended %d claims (%v), want 2", extended, err)
+	}
+	renewed, err := store.GetFeedById(ctx, feeds[1].ID)
+	if err != nil || !renewed.ClaimedUntil.Valid || renewed.ClaimedUntil.Time.Before(now().Add(30*time.Minute)) {
+		return fmt.Errorf("renewed claim lasts until %v (%v), want an hour from now", renewed.ClaimedUntil, err)
+	}
+
+	// removing instance releases its feeds
+	if err := store.DeleteAggInstance(ctx, instance.ID); err != nil {
+		return fmt.Errorf("error deleting instance: %v", err)
+	}
+	feed, err := store.GetFeedById(ctx, feeds[1].ID)
+	if err != nil {
+		return fmt.Errorf("error getting feed: %v", err)
+	}
+	if feed.ClaimedBy.Valid {
+		return errors.New("feed still claimed by deleted instance")
+	}
+	return nil
+}
+
+func checkTransactions(ctx context.Context, store storage.Store) error {
+	errRollback := errors.New("rollback")
+	err := store.InTx(ctx, func(q database.Querier) error {
+		if _, err := createUser(ctx, q, "alice"); err != nil {
+			return err
+		}
+		if _, err := q.GetUser(ctx, "alice"); err != nil {
+			return fmt.Errorf("user created in transaction not visible in it: %v", err)
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		return fmt.Errorf("transaction returned %v, want error of its function", err)
+	}
+	if _, err := store.GetUser(ctx, "alice"); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user of rolled back transaction found (%v)", err)
+	}
+
+	err = store.InTx(ctx, func(q database.Querier) error {
+		_, err := createUser(ctx, q, "alice")
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error committing transaction: %v", err)
+	}
+	if _, err := store.GetUser(ctx, "alice"); err != nil {
+		return fmt.Errorf("user of committed transaction missing: %v", err)
+	}
+	return nil
+}
+
+func checkCascades(ctx context.Context, store storage.Store) error {
+	owner, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	follower, err := createUser(ctx, store, "bob")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	feed, err := createFeed(ctx, store, owner, "blog")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	if _, err := store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UpdatedAt: now(),
+		UserID:    follower.ID,
+		FeedID:    feed.ID,
+	}); err != nil {
+		return fmt.Errorf("error following feed: %v", err)
+	}
+	if err := store.SetFeedCredentials(ctx, database.SetFeedCredentialsParams{
+		FeedID:    feed.ID,
+		CreatedAt: now(),
+		UpdatedAt: now(),
+		Sealed:    []byte("sealed"),
+	}); err != nil {
+		return fmt.Errorf("error storing credentials: %v", err)
+	}
+	if err := store.EnqueueFeedFetch(ctx, database.EnqueueFeedFetchParams{
+		ID:          uuid.New(),
+		FeedID:      feed.ID,
+		RequestedAt: now(),
+		RequestedBy: uuid.NullUUID{UUID: follower.ID, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("error queueing fetch: %v", err)
+	}
+
+	if err := store.DeleteFeeds(ctx); err != nil {
+		return fmt.Errorf("error deleting feeds: %v", err)
+	}
+	follows, err := store.GetFeedFollowsForUser(ctx, follower.ID)
+	if err != nil || len(follows) != 0 {
+		return fmt.Errorf("%d follows left after deleting feed (%v)", len(follows), err)
+	}
+	if _, err := store.GetFeedCredentials(ctx, feed.ID); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("credentials left after deleting feed (%v)", err)
+	}
+	queued, err := store.CountQueuedFeeds(ctx)
+	if err != nil || queued != 0 {
+		return fmt.Errorf("%d fetches queued after deleting feed (%v)", queued, err)
+	}
+	return nil
+}
+
+// Feeds outlive their creator when transferred before the creator is deleted
+func checkOwnership(ctx context.Context, store storage.Store) error {
+	owner, err := createUser(ctx, store, "alice")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	follower, err := createUser(ctx, store, "bob")
+	if err != nil {
+		return fmt.Errorf("error creating user: %v", err)
+	}
+	feed, err := createFeed(ctx, store, owner, "blog")
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	if _, err := createFeed(ctx, store, owner, "news"); err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	for _, user := range []database.User{owner, follower} {
+		if _, err := store.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: now(),
+			UpdatedAt: now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+		}); err != nil {
+			return fmt.Errorf("error following feed: %v", err)
+		}
+	}
+	if _, err := store.CreatePosts(ctx, database.CreatePostsParams{
+		CreatedAt:    now(),
+		FeedID:       feed.ID,
+		Titles:       []string{"first", "second"},
+		Urls:         []string{"https://example.com/1", "https://example.com/2"},
+		OriginalUrls: []string{"https://example.com/1", "https://example.com/2"},
+		Descriptions: []string{"", ""},
+		PublishedAts: []string{"", ""},
+		Contents:     []string{"", ""},
+	}); err != nil {
+		return fmt.Errorf("error creating posts: %v", err)
+	}
+	if _, err := store.CreateApiToken(ctx, database.CreateApiTokenParams{
+		ID:        uuid.New(),
+		CreatedAt: now(),
+		UserID:    owner.ID,
+		Name:      "default",
+		TokenHash: []byte("ownership"),
+	}); err != nil {
+		return fmt.Errorf("error creating token: %v", err)
+	}
+
+	activity, err := store.GetUserActivity(ctx, owner.ID)
+	want := database.GetUserActivityRow{Follows: 1, Tokens: 1, FollowedPosts: 2}
+	if err != nil || activity != want {
+		return fmt.Errorf("activity of owner is %+v (%v), want %+v", activity, err, want)
+	}
+	owned, err := store.GetOwnedFeeds(ctx, owner.ID)
+	if err != nil || len(owned) != 2 {
+		return fmt.Errorf("owner has %d feeds (%v), want 2", len(owned), err)
+	}
+	if owned[0].Name != "blog" || owned[0].OtherFollowers != 1 || owned[0].Posts != 2 {
+		return fmt.Errorf("owned feed is %+v, want blog with 1 other follower and 2 posts", owned[0])
+	}
+
+	// feed names are unique per user, so a clash fails the whole transfer
+	clash, err := store.CreateFeed(ctx, database.CreateFeedParams{ID: uuid.New(), CreatedAt: now(), UpdatedAt: now(), Name: "news", Url: "https://example.com/other.xml", UserID: follower.ID})
+	if err != nil {
+		return fmt.Errorf("error creating feed: %v", err)
+	}
+	transfer := database.TransferFeedsParams{ToUserID: follower.ID, UpdatedAt: now(), FromUserID: owner.ID}
+	if _, err := store.TransferFeeds(ctx, transfer); err == nil {
+		return errors.New("transfer clashing with feed name of new owner accepted")
+	}
+	if owned, err := store.GetOwnedFeeds(ctx, owner.ID); err != nil || len(owned) != 2 {
+		return fmt.Errorf("owner has %d feeds after failed transfer (%v), want 2", len(owned), err)
+	}
+	if _, err := store.DeleteFeed(ctx, clash.ID); err != nil {
+		return fmt.Errorf("error deleting feed: %v", err)
+	}
+	if transferred, err := store.TransferFeeds(ctx, transfer); err != nil || transferred != 2 {
+		return fmt.Errorf("transferred %d feeds (%v), want 2", transferred, err)
+	}
+
+	deleted, err := store.DeleteUser(ctx, owner.ID)
+	if err != nil || deleted != 1 {
+		return fmt.Errorf("deleted %d users (%v), want 1", deleted, err)
+	}
+	if deleted, err := store.DeleteUser(ctx, owner.ID); err != nil || deleted != 0 {
+		return fmt.Errorf("deleting missing user deleted %d (%v), want 0", deleted, err)
+	}
+	if _, err := store.GetUserByTokenHash(ctx, database.GetUserByTokenHashParams{TokenHash: []byte("ownership"), ValidAt: now()}); !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("token of deleted user returned %v, want sql.ErrNoRows", err)
+	}
+	activity, err = store.GetUserActivity(ctx, follower.ID)
+	want = database.GetUserActivityRow{Follows: 1, FollowedPosts: 2}
+	if err != nil || activity != want {
+		return fmt.Errorf("activity of follower after deleting owner is %+v (%v), want %+v", activity, err, want)
+	}
+	if owned, err := store.GetOwnedFeeds(ctx, follower.ID); err != nil || len(owned) != 2 || owned[0].OtherFollowers != 0 {
+		return fmt.Errorf("new owner has feeds %+v (%v), want 2 without other followers", owned, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Options accepted before command name, eg. gator --log-level debug agg 1m
+type globalOptions struct {
+	logLevel  string
+	logFormat string
+}
+
+// Splits leading global flags from command line arguments
+func parseGlobalFlags(args []string) (globalOptions, []string, error) {
+	opts := globalOptions{logLevel: "info", logFormat: "text"}
+
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		flag := args[0]
+		if len(args) < 2 {
+			return opts, nil, fmt.Errorf("%s expects a value", flag)
+		}
+
+		switch flag {
+		case "--log-level":
+			opts.logLevel = args[1]
+		case "--log-format":
+			opts.logFormat = args[1]
+		default:
+			return opts, nil, fmt.Errorf("unknown flag %s", flag)
+		}
+		args = args[2:]
+	}
+
+	return opts, args, nil
+}
+
+// Sets default slog logger writing diagnostics to stderr, stdout is left for command output
+func setupLogger(opts globalOptions) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(opts.logLevel)); err != nil {
+		return fmt.Errorf("invalid log level %s, expected debug, info, warn or error", opts.logLevel)
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch opts.logFormat {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, handlerOpts)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, handlerOpts)
+	default:
+		return fmt.Errorf("invalid log format %s, expected text or json", opts.logFormat)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
@@ -1,72 +1,107 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/MichalGul/blog_aggregator/internal/config"
-	"github.com/MichalGul/blog_aggregator/internal/database"
-
-	_ "github.com/lib/pq"
+	"github.com/MichalGul/blog_aggregator/internal/storage"
 )
 
 func main() {
 
+	globalOpts, providedCommands, flagsErr := parseGlobalFlags(os.Args[1:])
+	if flagsErr != nil {
+		fmt.Fprintln(os.Stderr, flagsErr)
+		os.Exit(1)
+	}
+	if logErr := setupLogger(globalOpts); logErr != nil {
+		fmt.Fprintln(os.Stderr, logErr)
+		os.Exit(1)
+	}
+
 	configData, err := config.Read()
 	if err != nil {
-		fmt.Printf("Error reading config data %v", err)
+		slog.Error("error reading config data", "error", err)
 		os.Exit(1)
 	}
-	// fmt.Printf("Config %v \n", configData)
 
-	db, db_err := sql.Open("postgres", configData.DB_URL)
+	store, db_err := storage.Open(configData.DB_URL)
 	if db_err != nil {
-		fmt.Printf("Error connecting to database using %s , error: %v", configData.DB_URL, db_err)
+		slog.Error("error connecting to database", "error", db_err)
 		os.Exit(1)
 	}
-
-	dbQueries := database.New(db)
-	defer db.Close()
+	defer store.Close()
 
 	appState := state{
-		db:     dbQueries,
+		db:     store,
 		config: &configData,
 	}
 
 	cliCommands := commands{
-		handableCommands: map[string]func(*state, command) error{},
+		handableCommands: map[string]func(context.Context, *state, command) error{},
 	}
 
 	cliCommands.register("login", handlerLogin)
 	cliCommands.register("register", handleRegister)
-	cliCommands.register("reset", handleReset)
+	cliCommands.register("token", middlewareLoggedIn(handleToken))
+	cliCommands.register("promote", middlewareAdmin(handlePromote))
+	cliCommands.register("user", middlewareLoggedIn(handleUser))
+	cliCommands.register("reset", middlewareAdmin(handleReset))
 	cliCommands.register("users", handlerUsers)
 	cliCommands.register("agg", handleAgg)
+	cliCommands.register("fetchlog", handleFetchLog)
 	cliCommands.register("addfeed", middlewareLoggedIn(handleAddFeed))
+	cliCommands.register("feedauth", middlewareLoggedIn(handleFeedAuth))
+	cliCommands.register("deletefeed", middlewareLoggedIn(handleDeleteFeed))
 	cliCommands.register("feeds", handleFeeds)
+	cliCommands.register("feedinterval", middlewareLoggedIn(handleFeedInterval))
+	cliCommands.register("enablefeed", middlewareLoggedIn(handleEnableFeed))
+	cliCommands.register("schedule", middlewareLoggedIn(handleSchedule))
 	cliCommands.register("follow", middlewareLoggedIn(handleFollow))
 	cliCommands.register("following", middlewareLoggedIn(handleFollowing))
 	cliCommands.register("unfollow", middlewareLoggedIn(handleUnfollow))
 	cliCommands.register("browse", middlewareLoggedIn(handleBrowse))
-
-	
-
-
-	providedCommands := os.Args
-	if len(providedCommands) < 2 {
-		fmt.Println("Missing arguments")
+	cliCommands.register("search", middlewareLoggedIn(handleSearch))
+	cliCommands.register("star", middlewareLoggedIn(handleStar))
+	cliCommands.register("unstar", middlewareLoggedIn(handleUnstar))
+	cliCommands.register("starred", middlewareLoggedIn(handleStarred))
+	cliCommands.register("retention", middlewareLoggedIn(handleRetention))
+	cliCommands.register("prune", middlewareAdmin(handlePrune))
+	cliCommands.register("refresh", middlewareLoggedIn(handleRefresh))
+	cliCommands.register("migrate", handleMigrate)
+	cliCommands.register("export", middlewareAdmin(handleExport))
+	cliCommands.register("import", middlewareAdmin(handleImport))
+	cliCommands.register("audit", middlewareAdmin(handleAudit))
+
+	if len(providedCommands) < 1 {
+		fmt.Fprintln(os.Stderr, "Missing arguments")
 		os.Exit(1)
 	}
 
 	command := command{
-		name: providedCommands[1],
-		args: providedCommands[2:],
+		name: providedCommands[0],
+		args: providedCommands[1:],
+	}
+
+	// Root context is cancelled on Ctrl-C or SIGTERM so handlers can stop in-flight work
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if command.name != "migrate" {
+		if schemaErr := checkSchema(ctx, &appState); schemaErr != nil {
+			slog.Error("command failed", "command", command.name, "error", schemaErr)
+			os.Exit(1)
+		}
 	}
 
-	cmdErr := cliCommands.run(&appState, command)
+	cmdErr := middlewareAudit(cliCommands.run)(ctx, &appState, command)
 	if cmdErr != nil {
-		fmt.Println(cmdErr)
+		slog.Error("command failed", "command", command.name, "error", cmdErr)
 		os.Exit(1)
 	}
 
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/config"
+	"github.com/MichalGul/blog_aggregator/internal/database"
+	"github.com/MichalGul/blog_aggregator/internal/storage"
+	"github.com/google/uuid"
+)
+
+// State backed by in-memory store, config is never written to disk
+func newTestState(t *testing.T) *state {
+	t.Helper()
+	return &state{db: storage.NewMemory(), config: &config.Config{}}
+}
+
+func createTestUser(t *testing.T, s *state, name string) database.User {
+	t.Helper()
+	user, err := s.db.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+	})
+	if err != nil {
+		t.Fatalf("error creating user %s: %v", name, err)
+	}
+	return user
+}
+
+func createTestFeed(t *testing.T, s *state, user database.User, name, url string) database.Feed {
+	t.Helper()
+	feed, err := s.db.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      name,
+		Url:       url,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		t.Fatalf("error creating feed %s: %v", name, err)
+	}
+	return feed
+}
+
+// Runs fn with stdout captured, handlers print their results there
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("error creating pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+	fn()
+	writer.Close()
+	return <-output
+}
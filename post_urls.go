@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MichalGul/blog_aggregator/internal/canonical"
+	"github.com/MichalGul/blog_aggregator/internal/database"
+)
+
+// Time limit of following single post link to its canonical URL
+const resolveUrlTimeout = 10 * time.Second
+
+// Tracking parameters stripped from post links, tracking_params in config replaces the defaults
+func trackingParams(s *state) []string {
+	if len(s.config.TRACKING_PARAMS) > 0 {
+		return s.config.TRACKING_PARAMS
+	}
+	return canonical.DefaultTrackingParams
+}
+
+// Canonical URLs of feed items in their order. Items without link get empty URL so they are
+// left out. When resolve_canonical_urls is set links are followed to canonical URL of the
+// page, items the feed already stored under their original link are not resolved again
+func canonicalPostUrls(ctx context.Context, s *state, feed database.Feed, items []RSSItem) []string {
+	params := trackingParams(s)
+	client := &http.Client{Timeout: resolveUrlTimeout}
+
+	urls := make([]string, len(items))
+	for i, item := range items {
+		link := strings.TrimSpace(item.Link)
+		if link == "" {
+			continue
+		}
+		if s.config.RESOLVE_CANONICAL_URLS {
+			exists, existsErr := s.db.PostExistsWithOriginalUrl(ctx, database.PostExistsWithOriginalUrlParams{
+				FeedID:      feed.ID,
+				OriginalUrl: item.Link,
+			})
+			if existsErr != nil {
+				slog.Warn("error checking stored post", "url", item.Link, "error", existsErr)
+			}
+			if exists {
+				continue
+			}
+
+			resolved, resolveErr := canonical.Resolve(ctx, client, item.Link)
+			if resolveErr != nil {
+				slog.Debug("keeping post link unresolved", "url", item.Link, "error", resolveErr)
+			} else {
+				link = resolved
+			}
+		}
+
+		normalized, normalizeErr := canonical.Normalize(link, params)
+		if normalizeErr != nil {
+			slog.Debug("keeping post link as is", "url", link, "error", normalizeErr)
+			normalized = link
+		}
+		urls[i] = normalized
+	}
+	return urls
+}
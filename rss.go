@@ -14,4 +14,6 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	// Full article from content module, HTML
+	Content string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 }
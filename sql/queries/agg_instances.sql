@@ -0,0 +1,23 @@
+-- name: HeartbeatAggInstance :exec
+INSERT INTO agg_instances (id, hostname, pid, started_at, daemon, heartbeat_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW()
+)
+ON CONFLICT (id) DO UPDATE SET heartbeat_at = NOW();
+
+-- name: DeleteAggInstance :exec
+DELETE FROM agg_instances WHERE id = $1;
+
+-- name: DeleteStaleAggInstances :execrows
+DELETE FROM agg_instances
+WHERE heartbeat_at < NOW() - (sqlc.arg(stale_seconds)::integer * INTERVAL '1 second');
+
+-- name: CountLiveAggDaemons :one
+SELECT COUNT(*) FROM agg_instances
+WHERE daemon
+AND heartbeat_at >= NOW() - (sqlc.arg(stale_seconds)::integer * INTERVAL '1 second');
@@ -0,0 +1,123 @@
+-- name: GetAllFeeds :many
+SELECT * FROM feeds ORDER BY created_at, url;
+
+-- name: GetAllFeedFollows :many
+SELECT * FROM feed_follows ORDER BY created_at, id;
+
+-- name: GetAllPosts :many
+SELECT  id,
+        created_at,
+        updated_at,
+        title,
+        url,
+        description,
+        published_at,
+        feed_id,
+        content,
+        original_url
+FROM posts
+ORDER BY feed_id, created_at, url;
+
+-- name: GetAllStarredPosts :many
+SELECT * FROM starred_posts ORDER BY created_at, user_id, post_id;
+
+-- name: GetAllFeedCredentials :many
+SELECT * FROM feed_credentials ORDER BY feed_id;
+
+-- name: GetFeedPostId :one
+SELECT id FROM posts WHERE feed_id = $1 AND url = $2;
+
+-- name: ImportFeed :execrows
+INSERT INTO feeds (
+    id,
+    created_at,
+    updated_at,
+    name,
+    url,
+    user_id,
+    last_fetched_at,
+    next_fetch_at,
+    fetch_interval_seconds,
+    min_fetch_interval_seconds,
+    max_fetch_interval_seconds,
+    consecutive_failures,
+    last_error,
+    last_succeeded_at,
+    disabled_at,
+    cron_schedule,
+    retention_max_posts,
+    retention_max_age_seconds
+)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    $11,
+    $12,
+    $13,
+    $14,
+    $15,
+    $16,
+    $17,
+    $18
+)
+ON CONFLICT (url) DO NOTHING;
+
+-- name: ImportFeedFollow :execrows
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (user_id, feed_id) DO NOTHING;
+
+-- name: ImportPost :execrows
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, original_url)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+ON CONFLICT (feed_id, url) DO NOTHING;
+
+-- name: ImportStarredPost :execrows
+INSERT INTO starred_posts (user_id, post_id, created_at, note)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, post_id) DO NOTHING;
+
+-- name: GetAllApiTokens :many
+SELECT * FROM api_tokens ORDER BY user_id, created_at, name;
+
+-- name: ImportApiToken :execrows
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6
+)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,24 @@
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (id, created_at, user_id, user_name, command, args, succeeded, error, duration_ms)
+VALUES (
+    sqlc.arg(id),
+    sqlc.arg(created_at),
+    (SELECT users.id FROM users WHERE users.id = sqlc.narg(user_id)::uuid),
+    sqlc.narg(user_name),
+    sqlc.arg(command),
+    sqlc.arg(args),
+    sqlc.arg(succeeded),
+    sqlc.narg(error),
+    sqlc.arg(duration_ms)
+);
+
+-- name: GetAuditEntries :many
+SELECT * FROM audit_log
+WHERE (
+    sqlc.narg(user_name)::text IS NULL
+    OR audit_log.user_name = sqlc.narg(user_name)
+    OR audit_log.user_id = (SELECT users.id FROM users WHERE users.name = sqlc.narg(user_name))
+)
+AND audit_log.created_at >= sqlc.arg(since)
+ORDER BY audit_log.created_at DESC
+LIMIT sqlc.arg(max_entries);
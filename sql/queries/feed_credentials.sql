@@ -0,0 +1,17 @@
+-- name: SetFeedCredentials :exec
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, sealed)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET sealed = EXCLUDED.sealed,
+updated_at = EXCLUDED.updated_at;
+
+-- name: GetFeedCredentials :one
+SELECT sealed FROM feed_credentials WHERE feed_id = $1;
+
+-- name: DeleteFeedCredentials :execrows
+DELETE FROM feed_credentials WHERE feed_id = $1;
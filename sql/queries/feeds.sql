@@ -19,7 +19,23 @@ SELECT * FROM feeds WHERE feeds.url=$1;
 -- name: DeleteFeeds :exec
 DELETE from feeds;
 
+-- name: DeleteFeed :execrows
+DELETE FROM feeds WHERE id = $1;
 
+-- name: GetOwnedFeeds :many
+SELECT  feeds.id,
+        feeds.name,
+        feeds.url,
+        (SELECT count(*) FROM feed_follows
+         WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> feeds.user_id) AS other_followers,
+        (SELECT count(*) FROM posts WHERE posts.feed_id = feeds.id) AS posts
+FROM feeds
+WHERE feeds.user_id = $1
+ORDER BY feeds.created_at, feeds.name;
+
+-- name: TransferFeeds :execrows
+UPDATE feeds SET user_id = sqlc.arg(to_user_id), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(from_user_id);
 
 -- name: CreateFeedFollow :one
 WITH inserted_feed_follow as (
@@ -49,21 +65,140 @@ SELECT  feed_follows.*,
  INNER JOIN users ON feed_follows.user_id = users.id
  WHERE feed_follows.user_id = $1;
 
--- name: MarkFeedFetched :one
-UPDATE feeds
-SET last_fetched_at = NOW(),
-updated_at = NOW()
-WHERE id = $1
-RETURNING *;
-
 -- name: DeleteFeedsFollow :exec
 WITH selected_feed_id as (
     SELECT feeds.id from feeds WHERE feeds.url = $1
 )
  DELETE FROM feed_follows WHERE feed_follows.user_id = $2 AND feed_follows.feed_id = (SELECT id from selected_feed_id);
 
--- name: GetNextFeedToFetch :one
-SELECT * FROM feeds ORDER BY last_fetched_at ASC NULLS FIRST LIMIT 1;
+-- name: ScheduleFeedFetch :one
+UPDATE feeds
+SET fetch_interval_seconds = $2,
+next_fetch_at = $3,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetFeedFetchBounds :one
+UPDATE feeds
+SET min_fetch_interval_seconds = $2,
+max_fetch_interval_seconds = $3,
+fetch_interval_seconds = LEAST(GREATEST(fetch_interval_seconds, $2), $3),
+updated_at = NOW()
+WHERE url = $1
+RETURNING *;
 
 -- name: GetFeedById :one
-SELECT * FROM feeds WHERE feeds.id = $1;
\ No newline at end of file
+SELECT * FROM feeds WHERE feeds.id = $1;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+last_error = NULL,
+last_succeeded_at = NOW(),
+updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+last_error = $2,
+next_fetch_at = $3,
+disabled_at = CASE WHEN consecutive_failures + 1 >= sqlc.arg(max_failures)::integer THEN NOW() ELSE disabled_at END,
+updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: EnableFeed :one
+UPDATE feeds
+SET disabled_at = NULL,
+consecutive_failures = 0,
+next_fetch_at = NULL,
+updated_at = NOW()
+WHERE url = $1
+RETURNING *;
+
+-- name: GetBrokenFeeds :many
+SELECT * FROM feeds
+WHERE disabled_at IS NOT NULL OR consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name;
+
+-- name: ClaimDueFeeds :many
+WITH claimable AS (
+    SELECT feeds.id FROM feeds
+    WHERE disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+    AND (claimed_by IS NULL OR claimed_until < NOW())
+    AND (NOT sqlc.arg(scheduled_only)::boolean OR cron_schedule IS NOT NULL)
+    ORDER BY next_fetch_at ASC NULLS FIRST
+    LIMIT sqlc.arg(max_feeds)
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE feeds
+SET claimed_by = sqlc.arg(instance_id),
+claimed_until = NOW() + (sqlc.arg(lease_seconds)::integer * INTERVAL '1 second'),
+last_fetched_at = NOW(),
+updated_at = NOW()
+FROM claimable
+WHERE feeds.id = claimable.id
+RETURNING feeds.*;
+
+-- name: ClaimActiveFeeds :many
+WITH claimable AS (
+    SELECT feeds.id FROM feeds
+    WHERE disabled_at IS NULL
+    AND (claimed_by IS NULL OR claimed_until < NOW())
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE feeds
+SET claimed_by = sqlc.arg(instance_id),
+claimed_until = NOW() + (sqlc.arg(lease_seconds)::integer * INTERVAL '1 second'),
+last_fetched_at = NOW(),
+updated_at = NOW()
+FROM claimable
+WHERE feeds.id = claimable.id
+RETURNING feeds.*;
+
+-- name: ClaimFeed :one
+UPDATE feeds
+SET claimed_by = sqlc.arg(instance_id),
+claimed_until = NOW() + (sqlc.arg(lease_seconds)::integer * INTERVAL '1 second'),
+last_fetched_at = NOW(),
+updated_at = NOW()
+WHERE id = sqlc.arg(id)
+AND (claimed_by IS NULL OR claimed_until < NOW())
+RETURNING *;
+
+-- name: ExtendFeedClaims :execrows
+UPDATE feeds
+SET claimed_until = NOW() + (sqlc.arg(lease_seconds)::integer * INTERVAL '1 second')
+WHERE claimed_by = sqlc.arg(instance_id);
+
+-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_by = NULL,
+claimed_until = NULL
+WHERE id = $1;
+
+-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE disabled_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+AND (claimed_by IS NULL OR claimed_until < NOW());
+
+
+-- name: SetFeedSchedule :one
+UPDATE feeds
+SET cron_schedule = $2,
+next_fetch_at = $3,
+updated_at = NOW()
+WHERE url = $1
+RETURNING *;
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET retention_max_posts = $2,
+retention_max_age_seconds = $3,
+updated_at = NOW()
+WHERE url = $1
+RETURNING *;
@@ -0,0 +1,36 @@
+-- name: EnqueueFeedFetch :exec
+INSERT INTO fetch_queue (id, feed_id, priority, requested_at, requested_by)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET priority = GREATEST(fetch_queue.priority, EXCLUDED.priority);
+
+-- name: ClaimQueuedFeeds :many
+WITH queued AS (
+    DELETE FROM fetch_queue
+    WHERE fetch_queue.id IN (
+        SELECT fetch_queue.id FROM fetch_queue
+        INNER JOIN feeds ON feeds.id = fetch_queue.feed_id
+        WHERE feeds.claimed_by IS NULL OR feeds.claimed_until < NOW()
+        ORDER BY fetch_queue.priority DESC, fetch_queue.requested_at ASC
+        LIMIT sqlc.arg(max_feeds)
+        FOR UPDATE OF fetch_queue, feeds SKIP LOCKED
+    )
+    RETURNING fetch_queue.feed_id
+)
+UPDATE feeds
+SET claimed_by = sqlc.arg(instance_id),
+claimed_until = NOW() + (sqlc.arg(lease_seconds)::integer * INTERVAL '1 second'),
+last_fetched_at = NOW(),
+updated_at = NOW()
+FROM queued
+WHERE feeds.id = queued.feed_id
+RETURNING feeds.*;
+
+-- name: CountQueuedFeeds :one
+SELECT COUNT(*) FROM fetch_queue;
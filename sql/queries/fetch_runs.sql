@@ -0,0 +1,29 @@
+-- name: CreateFetchRun :one
+INSERT INTO fetch_runs (id, feed_id, started_at, finished_at, http_status, bytes, items_seen, items_inserted, items_skipped, error)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+RETURNING *;
+
+-- name: GetFetchRuns :many
+SELECT  fetch_runs.*,
+        feeds.name AS feed_name,
+        feeds.url AS feed_url
+FROM fetch_runs
+INNER JOIN feeds ON fetch_runs.feed_id = feeds.id
+WHERE (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url))
+AND fetch_runs.started_at >= sqlc.arg(since)
+ORDER BY fetch_runs.started_at DESC
+LIMIT sqlc.arg(max_runs);
+
+-- name: DeleteFetchRunsBefore :execrows
+DELETE FROM fetch_runs WHERE started_at < $1;
@@ -1,16 +1,122 @@
--- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7,
-    $8
-)
+-- name: CreatePosts :many
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, original_url)
+SELECT  gen_random_uuid(),
+        sqlc.arg(created_at)::timestamp,
+        sqlc.arg(created_at)::timestamp,
+        item.title,
+        item.url,
+        NULLIF(item.description, ''),
+        NULLIF(item.published_at, '')::timestamptz AT TIME ZONE 'UTC',
+        sqlc.arg(feed_id)::uuid,
+        NULLIF(item.content, ''),
+        item.original_url
+FROM unnest(
+    sqlc.arg(titles)::text[],
+    sqlc.arg(urls)::text[],
+    sqlc.arg(descriptions)::text[],
+    sqlc.arg(published_ats)::text[],
+    sqlc.arg(contents)::text[],
+    sqlc.arg(original_urls)::text[]
+) AS item(title, url, description, published_at, content, original_url)
+ON CONFLICT (feed_id, url) DO NOTHING
 RETURNING *;
 
 -- name: GetPostForUser :many
-SELECT posts.* from posts INNER JOIN feeds on posts.feed_id = feeds.id where feeds.user_id = $1 order by posts.published_at DESC limit $2;
\ No newline at end of file
+SELECT posts.* from posts INNER JOIN feeds on posts.feed_id = feeds.id where feeds.user_id = $1 order by posts.published_at DESC limit $2;
+
+-- name: GetRecentPostTimes :many
+SELECT COALESCE(published_at, created_at)::timestamp AS posted_at
+FROM posts
+WHERE feed_id = $1
+ORDER BY posted_at DESC
+LIMIT $2;
+
+-- name: SearchPosts :many
+SELECT  posts.id,
+        posts.title,
+        posts.url,
+        posts.published_at,
+        feeds.name AS feed_name,
+        ts_rank(posts.search_vector, websearch_to_tsquery('english', sqlc.arg(query))) AS rank,
+        ts_headline(
+            'english',
+            concat_ws(' ', posts.title, posts.description, posts.content),
+            websearch_to_tsquery('english', sqlc.arg(query)),
+            'StartSel=**, StopSel=**, MaxFragments=2, MinWords=5, MaxWords=20'
+        ) AS snippet
+FROM posts
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+AND posts.search_vector @@ websearch_to_tsquery('english', sqlc.arg(query))
+AND (sqlc.narg(feed_url)::text IS NULL OR feeds.url = sqlc.narg(feed_url))
+AND COALESCE(posts.published_at, posts.created_at) >= sqlc.arg(since)
+ORDER BY rank DESC, COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: PostExistsWithOriginalUrl :one
+SELECT EXISTS(SELECT 1 FROM posts WHERE feed_id = $1 AND original_url = $2);
+
+-- name: GetPostByUrl :one
+SELECT posts.* FROM posts
+LEFT JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = $1
+WHERE posts.url = $2
+ORDER BY feed_follows.id IS NULL, posts.created_at
+LIMIT 1;
+
+-- name: GetPrunablePostCounts :many
+WITH ranked AS (
+    SELECT  posts.id,
+            posts.feed_id,
+            posts.created_at,
+            ROW_NUMBER() OVER (
+                PARTITION BY posts.feed_id
+                ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
+            ) AS position
+    FROM posts
+    WHERE NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+), prunable AS (
+    SELECT ranked.id, ranked.feed_id FROM ranked
+    INNER JOIN feeds ON feeds.id = ranked.feed_id
+    WHERE ranked.created_at < COALESCE(
+        NOW() - (feeds.retention_max_age_seconds * INTERVAL '1 second'),
+        sqlc.narg(created_before)::timestamp
+    )
+    OR ranked.position > feeds.retention_max_posts
+)
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, COUNT(*) AS posts
+FROM prunable
+INNER JOIN feeds ON feeds.id = prunable.feed_id
+GROUP BY feeds.id
+ORDER BY feeds.name;
+
+-- name: PrunePosts :many
+WITH ranked AS (
+    SELECT  posts.id,
+            posts.feed_id,
+            posts.created_at,
+            ROW_NUMBER() OVER (
+                PARTITION BY posts.feed_id
+                ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
+            ) AS position
+    FROM posts
+    WHERE NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+), prunable AS (
+    SELECT ranked.id, ranked.feed_id FROM ranked
+    INNER JOIN feeds ON feeds.id = ranked.feed_id
+    WHERE ranked.created_at < COALESCE(
+        NOW() - (feeds.retention_max_age_seconds * INTERVAL '1 second'),
+        sqlc.narg(created_before)::timestamp
+    )
+    OR ranked.position > feeds.retention_max_posts
+), deleted AS (
+    DELETE FROM posts
+    USING prunable
+    WHERE posts.id = prunable.id
+    RETURNING posts.feed_id
+)
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, COUNT(*) AS posts
+FROM deleted
+INNER JOIN feeds ON feeds.id = deleted.feed_id
+GROUP BY feeds.id
+ORDER BY feeds.name;
@@ -0,0 +1,26 @@
+-- name: StarPost :exec
+INSERT INTO starred_posts (user_id, post_id, created_at, note)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET note = EXCLUDED.note;
+
+-- name: UnstarPost :execrows
+DELETE FROM starred_posts WHERE user_id = $1 AND post_id = $2;
+
+-- name: GetStarredPosts :many
+SELECT  posts.title,
+        posts.url,
+        posts.published_at,
+        feeds.name AS feed_name,
+        starred_posts.created_at AS starred_at,
+        starred_posts.note
+FROM starred_posts
+INNER JOIN posts ON posts.id = starred_posts.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE starred_posts.user_id = $1
+ORDER BY starred_posts.created_at DESC;
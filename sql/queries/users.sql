@@ -9,7 +9,7 @@ VALUES (
 RETURNING *;
 
 -- name: GetUser :one
-SELECT users.id, users.created_at, users.updated_at, users.name from users where users.name=$1; 
+SELECT users.id, users.created_at, users.updated_at, users.name, users.role from users where users.name=$1; 
 
 -- name: GetUsers :many
 SELECT * FROM users;
@@ -18,4 +18,28 @@ SELECT * FROM users;
 SELECT users.name from users where users.id=$1;
 
 -- name: DeleteUsers :exec
-DELETE from users;
\ No newline at end of file
+DELETE from users;
+
+-- name: CountUsers :one
+SELECT count(*) FROM users;
+
+-- name: SetUserRole :one
+UPDATE users SET role = $2, updated_at = $3
+WHERE name = $1
+RETURNING *;
+
+-- name: RenameUser :one
+UPDATE users SET name = sqlc.arg(new_name), updated_at = sqlc.arg(updated_at)
+WHERE name = sqlc.arg(name)
+RETURNING *;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = $1;
+
+-- name: GetUserActivity :one
+SELECT  (SELECT count(*) FROM feed_follows WHERE feed_follows.user_id = $1) AS follows,
+        (SELECT count(*) FROM starred_posts WHERE starred_posts.user_id = $1) AS starred,
+        (SELECT count(*) FROM api_tokens WHERE api_tokens.user_id = $1) AS tokens,
+        (SELECT count(*) FROM posts
+         INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+         WHERE feed_follows.user_id = $1) AS followed_posts;
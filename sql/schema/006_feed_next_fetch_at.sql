@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN next_fetch_at TIMESTAMP,
+ADD COLUMN fetch_interval_seconds INTEGER NOT NULL DEFAULT 1800,
+ADD COLUMN min_fetch_interval_seconds INTEGER NOT NULL DEFAULT 300,
+ADD COLUMN max_fetch_interval_seconds INTEGER NOT NULL DEFAULT 86400;
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at NULLS FIRST);
+
+-- +goose Down
+DROP INDEX feeds_next_fetch_at_idx;
+
+ALTER TABLE feeds
+DROP COLUMN next_fetch_at,
+DROP COLUMN fetch_interval_seconds,
+DROP COLUMN min_fetch_interval_seconds,
+DROP COLUMN max_fetch_interval_seconds;
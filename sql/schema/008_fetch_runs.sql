@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE fetch_runs(
+    id UUID PRIMARY KEY,
+    feed_id UUID NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    http_status INTEGER,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    items_seen INTEGER NOT NULL DEFAULT 0,
+    items_inserted INTEGER NOT NULL DEFAULT 0,
+    items_skipped INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+
+CREATE INDEX fetch_runs_feed_started_idx ON fetch_runs (feed_id, started_at DESC);
+CREATE INDEX fetch_runs_started_idx ON fetch_runs (started_at);
+
+-- +goose Down
+DROP TABLE fetch_runs;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE fetch_queue(
+    id UUID PRIMARY KEY,
+    feed_id UUID UNIQUE NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    requested_at TIMESTAMP NOT NULL,
+    requested_by UUID,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    FOREIGN KEY(requested_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX fetch_queue_order_idx ON fetch_queue (priority DESC, requested_at ASC);
+
+-- +goose Down
+DROP TABLE fetch_queue;
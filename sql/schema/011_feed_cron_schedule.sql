@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN cron_schedule TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN cron_schedule;
@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE feed_credentials(
+    feed_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    sealed BYTEA NOT NULL,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
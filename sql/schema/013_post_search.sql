@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content TEXT;
+
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(content, '')), 'C')
+) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+-- +goose Down
+DROP INDEX posts_search_vector_idx;
+ALTER TABLE posts DROP COLUMN search_vector;
+ALTER TABLE posts DROP COLUMN content;
@@ -0,0 +1,20 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN retention_max_posts INTEGER;
+ALTER TABLE feeds ADD COLUMN retention_max_age_seconds INTEGER;
+
+CREATE TABLE starred_posts(
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    note TEXT,
+    PRIMARY KEY(user_id, post_id),
+    FOREIGN KEY(user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY(post_id) REFERENCES posts (id) ON DELETE CASCADE
+);
+
+CREATE INDEX starred_posts_post_id_idx ON starred_posts (post_id);
+
+-- +goose Down
+DROP TABLE starred_posts;
+ALTER TABLE feeds DROP COLUMN retention_max_age_seconds;
+ALTER TABLE feeds DROP COLUMN retention_max_posts;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN original_url TEXT;
+UPDATE posts SET original_url = url;
+ALTER TABLE posts ALTER COLUMN original_url SET NOT NULL;
+
+CREATE INDEX posts_original_url_idx ON posts (original_url);
+
+-- +goose Down
+DROP INDEX posts_original_url_idx;
+ALTER TABLE posts DROP COLUMN original_url;
@@ -0,0 +1,91 @@
+-- +goose Up
+ALTER TABLE users ALTER COLUMN name TYPE TEXT;
+ALTER TABLE feeds ALTER COLUMN name TYPE TEXT;
+ALTER TABLE feeds ALTER COLUMN url TYPE TEXT;
+
+-- Feed names only have to be distinct among feeds added by the same user
+ALTER TABLE feeds DROP CONSTRAINT feeds_name_key;
+ALTER TABLE feeds ADD CONSTRAINT feeds_user_id_name_key UNIQUE (user_id, name);
+
+-- Type of title can not change while generated search_vector depends on it
+DROP INDEX posts_search_vector_idx;
+ALTER TABLE posts DROP COLUMN search_vector;
+
+ALTER TABLE posts ALTER COLUMN title TYPE TEXT;
+ALTER TABLE posts ALTER COLUMN url TYPE TEXT;
+
+-- Post is identified by its url within the feed, titles may repeat
+ALTER TABLE posts DROP CONSTRAINT posts_title_key;
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_url_key UNIQUE (feed_id, url);
+CREATE INDEX posts_url_idx ON posts (url);
+
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(content, '')), 'C')
+) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+-- +goose Down
+-- Users and feeds can not be removed without losing follows of other users, so rolling back
+-- fails listing the rows that have to be renamed or deleted to fit old limits first
+-- +goose StatementBegin
+DO $$
+DECLARE
+    problems TEXT;
+BEGIN
+    SELECT string_agg(problem, '; ') INTO problems FROM (
+        SELECT format('user name %s is longer than 50 characters', name) AS problem FROM users WHERE length(name) > 50
+        UNION ALL
+        SELECT format('feed name %s is longer than 50 characters', name) FROM feeds WHERE length(name) > 50
+        UNION ALL
+        SELECT format('feed url %s is longer than 100 characters', url) FROM feeds WHERE length(url) > 100
+        UNION ALL
+        SELECT format('feed name %s is used by %s users', name, count(*)) FROM feeds GROUP BY name HAVING count(*) > 1
+    ) AS found;
+    IF problems IS NOT NULL THEN
+        RAISE EXCEPTION 'rows do not fit schema before v2, rename or delete them first: %', problems;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+-- Posts that do not fit old limits are removed, keeping the oldest post of each url and title
+DELETE FROM posts
+WHERE length(title) > 150
+OR length(url) > 100
+OR id IN (
+    SELECT id FROM (
+        SELECT  id,
+                ROW_NUMBER() OVER (PARTITION BY url ORDER BY created_at, id) AS url_position,
+                ROW_NUMBER() OVER (PARTITION BY title ORDER BY created_at, id) AS title_position
+        FROM posts
+    ) AS ranked
+    WHERE url_position > 1 OR title_position > 1
+);
+
+DROP INDEX posts_search_vector_idx;
+ALTER TABLE posts DROP COLUMN search_vector;
+
+DROP INDEX posts_url_idx;
+ALTER TABLE posts DROP CONSTRAINT posts_feed_id_url_key;
+ALTER TABLE posts ALTER COLUMN url TYPE VARCHAR(100);
+ALTER TABLE posts ALTER COLUMN title TYPE VARCHAR(150);
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+ALTER TABLE posts ADD CONSTRAINT posts_title_key UNIQUE (title);
+
+ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(content, '')), 'C')
+) STORED;
+
+CREATE INDEX posts_search_vector_idx ON posts USING GIN (search_vector);
+
+ALTER TABLE feeds DROP CONSTRAINT feeds_user_id_name_key;
+ALTER TABLE feeds ALTER COLUMN url TYPE VARCHAR(100);
+ALTER TABLE feeds ALTER COLUMN name TYPE VARCHAR(50);
+ALTER TABLE feeds ADD CONSTRAINT feeds_name_key UNIQUE (name);
+ALTER TABLE users ALTER COLUMN name TYPE VARCHAR(50);
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Name is kept next to id so entries still tell who acted after the user is deleted
+CREATE TABLE audit_log(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID REFERENCES users (id) ON DELETE SET NULL,
+    user_name TEXT,
+    command TEXT NOT NULL,
+    args TEXT NOT NULL,
+    succeeded BOOLEAN NOT NULL,
+    error TEXT,
+    duration_ms BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX audit_log_created_at_idx ON audit_log (created_at);
+CREATE INDEX audit_log_user_id_idx ON audit_log (user_id);
+
+-- +goose Down
+DROP TABLE audit_log;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member'));
+-- Existing install keeps one user able to run admin commands
+UPDATE users SET role = 'admin' WHERE id = (SELECT id FROM users ORDER BY created_at, name LIMIT 1);
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN role;
@@ -0,0 +1,23 @@
+-- name: HeartbeatAggInstance :exec
+INSERT INTO agg_instances (id, hostname, pid, started_at, daemon, heartbeat_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    now()
+)
+ON CONFLICT (id) DO UPDATE SET heartbeat_at = now();
+
+-- name: DeleteAggInstance :exec
+DELETE FROM agg_instances WHERE id = ?;
+
+-- name: DeleteStaleAggInstances :execrows
+DELETE FROM agg_instances
+WHERE heartbeat_at < now_offset(-CAST(sqlc.arg(stale_seconds) AS INTEGER));
+
+-- name: CountLiveAggDaemons :one
+SELECT COUNT(*) FROM agg_instances
+WHERE daemon
+AND heartbeat_at >= now_offset(-CAST(sqlc.arg(stale_seconds) AS INTEGER));
@@ -0,0 +1,23 @@
+-- name: CreateApiToken :one
+INSERT INTO api_tokens (id, created_at, user_id, name, token_hash, expires_at)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetUserByTokenHash :one
+SELECT users.* FROM users
+JOIN api_tokens ON api_tokens.user_id = users.id
+WHERE api_tokens.token_hash = sqlc.arg(token_hash)
+AND (api_tokens.expires_at IS NULL OR api_tokens.expires_at > sqlc.arg(valid_at));
+
+-- name: GetApiTokensForUser :many
+SELECT * FROM api_tokens WHERE user_id = ? ORDER BY created_at, name;
+
+-- name: DeleteApiToken :execrows
+DELETE FROM api_tokens WHERE user_id = ? AND name = ?;
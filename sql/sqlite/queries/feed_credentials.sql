@@ -0,0 +1,17 @@
+-- name: SetFeedCredentials :exec
+INSERT INTO feed_credentials (feed_id, created_at, updated_at, sealed)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET sealed = excluded.sealed,
+updated_at = excluded.updated_at;
+
+-- name: GetFeedCredentials :one
+SELECT sealed FROM feed_credentials WHERE feed_id = ?;
+
+-- name: DeleteFeedCredentials :execrows
+DELETE FROM feed_credentials WHERE feed_id = ?;
@@ -0,0 +1,194 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetFeeds :many
+SELECT feeds.name, feeds.url, feeds.user_id FROM feeds;
+
+-- name: GetFeedByUrl :one
+SELECT * FROM feeds WHERE feeds.url = ?;
+
+-- name: GetFeedById :one
+SELECT * FROM feeds WHERE feeds.id = ?;
+
+-- name: DeleteFeeds :exec
+DELETE FROM feeds;
+
+-- name: DeleteFeed :execrows
+DELETE FROM feeds WHERE id = ?;
+
+-- name: GetOwnedFeeds :many
+SELECT  feeds.id,
+        feeds.name,
+        feeds.url,
+        (SELECT count(*) FROM feed_follows
+         WHERE feed_follows.feed_id = feeds.id AND feed_follows.user_id <> feeds.user_id) AS other_followers,
+        (SELECT count(*) FROM posts WHERE posts.feed_id = feeds.id) AS posts
+FROM feeds
+WHERE feeds.user_id = ?
+ORDER BY feeds.created_at, feeds.name;
+
+-- name: TransferFeeds :execrows
+UPDATE feeds SET user_id = sqlc.arg(to_user_id), updated_at = sqlc.arg(updated_at)
+WHERE user_id = sqlc.arg(from_user_id);
+
+-- name: CreateFeedFollow :one
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetFeedFollow :one
+SELECT  feed_follows.*,
+        feeds.name AS feed_name,
+        users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+WHERE feed_follows.id = ?;
+
+-- name: GetFeedFollowsForUser :many
+SELECT  feed_follows.*,
+        feeds.name AS feed_name,
+        users.name AS user_name
+FROM feed_follows
+INNER JOIN feeds ON feed_follows.feed_id = feeds.id
+INNER JOIN users ON feed_follows.user_id = users.id
+WHERE feed_follows.user_id = ?;
+
+-- name: DeleteFeedsFollow :exec
+DELETE FROM feed_follows
+WHERE feed_follows.feed_id = (SELECT feeds.id FROM feeds WHERE feeds.url = ?)
+AND feed_follows.user_id = ?;
+
+-- name: ScheduleFeedFetch :one
+UPDATE feeds
+SET fetch_interval_seconds = ?,
+next_fetch_at = ?,
+updated_at = now()
+WHERE id = ?
+RETURNING *;
+
+-- name: SetFeedFetchBounds :one
+UPDATE feeds
+SET min_fetch_interval_seconds = sqlc.arg(min_fetch_interval_seconds),
+max_fetch_interval_seconds = sqlc.arg(max_fetch_interval_seconds),
+fetch_interval_seconds = MIN(MAX(fetch_interval_seconds, sqlc.arg(min_fetch_interval_seconds)), sqlc.arg(max_fetch_interval_seconds)),
+updated_at = now()
+WHERE url = sqlc.arg(url)
+RETURNING *;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET consecutive_failures = 0,
+last_error = NULL,
+last_succeeded_at = now(),
+updated_at = now()
+WHERE id = ?;
+
+-- name: RecordFeedFailure :one
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1,
+last_error = ?,
+next_fetch_at = ?,
+disabled_at = CASE WHEN consecutive_failures + 1 >= CAST(sqlc.arg(max_failures) AS INTEGER) THEN now() ELSE disabled_at END,
+updated_at = now()
+WHERE id = ?
+RETURNING *;
+
+-- name: EnableFeed :one
+UPDATE feeds
+SET disabled_at = NULL,
+consecutive_failures = 0,
+next_fetch_at = NULL,
+updated_at = now()
+WHERE url = ?
+RETURNING *;
+
+-- name: GetBrokenFeeds :many
+SELECT * FROM feeds
+WHERE disabled_at IS NOT NULL OR consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name;
+
+-- name: ClaimDueFeeds :many
+UPDATE feeds
+SET claimed_by = sqlc.arg(instance_id),
+claimed_until = now_offset(CAST(sqlc.arg(lease_seconds) AS INTEGER)),
+last_fetched_at = now(),
+updated_at = now()
+WHERE id IN (
+    SELECT id FROM feeds
+    WHERE disabled_at IS NULL
+    AND (next_fetch_at IS NULL OR next_fetch_at <= now())
+    AND (claimed_by IS NULL OR claimed_until < now())
+    AND (NOT CAST(sqlc.arg(scheduled_only) AS BOOLEAN) OR cron_schedule IS NOT NULL)
+    ORDER BY next_fetch_at ASC NULLS FIRST
+    LIMIT sqlc.arg(max_feeds)
+)
+RETURNING *;
+
+-- name: ClaimActiveFeeds :many
+UPDATE feeds
+SET claimed_by = sqlc.arg(instance_id),
+claimed_until = now_offset(CAST(sqlc.arg(lease_seconds) AS INTEGER)),
+last_fetched_at = now(),
+updated_at = now()
+WHERE disabled_at IS NULL
+AND (claimed_by IS NULL OR claimed_until < now())
+RETURNING *;
+
+-- name: ClaimFeed :one
+UPDATE feeds
+SET claimed_by = sqlc.arg(instance_id),
+claimed_until = now_offset(CAST(sqlc.arg(lease_seconds) AS INTEGER)),
+last_fetched_at = now(),
+updated_at = now()
+WHERE id = sqlc.arg(id)
+AND (claimed_by IS NULL OR claimed_until < now())
+RETURNING *;
+
+-- name: ExtendFeedClaims :execrows
+UPDATE feeds
+SET claimed_until = now_offset(CAST(sqlc.arg(lease_seconds) AS INTEGER))
+WHERE claimed_by = sqlc.arg(instance_id);
+
+-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_by = NULL,
+claimed_until = NULL
+WHERE id = ?;
+
+-- name: CountOverdueFeeds :one
+SELECT COUNT(*) FROM feeds
+WHERE disabled_at IS NULL
+AND (next_fetch_at IS NULL OR next_fetch_at <= now())
+AND (claimed_by IS NULL OR claimed_until < now());
+
+-- name: SetFeedSchedule :one
+UPDATE feeds
+SET cron_schedule = ?,
+next_fetch_at = ?,
+updated_at = now()
+WHERE url = ?
+RETURNING *;
+
+-- name: SetFeedRetention :one
+UPDATE feeds
+SET retention_max_posts = ?,
+retention_max_age_seconds = ?,
+updated_at = now()
+WHERE url = ?
+RETURNING *;
@@ -0,0 +1,32 @@
+-- name: EnqueueFeedFetch :exec
+INSERT INTO fetch_queue (id, feed_id, priority, requested_at, requested_by)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+ON CONFLICT (feed_id) DO UPDATE
+SET priority = MAX(fetch_queue.priority, excluded.priority);
+
+-- name: ClaimQueuedFeeds :many
+UPDATE feeds
+SET claimed_by = sqlc.arg(instance_id),
+claimed_until = now_offset(CAST(sqlc.arg(lease_seconds) AS INTEGER)),
+last_fetched_at = now(),
+updated_at = now()
+WHERE id IN (
+    SELECT fetch_queue.feed_id FROM fetch_queue
+    INNER JOIN feeds ON feeds.id = fetch_queue.feed_id
+    WHERE feeds.claimed_by IS NULL OR feeds.claimed_until < now()
+    ORDER BY fetch_queue.priority DESC, fetch_queue.requested_at ASC
+    LIMIT sqlc.arg(max_feeds)
+)
+RETURNING *;
+
+-- name: DeleteQueuedFetch :exec
+DELETE FROM fetch_queue WHERE feed_id = ?;
+
+-- name: CountQueuedFeeds :one
+SELECT COUNT(*) FROM fetch_queue;
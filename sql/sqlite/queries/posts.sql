@@ -0,0 +1,101 @@
+-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, content, original_url)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?,
+    ?
+)
+ON CONFLICT (feed_id, url) DO NOTHING
+RETURNING *;
+
+-- name: GetPostForUser :many
+SELECT posts.* FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+WHERE feeds.user_id = ?
+ORDER BY posts.published_at DESC NULLS FIRST
+LIMIT ?;
+
+-- name: SearchPosts :many
+SELECT  posts.id,
+        posts.title,
+        posts.url,
+        posts.published_at,
+        feeds.name AS feed_name,
+        CAST(-bm25(posts_fts, 0.0, 10.0, 4.0, 1.0) AS REAL) AS rank,
+        CAST(snippet(posts_fts, -1, '**', '**', '...', 20) AS TEXT) AS snippet
+FROM posts_fts
+INNER JOIN posts ON posts.id = posts_fts.post_id
+INNER JOIN feeds ON feeds.id = posts.feed_id
+INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE posts_fts MATCH CAST(sqlc.arg(query) AS TEXT)
+AND feed_follows.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(feed_url) IS NULL OR feeds.url = sqlc.narg(feed_url))
+AND COALESCE(posts.published_at, posts.created_at) >= sqlc.arg(since)
+ORDER BY rank DESC, COALESCE(posts.published_at, posts.created_at) DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: GetRecentPostTimes :many
+SELECT published_at, created_at
+FROM posts
+WHERE feed_id = ?
+ORDER BY COALESCE(published_at, created_at) DESC
+LIMIT ?;
+
+-- name: PostExistsWithOriginalUrl :one
+SELECT EXISTS(SELECT 1 FROM posts WHERE feed_id = ? AND original_url = ?);
+
+-- name: GetPostByUrl :one
+SELECT posts.* FROM posts
+LEFT JOIN feed_follows ON feed_follows.feed_id = posts.feed_id AND feed_follows.user_id = ?
+WHERE posts.url = ?
+ORDER BY feed_follows.id IS NULL, posts.created_at
+LIMIT 1;
+
+-- name: GetPrunablePostCounts :many
+WITH ranked AS (
+    SELECT  posts.id,
+            posts.feed_id,
+            posts.created_at,
+            ROW_NUMBER() OVER (
+                PARTITION BY posts.feed_id
+                ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
+            ) AS position
+    FROM posts
+    WHERE NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+), prunable AS (
+    SELECT ranked.id, ranked.feed_id FROM ranked
+    INNER JOIN feeds ON feeds.id = ranked.feed_id
+    WHERE ranked.created_at < COALESCE(now_offset(-feeds.retention_max_age_seconds), sqlc.narg(created_before))
+    OR ranked.position > feeds.retention_max_posts
+)
+SELECT feeds.name AS feed_name, feeds.url AS feed_url, COUNT(*) AS posts
+FROM prunable
+INNER JOIN feeds ON feeds.id = prunable.feed_id
+GROUP BY feeds.id
+ORDER BY feeds.name;
+
+-- name: DeletePrunablePosts :execrows
+WITH ranked AS (
+    SELECT  posts.id,
+            posts.feed_id,
+            posts.created_at,
+            ROW_NUMBER() OVER (
+                PARTITION BY posts.feed_id
+                ORDER BY COALESCE(posts.published_at, posts.created_at) DESC
+            ) AS position
+    FROM posts
+    WHERE NOT EXISTS (SELECT 1 FROM starred_posts WHERE starred_posts.post_id = posts.id)
+), prunable AS (
+    SELECT ranked.id, ranked.feed_id FROM ranked
+    INNER JOIN feeds ON feeds.id = ranked.feed_id
+    WHERE ranked.created_at < COALESCE(now_offset(-feeds.retention_max_age_seconds), sqlc.narg(created_before))
+    OR ranked.position > feeds.retention_max_posts
+)
+DELETE FROM posts WHERE id IN (SELECT id FROM prunable);
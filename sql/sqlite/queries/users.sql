@@ -0,0 +1,45 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name)
+VALUES (
+    ?,
+    ?,
+    ?,
+    ?
+)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT users.id, users.created_at, users.updated_at, users.name, users.role FROM users WHERE users.name = ?;
+
+-- name: GetUsers :many
+SELECT * FROM users;
+
+-- name: GetUsernameById :one
+SELECT users.name FROM users WHERE users.id = ?;
+
+-- name: DeleteUsers :exec
+DELETE FROM users;
+
+-- name: RenameUser :one
+UPDATE users SET name = sqlc.arg(new_name), updated_at = sqlc.arg(updated_at)
+WHERE name = sqlc.arg(name)
+RETURNING *;
+
+-- name: DeleteUser :execrows
+DELETE FROM users WHERE id = ?;
+
+-- name: GetUserActivity :one
+SELECT  (SELECT count(*) FROM feed_follows WHERE feed_follows.user_id = sqlc.arg(user_id)) AS follows,
+        (SELECT count(*) FROM starred_posts WHERE starred_posts.user_id = sqlc.arg(user_id)) AS starred,
+        (SELECT count(*) FROM api_tokens WHERE api_tokens.user_id = sqlc.arg(user_id)) AS tokens,
+        (SELECT count(*) FROM posts
+         INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+         WHERE feed_follows.user_id = sqlc.arg(user_id)) AS followed_posts;
+
+-- name: CountUsers :one
+SELECT count(*) FROM users;
+
+-- name: SetUserRole :one
+UPDATE users SET role = ?, updated_at = ?
+WHERE name = ?
+RETURNING *;
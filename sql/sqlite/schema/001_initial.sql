@@ -0,0 +1,109 @@
+-- +goose Up
+CREATE TABLE users (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name VARCHAR(50) UNIQUE NOT NULL
+);
+
+CREATE TABLE agg_instances(
+    id UUID PRIMARY KEY,
+    hostname TEXT NOT NULL,
+    pid INTEGER NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    heartbeat_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE feeds (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    url VARCHAR(100) UNIQUE NOT NULL,
+    user_id UUID NOT NULL,
+    last_fetched_at TIMESTAMP,
+    next_fetch_at TIMESTAMP,
+    fetch_interval_seconds INTEGER NOT NULL DEFAULT 1800,
+    min_fetch_interval_seconds INTEGER NOT NULL DEFAULT 300,
+    max_fetch_interval_seconds INTEGER NOT NULL DEFAULT 86400,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_succeeded_at TIMESTAMP,
+    disabled_at TIMESTAMP,
+    claimed_by UUID REFERENCES agg_instances (id) ON DELETE SET NULL,
+    claimed_until TIMESTAMP,
+    cron_schedule TEXT,
+    FOREIGN KEY(user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at);
+
+CREATE TABLE feed_follows(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    feed_id UUID NOT NULL,
+    FOREIGN KEY(user_id) REFERENCES users (id) ON DELETE CASCADE,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    UNIQUE(user_id, feed_id)
+);
+
+CREATE TABLE posts(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title VARCHAR(150) UNIQUE NOT NULL,
+    url VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id UUID NOT NULL,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+
+CREATE TABLE fetch_runs(
+    id UUID PRIMARY KEY,
+    feed_id UUID NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP NOT NULL,
+    http_status INTEGER,
+    bytes BIGINT NOT NULL DEFAULT 0,
+    items_seen INTEGER NOT NULL DEFAULT 0,
+    items_inserted INTEGER NOT NULL DEFAULT 0,
+    items_skipped INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+
+CREATE INDEX fetch_runs_feed_started_idx ON fetch_runs (feed_id, started_at DESC);
+CREATE INDEX fetch_runs_started_idx ON fetch_runs (started_at);
+
+CREATE TABLE fetch_queue(
+    id UUID PRIMARY KEY,
+    feed_id UUID UNIQUE NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    requested_at TIMESTAMP NOT NULL,
+    requested_by UUID,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    FOREIGN KEY(requested_by) REFERENCES users (id) ON DELETE SET NULL
+);
+
+CREATE INDEX fetch_queue_order_idx ON fetch_queue (priority DESC, requested_at ASC);
+
+CREATE TABLE feed_credentials(
+    feed_id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    sealed BLOB NOT NULL,
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE feed_credentials;
+DROP TABLE fetch_queue;
+DROP TABLE fetch_runs;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE feeds;
+DROP TABLE agg_instances;
+DROP TABLE users;
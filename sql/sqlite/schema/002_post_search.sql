@@ -0,0 +1,42 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content TEXT;
+
+-- Stand-in for tsvector column of Postgres, kept in sync by triggers
+CREATE VIRTUAL TABLE posts_fts USING fts5(
+    post_id UNINDEXED,
+    title,
+    description,
+    content,
+    tokenize = 'porter unicode61'
+);
+
+INSERT INTO posts_fts (post_id, title, description, content)
+SELECT id, title, coalesce(description, ''), coalesce(content, '') FROM posts;
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (post_id, title, description, content)
+    VALUES (new.id, new.title, coalesce(new.description, ''), coalesce(new.content, ''));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_update AFTER UPDATE ON posts BEGIN
+    UPDATE posts_fts
+    SET title = new.title, description = coalesce(new.description, ''), content = coalesce(new.content, '')
+    WHERE post_id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+    DELETE FROM posts_fts WHERE post_id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TRIGGER posts_fts_delete;
+DROP TRIGGER posts_fts_update;
+DROP TRIGGER posts_fts_insert;
+DROP TABLE posts_fts;
+ALTER TABLE posts DROP COLUMN content;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN original_url TEXT NOT NULL DEFAULT '';
+UPDATE posts SET original_url = url;
+
+CREATE INDEX posts_original_url_idx ON posts (original_url);
+
+-- +goose Down
+DROP INDEX posts_original_url_idx;
+ALTER TABLE posts DROP COLUMN original_url;
@@ -0,0 +1,211 @@
+-- +goose NO TRANSACTION
+-- +goose Up
+-- SQLite does not enforce VARCHAR lengths but can not drop constraints, so feeds and posts
+-- are rebuilt. Foreign keys are off while tables are swapped, otherwise dropping them
+-- would cascade to dependent rows
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+CREATE TABLE feeds_new (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    user_id UUID NOT NULL,
+    last_fetched_at TIMESTAMP,
+    next_fetch_at TIMESTAMP,
+    fetch_interval_seconds INTEGER NOT NULL DEFAULT 1800,
+    min_fetch_interval_seconds INTEGER NOT NULL DEFAULT 300,
+    max_fetch_interval_seconds INTEGER NOT NULL DEFAULT 86400,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_succeeded_at TIMESTAMP,
+    disabled_at TIMESTAMP,
+    claimed_by UUID REFERENCES agg_instances (id) ON DELETE SET NULL,
+    claimed_until TIMESTAMP,
+    cron_schedule TEXT,
+    retention_max_posts INTEGER,
+    retention_max_age_seconds INTEGER,
+    FOREIGN KEY(user_id) REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+INSERT INTO feeds_new
+SELECT  id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at,
+        fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds,
+        consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by,
+        claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
+FROM feeds;
+DROP TABLE feeds;
+ALTER TABLE feeds_new RENAME TO feeds;
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at);
+
+CREATE TABLE posts_new(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id UUID NOT NULL,
+    content TEXT,
+    original_url TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE,
+    UNIQUE(feed_id, url)
+);
+INSERT INTO posts_new
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, original_url
+FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_new RENAME TO posts;
+
+CREATE INDEX posts_url_idx ON posts (url);
+CREATE INDEX posts_original_url_idx ON posts (original_url);
+
+-- Search index keeps its rows, only triggers went away with the old table
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (post_id, title, description, content)
+    VALUES (new.id, new.title, coalesce(new.description, ''), coalesce(new.content, ''));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_update AFTER UPDATE ON posts BEGIN
+    UPDATE posts_fts
+    SET title = new.title, description = coalesce(new.description, ''), content = coalesce(new.content, '')
+    WHERE post_id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+    DELETE FROM posts_fts WHERE post_id = old.id;
+END;
+-- +goose StatementEnd
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
+
+-- +goose Down
+-- Feed names were unique across users before, feeds can not be removed without losing follows
+-- of other users, so rolling back fails listing the names that have to be changed first
+CREATE TEMP TABLE IF NOT EXISTS schema_v2_down_check (problem TEXT NOT NULL);
+
+-- +goose StatementBegin
+CREATE TEMP TRIGGER IF NOT EXISTS schema_v2_down_check_raise AFTER INSERT ON schema_v2_down_check BEGIN
+    SELECT RAISE(ABORT, 'rows do not fit schema before v2, rename or delete them first: ' || new.problem);
+END;
+-- +goose StatementEnd
+
+INSERT INTO schema_v2_down_check (problem)
+SELECT problem FROM (
+    SELECT group_concat('feed name ' || name || ' is used by ' || users || ' users', '; ') AS problem
+    FROM (SELECT name, count(*) AS users FROM feeds GROUP BY name HAVING count(*) > 1)
+)
+WHERE problem IS NOT NULL;
+
+DROP TABLE schema_v2_down_check;
+
+PRAGMA foreign_keys = OFF;
+
+BEGIN;
+
+-- Rows that do not fit old unique keys are removed, keeping the oldest post of each url and title
+DELETE FROM posts
+WHERE id IN (
+    SELECT id FROM (
+        SELECT  id,
+                ROW_NUMBER() OVER (PARTITION BY url ORDER BY created_at, id) AS url_position,
+                ROW_NUMBER() OVER (PARTITION BY title ORDER BY created_at, id) AS title_position
+        FROM posts
+    )
+    WHERE url_position > 1 OR title_position > 1
+);
+DELETE FROM starred_posts WHERE post_id NOT IN (SELECT id FROM posts);
+
+CREATE TABLE posts_old(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title VARCHAR(150) UNIQUE NOT NULL,
+    url VARCHAR(100) UNIQUE NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP,
+    feed_id UUID NOT NULL,
+    content TEXT,
+    original_url TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY(feed_id) REFERENCES feeds (id) ON DELETE CASCADE
+);
+INSERT INTO posts_old
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, content, original_url
+FROM posts;
+DROP TABLE posts;
+ALTER TABLE posts_old RENAME TO posts;
+
+CREATE INDEX posts_original_url_idx ON posts (original_url);
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+    INSERT INTO posts_fts (post_id, title, description, content)
+    VALUES (new.id, new.title, coalesce(new.description, ''), coalesce(new.content, ''));
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_update AFTER UPDATE ON posts BEGIN
+    UPDATE posts_fts
+    SET title = new.title, description = coalesce(new.description, ''), content = coalesce(new.content, '')
+    WHERE post_id = old.id;
+END;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+    DELETE FROM posts_fts WHERE post_id = old.id;
+END;
+-- +goose StatementEnd
+
+DELETE FROM posts_fts WHERE post_id NOT IN (SELECT id FROM posts);
+
+CREATE TABLE feeds_old (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name VARCHAR(50) UNIQUE NOT NULL,
+    url VARCHAR(100) UNIQUE NOT NULL,
+    user_id UUID NOT NULL,
+    last_fetched_at TIMESTAMP,
+    next_fetch_at TIMESTAMP,
+    fetch_interval_seconds INTEGER NOT NULL DEFAULT 1800,
+    min_fetch_interval_seconds INTEGER NOT NULL DEFAULT 300,
+    max_fetch_interval_seconds INTEGER NOT NULL DEFAULT 86400,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    last_succeeded_at TIMESTAMP,
+    disabled_at TIMESTAMP,
+    claimed_by UUID REFERENCES agg_instances (id) ON DELETE SET NULL,
+    claimed_until TIMESTAMP,
+    cron_schedule TEXT,
+    retention_max_posts INTEGER,
+    retention_max_age_seconds INTEGER,
+    FOREIGN KEY(user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+INSERT INTO feeds_old
+SELECT  id, created_at, updated_at, name, url, user_id, last_fetched_at, next_fetch_at,
+        fetch_interval_seconds, min_fetch_interval_seconds, max_fetch_interval_seconds,
+        consecutive_failures, last_error, last_succeeded_at, disabled_at, claimed_by,
+        claimed_until, cron_schedule, retention_max_posts, retention_max_age_seconds
+FROM feeds;
+DROP TABLE feeds;
+ALTER TABLE feeds_old RENAME TO feeds;
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at);
+
+COMMIT;
+
+PRAGMA foreign_keys = ON;
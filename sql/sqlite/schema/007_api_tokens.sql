@@ -0,0 +1,15 @@
+-- +goose Up
+-- Only SHA-256 hash of token is kept, the token itself is shown once when created
+CREATE TABLE api_tokens(
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    token_hash BLOB UNIQUE NOT NULL,
+    expires_at TIMESTAMP,
+    FOREIGN KEY(user_id) REFERENCES users (id) ON DELETE CASCADE,
+    UNIQUE(user_id, name)
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Only daemons serve the refresh queue, one-off runs register for their claims only
+ALTER TABLE agg_instances ADD COLUMN daemon BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE agg_instances DROP COLUMN daemon;